@@ -62,7 +62,7 @@ func RecordBaseline(flowSpec *spec.FlowSpec, results []validate.StepResult, flow
 	// Extract covered steps (PASS status)
 	var coveredSteps []string
 	for _, result := range results {
-		if result.Status == "PASS" {
+		if result.Status == "PASS" && !validate.IsForbidResult(result) {
 			coveredSteps = append(coveredSteps, result.Step)
 		}
 	}
@@ -128,14 +128,52 @@ func EvaluateGate(results []validate.StepResult, thresholds ThresholdConfig, bas
 	conditionsTotal := 0
 	conditionsPass := 0
 	conditionsFail := 0
+	bySeverity := map[string]map[string]int{} // severity -> pass/fail/skip counts
+	forbidTotal := 0
+	forbidFail := 0
 
 	for _, result := range results {
+		// Forbid rule results are not flow steps; they are counted separately
+		if validate.IsForbidResult(result) {
+			stepsTotal--
+			forbidTotal++
+			if result.Status != "PASS" {
+				forbidFail++
+			}
+			continue
+		}
+		// Steps not applicable to this execution (e.g. untriggered saga compensations) are neutral
+		if result.Status == validate.StatusNotApplicable {
+			stepsTotal--
+			continue
+		}
 		if result.Status == "PASS" {
 			stepsPass++
 		}
 
 		for _, condition := range result.Conditions {
 			conditionsTotal++
+			severity := condition.Severity
+			if severity == "" {
+				severity = spec.SeverityError
+			}
+			counts := bySeverity[severity]
+			if counts == nil {
+				counts = map[string]int{"pass": 0, "fail": 0, "skip": 0}
+				bySeverity[severity] = counts
+			}
+			switch condition.Status {
+			case "PASS":
+				counts["pass"]++
+			case "FAIL", "WARN":
+				counts["fail"]++
+			case "SKIP":
+				counts["skip"]++
+			}
+			// Only error-severity conditions gate; warn/info are reported but never fail the gate
+			if severity != spec.SeverityError {
+				continue
+			}
 			switch condition.Status {
 			case "PASS":
 				conditionsPass++
@@ -176,7 +214,10 @@ func EvaluateGate(results []validate.StepResult, thresholds ThresholdConfig, bas
 		"conditionsEvaluated":  conditionsEvaluated,
 		"conditionsRate":       conditionsRate,
 		"conditionsThreshold":  thresholds.ConditionsThreshold,
+		"conditionsBySeverity": bySeverity,
 		"skipAsFail":          thresholds.SkipAsFail,
+		"forbidTotal":          forbidTotal,
+		"forbidFail":           forbidFail,
 	}
 
 	if baseline != nil {
@@ -175,6 +175,23 @@ func TestEvaluateGate(t *testing.T) {
 			expectPass: false,
 			expectViolations: 1,
 		},
+		{
+			name: "Warn and info conditions do not gate",
+			results: []validate.StepResult{
+				{Step: "step1", Status: "PASS", Conditions: []validate.ConditionResult{
+					{Status: "PASS"},
+					{Status: "WARN", Severity: "warn"},
+					{Status: "SKIP", Severity: "info"},
+				}},
+			},
+			thresholds: ThresholdConfig{
+				StepsThreshold: 0.9,
+				ConditionsThreshold: 0.9,
+				SkipAsFail: true,
+			},
+			expectPass: true,
+			expectViolations: 0,
+		},
 	}
 
 	for _, tt := range tests {
@@ -269,4 +286,51 @@ func TestDefaultThresholds(t *testing.T) {
 	if thresholds.SkipAsFail != false {
 		t.Errorf("Expected default SkipAsFail false, got %v", thresholds.SkipAsFail)
 	}
-}
\ No newline at end of file
+}
+
+func TestEvaluateGate_CountsBySeverity(t *testing.T) {
+	results := []validate.StepResult{
+		{Step: "step1", Status: "PASS", Conditions: []validate.ConditionResult{
+			{Status: "PASS", Severity: "error"},
+			{Status: "PASS"},
+			{Status: "WARN", Severity: "warn"},
+			{Status: "PASS", Severity: "warn"},
+			{Status: "SKIP", Severity: "info"},
+		}},
+	}
+	result := EvaluateGate(results, DefaultThresholds(), nil)
+
+	counts := result.Details["conditionsBySeverity"].(map[string]map[string]int)
+	if got := counts["error"]; got["pass"] != 2 || got["fail"] != 0 {
+		t.Errorf("error counts = %v", got)
+	}
+	if got := counts["warn"]; got["pass"] != 1 || got["fail"] != 1 {
+		t.Errorf("warn counts = %v", got)
+	}
+	if got := counts["info"]; got["skip"] != 1 {
+		t.Errorf("info counts = %v", got)
+	}
+	if rate := result.Details["conditionsRate"].(float64); rate != 1 {
+		t.Errorf("pass rate should only consider error conditions, got %v", rate)
+	}
+}
+
+func TestEvaluateGate_ExcludesForbidResults(t *testing.T) {
+	results := []validate.StepResult{
+		{Step: "step1", Status: "PASS"},
+		{Step: "step2", Status: "FAIL"},
+		{Step: "forbid: no-direct-db", Status: "PASS"},
+		{Step: "forbid: no-refund-before-charge", Status: "FAIL", Code: validate.CodeForbiddenCall},
+	}
+	result := EvaluateGate(results, DefaultThresholds(), nil)
+
+	if got := result.Details["stepsTotal"]; got != 2 {
+		t.Errorf("stepsTotal = %v, want 2 (forbid results are not steps)", got)
+	}
+	if got := result.Details["stepsCoverage"].(float64); got != 0.5 {
+		t.Errorf("stepsCoverage = %v, want 0.5", got)
+	}
+	if result.Details["forbidTotal"] != 2 || result.Details["forbidFail"] != 1 {
+		t.Errorf("forbid counts = %v/%v, want 2/1", result.Details["forbidTotal"], result.Details["forbidFail"])
+	}
+}
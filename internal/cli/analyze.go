@@ -0,0 +1,140 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/choreoatlas2025/cli/internal/spec"
+	"github.com/choreoatlas2025/cli/internal/trace"
+	"github.com/choreoatlas2025/cli/internal/validate"
+)
+
+func runAnalyze(args []string) {
+	fs := flag.NewFlagSet("analyze", flag.ExitOnError)
+	flowPath := fs.String("flow", ".flowspec.yaml", "FlowSpec file path")
+	tracesDir := fs.String("traces", "", "Directory of trace JSON files")
+	format := fs.String("format", "human", "Output format: human|json")
+	outPath := fs.String("out", "", "Write the report to this file instead of stdout")
+	rare := fs.Float64("rare-threshold", validate.DefaultRareVariantThreshold, "Flag variants observed in fewer than this fraction of traces")
+	semantic := fs.Bool("semantic", true, "Enable semantic validation (CEL)")
+	causalityMode := fs.String("causality", "temporal", "Causality check mode: strict|temporal|off (default: temporal)")
+	_ = fs.Parse(args)
+
+	if *tracesDir == "" {
+		exitErr(errors.New("--traces parameter is required"))
+	}
+	if *format != "human" && *format != "json" {
+		exitErr(fmt.Errorf("invalid format: %s, supported formats: human|json", *format))
+	}
+
+	flow, opIndex, err := loadAndValidateFlow(*flowPath)
+	if err != nil {
+		exitErr(err)
+	}
+	traces, err := loadTraceDir(*tracesDir)
+	if err != nil {
+		exitErr(err)
+	}
+
+	validate.EnableSemantic = *semantic
+	switch validate.CausalityMode(*causalityMode) {
+	case validate.CausalityStrict, validate.CausalityTemporal, validate.CausalityOff:
+		validate.GlobalCausalityMode = validate.CausalityMode(*causalityMode)
+	default:
+		exitErr(fmt.Errorf("Invalid causality mode: %s, supported modes: strict|temporal|off", *causalityMode))
+	}
+
+	report := validate.AnalyzeTraces(flow, opIndex, traces, *rare)
+
+	out := io.Writer(os.Stdout)
+	if *outPath != "" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			exitErr(fmt.Errorf("cannot create report file: %w", err))
+		}
+		defer f.Close()
+		out = f
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			exitErr(err)
+		}
+	} else {
+		printConformance(out, flow, report)
+	}
+	if *outPath != "" {
+		fmt.Printf("Conformance report written to: %s\n", *outPath)
+	}
+}
+
+// loadTraceDir loads every *.json trace in dir, sorted by file name
+func loadTraceDir(dir string) ([]validate.NamedTrace, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no trace files (*.json) found in %s", dir)
+	}
+	sort.Strings(paths)
+
+	var traces []validate.NamedTrace
+	for _, p := range paths {
+		tr, err := trace.LoadFromFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		traces = append(traces, validate.NamedTrace{Name: filepath.Base(p), Trace: tr})
+	}
+	return traces, nil
+}
+
+// printConformance renders a process-mining style summary of the corpus
+func printConformance(w io.Writer, flow *spec.FlowSpec, r validate.ConformanceReport) {
+	fmt.Fprintf(w, "Conformance: %s (%d traces)\n", flow.Info.Title, r.TracesTotal)
+	fmt.Fprintf(w, "  Fully conforming: %d/%d (%.1f%%)\n\n", r.Conforming, r.TracesTotal, r.ConformanceRate*100)
+
+	width := len("Step")
+	for _, s := range r.Steps {
+		if len(s.Step) > width {
+			width = len(s.Step)
+		}
+	}
+	fmt.Fprintf(w, "  %-*s  %8s  %8s  %10s  %8s\n", width, "Step", "Present", "In order", "Conditions", "Passed")
+	for _, s := range r.Steps {
+		fmt.Fprintf(w, "  %-*s  %7.1f%%  %7.1f%%  %9.1f%%  %7.1f%%\n",
+			width, s.Step, s.PresentRate*100, s.InOrderRate*100, s.ConditionRate*100, s.PassRate*100)
+	}
+
+	fmt.Fprintf(w, "\nVariants (%d):\n", len(r.Variants))
+	for i, v := range r.Variants {
+		seq := strings.Join(v.Sequence, " -> ")
+		if seq == "" {
+			seq = "(no steps observed)"
+		}
+		rare := ""
+		if v.Rare {
+			rare = "  [RARE]"
+		}
+		noun := "traces"
+		if v.Count == 1 {
+			noun = "trace"
+		}
+		fmt.Fprintf(w, "  #%d  %d %s (%.1f%%)%s\n      %s\n", i+1, v.Count, noun, v.Frequency*100, rare, seq)
+		if v.Rare {
+			fmt.Fprintf(w, "      e.g. %s\n", strings.Join(v.Traces, ", "))
+		}
+	}
+}
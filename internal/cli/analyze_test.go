@@ -0,0 +1,55 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/choreoatlas2025/cli/internal/validate"
+)
+
+func TestAnalyze_TraceDirCorpus(t *testing.T) {
+	dir := t.TempDir()
+	copyTrace := func(src, dst string) {
+		b, err := os.ReadFile(filepath.Join("..", "..", "examples", "traces", src))
+		if err != nil {
+			t.Fatalf("read %s: %v", src, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, dst), b, 0o644); err != nil {
+			t.Fatalf("write %s: %v", dst, err)
+		}
+	}
+	for _, name := range []string{"a.json", "b.json", "c.json"} {
+		copyTrace("successful-order.trace.json", name)
+	}
+	copyTrace("failed-inventory.trace.json", "d.json")
+
+	traces, err := loadTraceDir(dir)
+	if err != nil || len(traces) != 4 || traces[0].Name != "a.json" {
+		t.Fatalf("expected 4 traces sorted by name, got %v %+v", err, traces)
+	}
+
+	flowPath := filepath.Join("..", "..", "examples", "flows", "order-fulfillment.flowspec.yaml")
+	flow, opIndex, err := loadAndValidateFlow(flowPath)
+	if err != nil {
+		t.Fatalf("load flow: %v", err)
+	}
+	report := validate.AnalyzeTraces(flow, opIndex, traces, 0.3)
+
+	var out bytes.Buffer
+	printConformance(&out, flow, report)
+	text := out.String()
+	for _, want := range []string{"Fully conforming: 3/4 (75.0%)", "3 traces (75.0%)", "1 trace (25.0%)  [RARE]", "e.g. d.json"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in report:\n%s", want, text)
+		}
+	}
+
+	if _, err := loadTraceDir(t.TempDir()); err == nil {
+		t.Error("expected error for directory without traces")
+	}
+}
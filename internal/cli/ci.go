@@ -12,8 +12,18 @@ func runCIGate(args []string) {
 	fs := flag.NewFlagSet("ci-gate", flag.ExitOnError)
 	flowPath := fs.String("flow", ".flowspec.yaml", "FlowSpec file path")
 	tracePath := fs.String("trace", "", "trace.json path")
+	configPath := fs.String("config", "", "Project config file (default: nearest .choreoatlas.yaml)")
+	format := fs.String("format", "text", "Output format: text|sarif (SARIF is written to stdout)")
 	_ = fs.Parse(args)
 
-	runLint([]string{"--flow", *flowPath})
-	runValidate([]string{"--flow", *flowPath, "--trace", *tracePath})
-}
\ No newline at end of file
+	if so := startSARIF(*format); so != nil {
+		// validate 已包含 lint 问题；为输出单个 SARIF 文档，这里只做 schema 检查，
+		// validate 沿用同一个 SARIF 输出并负责写出
+		checkSchemas(*flowPath)
+		runValidate([]string{"--flow", *flowPath, "--trace", *tracePath, "--config", *configPath, "--format", "sarif"})
+		so.flush()
+		return
+	}
+	runLint([]string{"--flow", *flowPath, "--config", *configPath, "--format", *format})
+	runValidate([]string{"--flow", *flowPath, "--trace", *tracePath, "--config", *configPath, "--format", *format})
+}
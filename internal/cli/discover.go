@@ -7,11 +7,16 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 
+	"gopkg.in/yaml.v3"
+
+	"github.com/choreoatlas2025/cli/internal/diff"
 	"github.com/choreoatlas2025/cli/internal/spec"
 	"github.com/choreoatlas2025/cli/internal/trace"
+	"github.com/choreoatlas2025/cli/internal/validate"
 )
 
 func runDiscover(args []string) {
@@ -20,28 +25,78 @@ func runDiscover(args []string) {
     out := fs.String("out", "discovered.flowspec.yaml", "FlowSpec output path")
     outServices := fs.String("out-services", "./services", "ServiceSpec output directory")
     title := fs.String("title", "Flow generated from trace", "FlowSpec title")
+    format := fs.String("format", "flow", "FlowSpec format: flow|graph")
     noValidate := fs.Bool("no-validate", false, "Skip schema + lint validation gate (not recommended)")
+    merge := fs.Bool("merge", false, "Merge into existing specs at --out instead of overwriting them")
+    dryRun := fs.Bool("dry-run", false, "With --merge, only print the diff without writing files")
     _ = fs.Parse(args)
 
 	if *tracePath == "" {
 		exitErr(fmt.Errorf("--trace parameter is required"))
 	}
+	if *format != "flow" && *format != "graph" {
+		exitErr(fmt.Errorf("invalid format: %s, supported formats: flow|graph", *format))
+	}
+	if *dryRun && !*merge {
+		exitErr(fmt.Errorf("--dry-run requires --merge"))
+	}
 
-	tr, err := trace.LoadFromFile(*tracePath)
-	if err != nil {
-		exitErr(err)
+	// 增量模式：沿用已有规约的格式
+	var existing []byte
+	if *merge {
+		b, err := os.ReadFile(*out)
+		switch {
+		case err == nil:
+			existing = b
+			if existingFlow, err := spec.LoadFlowSpec(*out); err == nil && existingFlow.IsGraphMode() {
+				*format = "graph"
+			}
+		case os.IsNotExist(err):
+			fmt.Printf("%s does not exist yet, generating it from scratch\n", *out)
+		default:
+			exitErr(err)
+		}
 	}
 
-	// 按时间排序 spans
-	sort.Slice(tr.Spans, func(i, j int) bool {
-		return tr.Spans[i].StartNanos < tr.Spans[j].StartNanos
-	})
+	var yml string
+	var spans []trace.Span
+	if info, err := os.Stat(*tracePath); err == nil && info.IsDir() || *format == "graph" {
+		// 多条 trace：挖掘依赖、并发与可选步骤
+		traces, err := loadDiscoverTraces(*tracePath)
+		if err != nil {
+			exitErr(err)
+		}
+		for _, tr := range traces {
+			spans = append(spans, tr.Spans...)
+		}
+		mined := spec.MineFlow(traces, 0)
+		fmt.Printf("Mined %d activities from %d traces\n", len(mined.Activities), mined.Traces)
+		yml = generateMinedFlowYAML(mined, spec.InferDataFlow(traces), *title, *outServices, *format)
+	} else {
+		tr, err := trace.LoadFromFile(*tracePath)
+		if err != nil {
+			exitErr(err)
+		}
+
+		// 按时间排序 spans
+		sort.Slice(tr.Spans, func(i, j int) bool {
+			return tr.Spans[i].StartNanos < tr.Spans[j].StartNanos
+		})
+
+		// 生成 FlowSpec YAML（先不落盘，先生成 ServiceSpec 与校验）
+		yml = generateFlowYAML(tr, *title, *outServices)
+		spans = tr.Spans
+	}
 
-    // 生成 FlowSpec YAML（先不落盘，先生成 ServiceSpec 与校验）
-    yml := generateFlowYAML(tr, *title, *outServices)
+	if existing != nil {
+		if err := mergeDiscovered(existing, yml, spans, *out, *dryRun, *noValidate); err != nil {
+			exitErr(err)
+		}
+		return
+	}
 
     // 先生成 ServiceSpec 文件（FlowSpec 校验依赖其存在）
-    if err := spec.GenerateServiceSpecs(tr.Spans, *outServices); err != nil {
+    if err := spec.GenerateServiceSpecs(spans, *outServices); err != nil {
         exitErr(fmt.Errorf("failed to generate ServiceSpec: %w", err))
     }
 
@@ -60,13 +115,127 @@ func runDiscover(args []string) {
     fmt.Println("Dual contract generation complete! Please adjust the generated specifications as needed.")
 }
 
+// mergeDiscovered 将新发现的步骤与操作合并进已有规约：保留已有内容、注释与顺序，
+// 打印可审阅的 diff，并标出不再被观测到的条目
+func mergeDiscovered(existing []byte, yml string, spans []trace.Span, outPath string, dryRun, noValidate bool) error {
+	mergedFlow, report, err := spec.MergeFlowSpec(existing, []byte(yml))
+	if err != nil {
+		return err
+	}
+	var flow spec.FlowSpec
+	if err := yaml.Unmarshal(mergedFlow, &flow); err != nil {
+		return fmt.Errorf("failed to parse merged flowspec: %w", err)
+	}
+
+	type pendingWrite struct {
+		path string
+		data []byte
+	}
+	var writes []pendingWrite
+	var added, stale []string
+	changed := false
+	if d := diff.Unified("a/"+outPath, "b/"+outPath, string(existing), string(mergedFlow)); d != "" {
+		fmt.Print(d)
+		changed = true
+	}
+	for _, a := range report.Added {
+		added = append(added, outPath+": "+a)
+	}
+	for _, s := range report.Stale {
+		stale = append(stale, outPath+": "+s)
+	}
+
+	serviceOps := spec.ServiceOperationsFromSpans(spans)
+	var services []string
+	for svc := range serviceOps {
+		services = append(services, svc)
+	}
+	sort.Strings(services)
+	for _, svc := range services {
+		bind, ok := flow.Services[svc]
+		if !ok {
+			continue
+		}
+		path := spec.ResolvePath(outPath, bind.Spec)
+		old, err := os.ReadFile(path)
+		var data []byte
+		switch {
+		case err == nil:
+			var r *spec.MergeReport
+			if data, r, err = spec.MergeServiceSpec(old, serviceOps[svc]); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			for _, a := range r.Added {
+				added = append(added, path+": "+a)
+			}
+			for _, s := range r.Stale {
+				stale = append(stale, path+": "+s)
+			}
+		case os.IsNotExist(err):
+			if data, err = spec.MarshalServiceSpec(&spec.ServiceSpecFile{Service: svc, Operations: serviceOps[svc]}); err != nil {
+				return err
+			}
+			added = append(added, path+": new servicespec")
+		default:
+			return err
+		}
+		fromName := "a/" + path
+		if old == nil {
+			fromName = "/dev/null"
+		}
+		if d := diff.Unified(fromName, "b/"+path, string(old), string(data)); d != "" {
+			fmt.Print(d)
+			changed = true
+			writes = append(writes, pendingWrite{path, data})
+		}
+	}
+
+	for _, a := range added {
+		fmt.Printf("Added: %s\n", a)
+	}
+	for _, s := range stale {
+		fmt.Printf("No longer observed: %s\n", s)
+	}
+	if !changed {
+		fmt.Println("Specs are up to date, nothing to merge.")
+		return nil
+	}
+	if dryRun {
+		fmt.Println("Dry run: no files written.")
+		return nil
+	}
+
+	for _, w := range writes {
+		if err := os.MkdirAll(filepath.Dir(w.path), 0o755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+		if err := os.WriteFile(w.path, w.data, 0644); err != nil {
+			return fmt.Errorf("failed to write ServiceSpec file %s: %w", w.path, err)
+		}
+		fmt.Printf("Merged ServiceSpec: %s\n", w.path)
+	}
+	if noValidate {
+		if err := os.WriteFile(outPath, mergedFlow, 0644); err != nil {
+			return fmt.Errorf("failed to write file: %w", err)
+		}
+		fmt.Printf("Merged FlowSpec (no-validate): %s\n", outPath)
+		return nil
+	}
+	if err := validateAndPersistFlow(string(mergedFlow), outPath, ""); err != nil {
+		return fmt.Errorf("merged flowspec failed schema/lint gate: %w", err)
+	}
+	fmt.Printf("Merged FlowSpec (validated): %s\n", outPath)
+	return nil
+}
+
 // generateFlowYAML 从 trace 生成 FlowSpec YAML
 func generateFlowYAML(tr *trace.Trace, title string, outServices string) string {
 	var sb strings.Builder
 
 	// Info 部分
 	sb.WriteString("info:\n")
-	sb.WriteString(fmt.Sprintf("  title: \"%s\"\n\n", title))
+	sb.WriteString(fmt.Sprintf("  title: \"%s\"\n", title))
+	sb.WriteString(fmt.Sprintf("  version: \"%s\"\n\n", validate.DefaultFlowVersion))
 
 	// Services 部分
 	services := make(map[string]struct{})
@@ -83,6 +252,21 @@ func generateFlowYAML(tr *trace.Trace, title string, outServices string) string
 	}
 	sb.WriteString("\n")
 
+    // 变量连线：值从前序调用的输出流入后续调用的请求（挂在各操作首次出现的步骤上）
+    firstStep := make(map[string]int)
+    for i, span := range tr.Spans {
+        if span.Service == "" || span.Name == "" {
+            continue
+        }
+        key := span.Service + "." + spec.ComputeOperationID(span)
+        if _, ok := firstStep[key]; !ok {
+            firstStep[key] = i
+        }
+    }
+    inputs, outputs := wiringByCall(spec.InferDataFlow([]*trace.Trace{tr}), func(producer, consumer string) bool {
+        return firstStep[producer] < firstStep[consumer]
+    })
+
     // Flow 部分
     sb.WriteString("flow:\n")
     for i, span := range tr.Spans {
@@ -93,16 +277,23 @@ func generateFlowYAML(tr *trace.Trace, title string, outServices string) string
 
         // 使用规范化的 operationId
         opId := spec.ComputeOperationID(span)
+        key := span.Service + "." + opId
         sb.WriteString(fmt.Sprintf("  - step: \"%s\"\n", stepName))
-        sb.WriteString(fmt.Sprintf("    call: \"%s.%s\"\n", span.Service, opId))
-
-        // 不再将遥测属性塞入 FlowSpec.input，保持输入干净。
-        // 如需传入真实调用实参（path/query/headers/body），请由用户后续补充。
+        sb.WriteString(fmt.Sprintf("    call: \"%s\"\n", key))
 
-		// Generate output (assuming each step has a response)
-        outputVar := fmt.Sprintf("%sResponse", strings.ToLower(span.Service))
-        sb.WriteString("    output:\n")
-        sb.WriteString(fmt.Sprintf("      %s: \"response.body\"  # TODO: Adjust output mapping\n", outputVar))
+        // 不将遥测属性塞入 FlowSpec.input，只生成由值关联推断出的 ${var} 引用；
+        // 其余调用实参（path/query/headers/body）请由用户后续补充。
+        if firstStep[key] == i && len(outputs[key]) > 0 {
+            writeWiring(&sb, "    ", inputs[key], outputs[key])
+        } else {
+            if firstStep[key] == i {
+                writeWiring(&sb, "    ", inputs[key], nil)
+            }
+            // Generate output (assuming each step has a response)
+            outputVar := fmt.Sprintf("%sResponse", strings.ToLower(span.Service))
+            sb.WriteString("    output:\n")
+            sb.WriteString(fmt.Sprintf("      %s: \"response.body\"  # TODO: Adjust output mapping\n", outputVar))
+        }
 
         sb.WriteString("\n")
     }
@@ -117,3 +308,218 @@ func generateFlowYAML(tr *trace.Trace, title string, outServices string) string
 
 	return sb.String()
 }
+
+// loadDiscoverTraces loads a trace file or every trace in a directory, skipping traces without spans
+func loadDiscoverTraces(path string) ([]*trace.Trace, error) {
+	var named []validate.NamedTrace
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		var err error
+		if named, err = loadTraceDir(path); err != nil {
+			return nil, err
+		}
+	} else {
+		tr, err := trace.LoadFromFile(path)
+		if err != nil {
+			return nil, err
+		}
+		named = []validate.NamedTrace{{Name: path, Trace: tr}}
+	}
+
+	var traces []*trace.Trace
+	for _, nt := range named {
+		if len(nt.Trace.Spans) == 0 {
+			fmt.Printf("Skipping %s: no spans\n", nt.Name)
+			continue
+		}
+		traces = append(traces, nt.Trace)
+	}
+	if len(traces) == 0 {
+		return nil, fmt.Errorf("no spans found in %s", path)
+	}
+	return traces, nil
+}
+
+// generateMinedFlowYAML 从多条 trace 挖掘出的模型生成 FlowSpec YAML（flow 或 graph 格式）
+func generateMinedFlowYAML(m *spec.MinedFlow, links []spec.DataLink, title string, outServices string, format string) string {
+	var sb strings.Builder
+
+	sb.WriteString("info:\n")
+	sb.WriteString(fmt.Sprintf("  title: \"%s\"\n", title))
+	sb.WriteString(fmt.Sprintf("  version: \"%s\"\n", validate.DefaultFlowVersion))
+	sb.WriteString(fmt.Sprintf("  description: \"Mined from %d traces\"\n\n", m.Traces))
+
+	var services []string
+	seen := make(map[string]bool)
+	for _, act := range m.Activities {
+		if !seen[act.Service] {
+			seen[act.Service] = true
+			services = append(services, act.Service)
+		}
+	}
+	sort.Strings(services)
+	sb.WriteString("services:\n")
+	for _, service := range services {
+		sb.WriteString(fmt.Sprintf("  %s:\n", service))
+		sb.WriteString(fmt.Sprintf("    spec: \"%s/%s.servicespec.yaml\"\n", outServices, service))
+	}
+	sb.WriteString("\n")
+
+	// 可选步骤与被丢弃的重复调用以注释说明
+	writeExtras := func(act spec.MinedActivity, indent string) {
+		if act.Optional {
+			sb.WriteString(fmt.Sprintf("%soptional: true  # seen in %d/%d traces\n", indent, act.Support, m.Traces))
+		}
+		if act.Duplicates > 0 {
+			noun := "calls"
+			if act.Duplicates == 1 {
+				noun = "call"
+			}
+			sb.WriteString(fmt.Sprintf("%s# %d repeated %s dropped\n", indent, act.Duplicates, noun))
+		}
+	}
+
+	if format == "graph" {
+		// 变量只能引用（传递）前驱节点的输出
+		keyOf := make(map[string]string)
+		depends := make(map[string][]string)
+		for _, act := range m.Activities {
+			key := act.Service + "." + act.OperationID
+			keyOf[act.ID] = key
+			depends[key] = act.Depends
+		}
+		var isAncestor func(anc, key string) bool
+		isAncestor = func(anc, key string) bool {
+			for _, d := range depends[key] {
+				if keyOf[d] == anc || isAncestor(anc, keyOf[d]) {
+					return true
+				}
+			}
+			return false
+		}
+		inputs, outputs := wiringByCall(links, isAncestor)
+
+		sb.WriteString("graph:\n")
+		sb.WriteString("  nodes:\n")
+		for _, act := range m.Activities {
+			key := act.Service + "." + act.OperationID
+			sb.WriteString(fmt.Sprintf("    - id: \"%s\"\n", act.ID))
+			sb.WriteString(fmt.Sprintf("      call: \"%s\"\n", key))
+			if len(act.Depends) > 0 {
+				sb.WriteString(fmt.Sprintf("      depends: [\"%s\"]\n", strings.Join(act.Depends, "\", \"")))
+			}
+			writeWiring(&sb, "      ", inputs[key], outputs[key])
+			writeExtras(act, "      ")
+			sb.WriteString("\n")
+		}
+	} else {
+		// 相邻且两两并发的步骤合并为 parallel 组
+		var groups [][]spec.MinedActivity
+		for _, act := range m.Activities {
+			if n := len(groups); n > 0 {
+				concurrent := true
+				for _, other := range groups[n-1] {
+					if !m.Concurrent(act.ID, other.ID) {
+						concurrent = false
+						break
+					}
+				}
+				if concurrent {
+					groups[n-1] = append(groups[n-1], act)
+					continue
+				}
+			}
+			groups = append(groups, []spec.MinedActivity{act})
+		}
+
+		// 变量只能引用之前 flow 条目的输出（同一 parallel 组内不可见）
+		groupOf := make(map[string]int)
+		for gi, group := range groups {
+			for _, act := range group {
+				groupOf[act.Service+"."+act.OperationID] = gi
+			}
+		}
+		inputs, outputs := wiringByCall(links, func(producer, consumer string) bool {
+			return groupOf[producer] < groupOf[consumer]
+		})
+
+		sb.WriteString("flow:\n")
+		for _, group := range groups {
+			if len(group) == 1 {
+				act := group[0]
+				key := act.Service + "." + act.OperationID
+				sb.WriteString(fmt.Sprintf("  - step: \"%s\"\n", act.ID))
+				sb.WriteString(fmt.Sprintf("    call: \"%s\"\n", key))
+				writeWiring(&sb, "    ", inputs[key], outputs[key])
+				writeExtras(act, "    ")
+				sb.WriteString("\n")
+				continue
+			}
+			var names []string
+			for _, act := range group {
+				names = append(names, act.ID)
+			}
+			sb.WriteString(fmt.Sprintf("  - step: \"Parallel: %s\"\n", strings.Join(names, ", ")))
+			sb.WriteString("    parallel:\n")
+			for _, act := range group {
+				key := act.Service + "." + act.OperationID
+				sb.WriteString(fmt.Sprintf("      - step: \"%s\"\n", act.ID))
+				sb.WriteString(fmt.Sprintf("        call: \"%s\"\n", key))
+				writeWiring(&sb, "        ", inputs[key], outputs[key])
+				writeExtras(act, "        ")
+			}
+			sb.WriteString("\n")
+		}
+	}
+
+	sb.WriteString("# This file was auto-generated by flowspec discover from multiple traces\n")
+	sb.WriteString("# TODO list:\n")
+	sb.WriteString("# 1. Review optional steps and dependencies learned from the traces\n")
+	sb.WriteString("# 2. Add input/output mappings and variable references\n")
+	sb.WriteString("# 3. Add appropriate meta information\n")
+
+	return sb.String()
+}
+
+// wiringByCall 按调用（service.operationId）整理变量连线，只保留 visible(producer, consumer)
+// 成立的连线：inputs[consumer][param] = var，outputs[producer][var] = 映射表达式
+func wiringByCall(links []spec.DataLink, visible func(producer, consumer string) bool) (map[string]map[string]string, map[string]map[string]string) {
+	inputs := make(map[string]map[string]string)
+	outputs := make(map[string]map[string]string)
+	for _, l := range links {
+		if !visible(l.Producer, l.Consumer) {
+			continue
+		}
+		if inputs[l.Consumer] == nil {
+			inputs[l.Consumer] = make(map[string]string)
+		}
+		if outputs[l.Producer] == nil {
+			outputs[l.Producer] = make(map[string]string)
+		}
+		inputs[l.Consumer][l.Param] = l.Variable
+		outputs[l.Producer][l.Variable] = l.Source
+	}
+	return inputs, outputs
+}
+
+// writeWiring 写出步骤的 input（${var} 引用）与 output 映射
+func writeWiring(sb *strings.Builder, indent string, inputs, outputs map[string]string) {
+	for _, block := range []struct {
+		key    string
+		values map[string]string
+		format string
+	}{{"input", inputs, "${%s}"}, {"output", outputs, "%s"}} {
+		if len(block.values) == 0 {
+			continue
+		}
+		names := make([]string, 0, len(block.values))
+		for name := range block.values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		sb.WriteString(indent + block.key + ":\n")
+		for _, name := range names {
+			value := fmt.Sprintf(block.format, block.values[name])
+			sb.WriteString(fmt.Sprintf("%s  %s: %q\n", indent, name, value))
+		}
+	}
+}
@@ -61,8 +61,15 @@ func validateAndPersistFlow(flowYAML string, outPath string, outServices string)
         return err
     }
 
-    // Static lint gate (call format, references, variables)
-    issues, err := validate.LintFlow(tmpPath, flow, opIndex)
+    // Static lint gate (call format, references, variables); project config is looked up next to outPath
+    var cfg *validate.LintConfig
+    if configPath := validate.FindProjectConfig(outPath); configPath != "" {
+        if cfg, err = validate.LoadLintConfig(configPath); err != nil {
+            os.Remove(tmpPath)
+            return err
+        }
+    }
+    issues, err := validate.LintFlow(tmpPath, flow, opIndex, cfg)
     if err != nil {
         os.Remove(tmpPath)
         return err
@@ -70,7 +77,7 @@ func validateAndPersistFlow(flowYAML string, outPath string, outServices string)
     for _, is := range issues {
         if is.Level == "ERROR" {
             os.Remove(tmpPath)
-            return fmt.Errorf("lint error: %s", is.Msg)
+            return fmt.Errorf("lint error: %s", is)
         }
     }
 
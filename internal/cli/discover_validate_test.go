@@ -5,10 +5,13 @@ package cli
 import (
     "os"
     "path/filepath"
+    "sort"
+    "strings"
     "testing"
 
     "github.com/choreoatlas2025/cli/internal/spec"
     "github.com/choreoatlas2025/cli/internal/trace"
+    "github.com/choreoatlas2025/cli/internal/validate"
 )
 
 func TestValidateAndPersistFlow_NormalizesHTTPAndPasses(t *testing.T) {
@@ -89,3 +92,208 @@ func TestValidateAndPersistFlow_PassesOnValidExample(t *testing.T) {
         t.Fatalf("expected validated flow written, stat error: %v", err)
     }
 }
+
+// discoverAndValidate runs discover on a trace file and validates the result
+// against the very same trace
+func discoverAndValidate(t *testing.T, tracePath string) ([]validate.StepResult, bool) {
+    t.Helper()
+    dir := t.TempDir()
+    outFlow := filepath.Join(dir, "discovered.flowspec.yaml")
+    outServices := filepath.Join(dir, "services")
+
+    tr, err := trace.LoadFromFile(tracePath)
+    if err != nil {
+        t.Fatalf("failed to load trace: %v", err)
+    }
+    sort.Slice(tr.Spans, func(i, j int) bool { return tr.Spans[i].StartNanos < tr.Spans[j].StartNanos })
+    if err := spec.GenerateServiceSpecs(tr.Spans, outServices); err != nil {
+        t.Fatalf("failed to generate servicespecs: %v", err)
+    }
+    if err := validateAndPersistFlow(generateFlowYAML(tr, "Round Trip", outServices), outFlow, outServices); err != nil {
+        t.Fatalf("discovered flow failed validation gate: %v", err)
+    }
+
+    flow, err := spec.LoadFlowSpec(outFlow)
+    if err != nil {
+        t.Fatalf("failed to load discovered flow: %v", err)
+    }
+    _, opIndex, err := flow.BuildOperationIndex(outFlow)
+    if err != nil {
+        t.Fatalf("failed to build operation index: %v", err)
+    }
+    raw, err := trace.LoadFromFile(tracePath)
+    if err != nil {
+        t.Fatalf("failed to reload trace: %v", err)
+    }
+    return validate.ValidateAgainstTrace(flow, opIndex, raw)
+}
+
+func TestDiscoverValidateRoundTrip_RawHTTPTrace(t *testing.T) {
+    dir := t.TempDir()
+    // Generic span names: only attributes carry the route
+    traceJSON := `{
+        "spans": [
+          {"name": "HTTP POST", "service": "order-service", "startNanos": 100, "endNanos": 200,
+           "attributes": {"http.method": "POST", "http.route": "/orders", "http.status_code": 201}},
+          {"name": "HTTP GET", "service": "order-service", "startNanos": 300, "endNanos": 400,
+           "attributes": {"http.method": "GET", "http.route": "/orders/{id}", "http.status_code": 200}},
+          {"name": "GET /inventory/42", "service": "inventory-service", "startNanos": 500, "endNanos": 600,
+           "attributes": {"http.status_code": 200}},
+          {"name": "payments.v1.Payments/Charge", "service": "payment-service", "startNanos": 700, "endNanos": 800,
+           "attributes": {"rpc.method": "Charge", "rpc.service": "payments.v1.Payments"}}
+        ]
+    }`
+    tracePath := filepath.Join(dir, "raw.trace.json")
+    if err := os.WriteFile(tracePath, []byte(traceJSON), 0o644); err != nil {
+        t.Fatalf("failed to write trace: %v", err)
+    }
+
+    results, ok := discoverAndValidate(t, tracePath)
+    if !ok {
+        t.Fatalf("expected discover -> validate round trip to pass, got %+v", results)
+    }
+    if len(results) != 4 {
+        t.Fatalf("expected 4 step results, got %d", len(results))
+    }
+}
+
+func TestDiscoverValidateRoundTrip_ExampleTraces(t *testing.T) {
+    traces, _ := filepath.Glob("../../examples/traces/*.trace.json")
+    if len(traces) == 0 {
+        t.Fatal("no example traces found")
+    }
+    for _, tracePath := range traces {
+        t.Run(filepath.Base(tracePath), func(t *testing.T) {
+            if results, ok := discoverAndValidate(t, tracePath); !ok {
+                t.Fatalf("expected discover -> validate round trip to pass, got %+v", results)
+            }
+        })
+    }
+}
+
+func TestDiscoverMined_RoundTripBothFormats(t *testing.T) {
+    span := func(svc, name string, start, end int64) trace.Span {
+        return trace.Span{Service: svc, Name: name, StartNanos: start, EndNanos: end,
+            Attributes: map[string]any{"http.status_code": 200}}
+    }
+    traces := []*trace.Trace{
+        {Spans: []trace.Span{span("orderService", "createOrder", 0, 100), span("riskService", "check", 110, 150),
+            span("inventoryService", "reserve", 160, 200), span("paymentService", "pay", 300, 400),
+            span("shippingService", "ship", 500, 600)}},
+        {Spans: []trace.Span{span("orderService", "createOrder", 0, 100), span("inventoryService", "reserve", 110, 150),
+            span("riskService", "check", 160, 200), span("paymentService", "pay", 300, 400),
+            span("paymentService", "pay", 410, 450)}},
+    }
+    var spans []trace.Span
+    for _, tr := range traces {
+        spans = append(spans, tr.Spans...)
+    }
+    mined := spec.MineFlow(traces, 0)
+
+    for _, format := range []string{"flow", "graph"} {
+        t.Run(format, func(t *testing.T) {
+            dir := t.TempDir()
+            outFlow := filepath.Join(dir, "mined.flowspec.yaml")
+            outServices := filepath.Join(dir, "services")
+            if err := spec.GenerateServiceSpecs(spans, outServices); err != nil {
+                t.Fatalf("failed to generate servicespecs: %v", err)
+            }
+            yml := generateMinedFlowYAML(mined, spec.InferDataFlow(traces), "Mined", outServices, format)
+            if err := validateAndPersistFlow(yml, outFlow, outServices); err != nil {
+                t.Fatalf("mined flow failed validation gate: %v\n%s", err, yml)
+            }
+            flow, err := spec.LoadFlowSpec(outFlow)
+            if err != nil {
+                t.Fatalf("failed to load mined flow: %v", err)
+            }
+            if flow.IsGraphMode() != (format == "graph") {
+                t.Fatalf("expected %s format", format)
+            }
+            if format == "flow" && (len(flow.Flow) != 4 || len(flow.Flow[1].Parallel) != 2) {
+                t.Errorf("expected reserve/check parallel group and 4 entries, got %+v", flow.Flow)
+            }
+            if ship, ok := flow.StepByName("ship"); !ok || !ship.Optional {
+                t.Errorf("expected optional ship step, got %+v", ship)
+            }
+            _, opIndex, err := flow.BuildOperationIndex(outFlow)
+            if err != nil {
+                t.Fatalf("failed to build operation index: %v", err)
+            }
+            for i, tr := range traces {
+                if results, ok := validate.ValidateAgainstTrace(flow, opIndex, tr); !ok {
+                    t.Errorf("trace %d failed against mined %s: %+v", i, format, results)
+                }
+            }
+        })
+    }
+}
+
+func TestDiscover_InfersVariableWiring(t *testing.T) {
+    span := func(svc, name string, start int64, attrs map[string]any) trace.Span {
+        return trace.Span{Service: svc, Name: name, StartNanos: start, EndNanos: start + 50, Attributes: attrs}
+    }
+    tr := &trace.Trace{Spans: []trace.Span{
+        span("orderService", "createOrder", 0, map[string]any{
+            "response.body": map[string]any{"orderId": "ORD-1001", "total": 199.99}}),
+        span("inventoryService", "reserve", 100, map[string]any{
+            "request.body.order_id": "ORD-1001"}),
+        span("paymentService", "pay", 200, map[string]any{
+            "request.body.orderId": "ORD-1001", "request.body.amount": 199.99}),
+    }}
+    mined := spec.MineFlow([]*trace.Trace{tr}, 0)
+    links := spec.InferDataFlow([]*trace.Trace{tr})
+
+    ymls := map[string]string{"single": generateFlowYAML(tr, "Wired", "")}
+    for _, format := range []string{"flow", "graph"} {
+        ymls[format] = generateMinedFlowYAML(mined, links, "Wired", "", format)
+    }
+    for name, yml := range ymls {
+        t.Run(name, func(t *testing.T) {
+            dir := t.TempDir()
+            outServices := filepath.Join(dir, "services")
+            if err := spec.GenerateServiceSpecs(tr.Spans, outServices); err != nil {
+                t.Fatalf("failed to generate servicespecs: %v", err)
+            }
+            yml = strings.ReplaceAll(yml, `spec: "/`, `spec: "`+outServices+"/")
+            outFlow := filepath.Join(dir, "wired.flowspec.yaml")
+            if err := validateAndPersistFlow(yml, outFlow, outServices); err != nil {
+                t.Fatalf("wired flow failed validation gate: %v\n%s", err, yml)
+            }
+            flow, err := spec.LoadFlowSpec(outFlow)
+            if err != nil {
+                t.Fatalf("failed to load flow: %v", err)
+            }
+            var create, reserve, pay spec.FlowStep
+            for _, st := range flowStepsOf(flow) {
+                switch {
+                case strings.HasPrefix(st.Call, "orderService."):
+                    create = st
+                case strings.HasPrefix(st.Call, "inventoryService."):
+                    reserve = st
+                case strings.HasPrefix(st.Call, "paymentService."):
+                    pay = st
+                }
+            }
+            if create.Output["orderId"] != "response.body.orderId" || create.Output["total"] != "response.body.total" {
+                t.Errorf("unexpected producer outputs: %v", create.Output)
+            }
+            if reserve.Input["orderId"] != "${orderId}" {
+                t.Errorf("unexpected reserve inputs: %v", reserve.Input)
+            }
+            if pay.Input["orderId"] != "${orderId}" || pay.Input["amount"] != "${total}" {
+                t.Errorf("unexpected pay inputs: %v", pay.Input)
+            }
+        })
+    }
+}
+
+// flowStepsOf lists flow steps (parallel children expanded) or graph nodes
+func flowStepsOf(flow *spec.FlowSpec) []spec.FlowStep {
+    var steps []spec.FlowStep
+    for _, name := range flow.GetStepNames() {
+        if st, ok := flow.StepByName(name); ok {
+            steps = append(steps, st)
+        }
+    }
+    return steps
+}
@@ -4,11 +4,13 @@
 package cli
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"os"
 
 	"github.com/choreoatlas2025/cli/internal/cli/exitcode"
+	"github.com/choreoatlas2025/cli/internal/diff"
 	"github.com/choreoatlas2025/cli/internal/schemas"
 	"github.com/choreoatlas2025/cli/internal/spec"
 	"github.com/choreoatlas2025/cli/internal/validate"
@@ -18,60 +20,205 @@ func runLint(args []string) {
 	fs := flag.NewFlagSet("lint", flag.ExitOnError)
 	flowPath := fs.String("flow", ".flowspec.yaml", "FlowSpec file path")
 	useSchema := fs.Bool("schema", true, "Enable JSON Schema strict validation")
+	configPath := fs.String("config", "", "Project config file (default: nearest "+validate.ProjectConfigFile+")")
+	listRules := fs.Bool("list-rules", false, "List lint rules and exit")
+	format := fs.String("format", "text", "Output format: text|sarif (SARIF is written to stdout)")
+	fix := fs.Bool("fix", false, "Rewrite the FlowSpec and its ServiceSpecs to fix mechanically fixable issues")
+	dryRun := fs.Bool("dry-run", false, "With --fix, only print the diff without writing files")
 	_ = fs.Parse(args)
 
+	if *listRules {
+		printLintRules()
+		return
+	}
+	if *dryRun && !*fix {
+		exitErr(fmt.Errorf("--dry-run requires --fix"))
+	}
+
+	so := startSARIF(*format)
+
 	// JSON Schema validation (if enabled)
 	if *useSchema {
-		// FlowSpec schema validation (using embedded schema for robustness)
-		if err := spec.ValidateYAMLWithSchemaFS(*flowPath, schemas.FS, "flowspec.schema.json"); err != nil {
-			// Fallback to file path method
-			if err := spec.ValidateYAMLWithSchema(*flowPath, "schemas/flowspec.schema.json"); err != nil {
-				exitErr(fmt.Errorf("FlowSpec structure validation failed: %w", err))
+		checkSchemas(*flowPath)
+	}
+
+	cfg := loadLintConfig(*configPath, *flowPath)
+	flow, opIndex, issues := lintFlowFile(*flowPath, cfg)
+	if *fix {
+		fixes, err := validate.FixFlow(*flowPath, flow, opIndex, issues)
+		if err != nil {
+			exitErr(err)
+		}
+		if *dryRun {
+			printFixDiffs(fixes)
+			// SARIF 模式下 diff 写入 stderr，stdout 仍输出修复前的问题
+			if so != nil {
+				so.addLint(*flowPath, issues)
+				so.flush()
 			}
+			return
+		}
+		if writeFixes(fixes) {
+			// 修复后重新检查，只报告剩余问题
+			_, _, issues = lintFlowFile(*flowPath, cfg)
 		}
-		fmt.Println("[SCHEMA] FlowSpec structure validation passed")
 	}
-
-	flow, err := spec.LoadFlowSpec(*flowPath)
-	if err != nil {
-		exitErr(err)
+	if so != nil {
+		so.addLint(*flowPath, issues)
+		so.flush()
+		if hasLintError(issues) {
+			os.Exit(exitcode.InputError)
+		}
+		return
 	}
 
-	// ServiceSpec schema validation (if enabled)
-	if *useSchema {
-		for alias, bind := range flow.Services {
-			serviceSpecPath := spec.ResolvePath(*flowPath, bind.Spec)
-			// Using embedded schema for robustness
-			if err := spec.ValidateYAMLWithSchemaFS(serviceSpecPath, schemas.FS, "servicespec.schema.json"); err != nil {
-				// Fallback to file path method
-				if err := spec.ValidateYAMLWithSchema(serviceSpecPath, "schemas/servicespec.schema.json"); err != nil {
-					exitErr(fmt.Errorf("ServiceSpec structure validation failed (%s): %w", alias, err))
-				}
-			}
+	if len(issues) == 0 {
+		fmt.Println("Lint: OK")
+		return
+	}
+	errCount := 0
+	for _, is := range issues {
+		if is.Level == "ERROR" {
+			errCount++
 		}
-		fmt.Println("[SCHEMA] ServiceSpec structure validation passed")
+		printLintIssue("", is)
 	}
-	_, opIndex, err := flow.BuildOperationIndex(*flowPath)
+	if errCount > 0 {
+		os.Exit(exitcode.InputError)
+	}
+}
+
+// lintFlowFile 加载 FlowSpec 及其引用的 ServiceSpec 并运行 lint
+func lintFlowFile(flowPath string, cfg *validate.LintConfig) (*spec.FlowSpec, map[string]map[string]spec.ServiceOperation, []validate.LintIssue) {
+	flow, err := spec.LoadFlowSpec(flowPath)
 	if err != nil {
 		exitErr(err)
 	}
-	issues, err := validate.LintFlow(*flowPath, flow, opIndex)
+	_, opIndex, err := flow.BuildOperationIndex(flowPath)
 	if err != nil {
 		exitErr(err)
 	}
+	issues, err := validate.LintFlow(flowPath, flow, opIndex, cfg)
+	if err != nil {
+		exitErr(err)
+	}
+	return flow, opIndex, issues
+}
 
-	if len(issues) == 0 {
-		fmt.Println("Lint: OK")
+// printFixDiffs 输出 --fix --dry-run 的统一 diff
+func printFixDiffs(fixes []validate.FileFix) {
+	changed := false
+	for _, f := range fixes {
+		if d := diff.Unified("a/"+f.Path, "b/"+f.Path, string(f.Before), string(f.After)); d != "" {
+			fmt.Print(d)
+			changed = true
+		}
+	}
+	if !changed {
+		fmt.Println("Nothing to fix.")
 		return
 	}
-	errCount := 0
+	fmt.Println("Dry run: no files written.")
+}
+
+// writeFixes 写回修复后的文件并列出每项修改，返回是否有文件被修改
+func writeFixes(fixes []validate.FileFix) bool {
+	changed := false
+	for _, f := range fixes {
+		if bytes.Equal(f.Before, f.After) {
+			continue
+		}
+		if err := os.WriteFile(f.Path, f.After, 0644); err != nil {
+			exitErr(fmt.Errorf("failed to write %s: %w", f.Path, err))
+		}
+		for _, msg := range f.Fixes {
+			fmt.Printf("[FIX] %s: %s\n", f.Path, msg)
+		}
+		changed = true
+	}
+	return changed
+}
+
+// checkSchemas 用 JSON Schema 校验 FlowSpec 及其引用的 ServiceSpec，失败时退出
+func checkSchemas(flowPath string) {
+	// FlowSpec schema validation (using embedded schema for robustness)
+	if err := spec.ValidateYAMLWithSchemaFS(flowPath, schemas.FS, "flowspec.schema.json"); err != nil {
+		// Fallback to file path method
+		if err := spec.ValidateYAMLWithSchema(flowPath, "schemas/flowspec.schema.json"); err != nil {
+			exitErr(fmt.Errorf("FlowSpec structure validation failed: %w", err))
+		}
+	}
+	fmt.Println("[SCHEMA] FlowSpec structure validation passed")
+
+	flow, err := spec.LoadFlowSpec(flowPath)
+	if err != nil {
+		exitErr(err)
+	}
+	for alias, bind := range flow.Services {
+		serviceSpecPath := spec.ResolvePath(flowPath, bind.Spec)
+		// Using embedded schema for robustness
+		if err := spec.ValidateYAMLWithSchemaFS(serviceSpecPath, schemas.FS, "servicespec.schema.json"); err != nil {
+			// Fallback to file path method
+			if err := spec.ValidateYAMLWithSchema(serviceSpecPath, "schemas/servicespec.schema.json"); err != nil {
+				exitErr(fmt.Errorf("ServiceSpec structure validation failed (%s): %w", alias, err))
+			}
+		}
+	}
+	fmt.Println("[SCHEMA] ServiceSpec structure validation passed")
+}
+
+// hasLintError 报告是否存在 ERROR 级别的问题
+func hasLintError(issues []validate.LintIssue) bool {
 	for _, is := range issues {
 		if is.Level == "ERROR" {
-			errCount++
+			return true
 		}
-		fmt.Printf("[%s] %s\n", is.Level, is.Msg)
 	}
-	if errCount > 0 {
-		os.Exit(exitcode.InputError)
+	return false
+}
+
+// loadLintConfig 读取 --config 指定的项目配置，未指定时查找 FlowSpec 附近的配置文件
+func loadLintConfig(configPath, flowPath string) *validate.LintConfig {
+	if configPath == "" {
+		configPath = validate.FindProjectConfig(flowPath)
+	}
+	if configPath == "" {
+		return nil
+	}
+	cfg, err := validate.LoadLintConfig(configPath)
+	if err != nil {
+		exitErr(fmt.Errorf("failed to load project config: %w", err))
+	}
+	return cfg
+}
+
+// printLintRules 列出全部规则及其默认级别
+func printLintRules() {
+	for _, r := range validate.LintRules() {
+		level := r.Level
+		if r.Disabled {
+			level = "OFF"
+		}
+		fmt.Printf("%-12s %-20s %-6s %s\n", r.Code, r.Name, level, r.Description)
+	}
+}
+
+// printLintIssue 输出 `[LEVEL] file:line:col: msg (CODE title)`，有修复建议时另起一行
+func printLintIssue(prefix string, is validate.LintIssue) {
+	fmt.Printf("[%s%s] %s\n", prefix, is.Level, is)
+	if is.Hint != "" {
+		fmt.Printf("  hint: %s\n", is.Hint)
+	}
+}
+
+// diagnosticSuffix 为校验结果追加 ` (CODE title) at file:line:col`
+func diagnosticSuffix(code string, pos *spec.Position) string {
+	var s string
+	if code != "" {
+		s = fmt.Sprintf(" (%s %s)", code, validate.DiagnosticTitle(code))
+	}
+	if pos != nil && pos.IsValid() {
+		s += " at " + pos.String()
 	}
+	return s
 }
@@ -0,0 +1,22 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import (
+	"flag"
+	"os"
+
+	"github.com/choreoatlas2025/cli/internal/lsp"
+)
+
+// runLSP 在 stdin/stdout 上运行 Language Server，日志写入 stderr
+func runLSP(args []string) {
+	fs := flag.NewFlagSet("lsp", flag.ExitOnError)
+	_ = fs.Bool("stdio", true, "Communicate over stdin/stdout (the only supported transport)")
+	_ = fs.Parse(args)
+
+	if err := lsp.NewServer(Version, os.Stderr).Run(os.Stdin, os.Stdout); err != nil {
+		exitErr(err)
+	}
+}
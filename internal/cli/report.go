@@ -25,14 +25,15 @@ const (
 )
 
 // WriteReport 生成结构化报告
-func WriteReport(path string, fmtType ReportFormat, steps []validate.StepResult, spans []trace.Span, gateResult *html.GateResult) error {
+// unmatched is the optional unexpected interactions analysis
+func WriteReport(path string, fmtType ReportFormat, steps []validate.StepResult, spans []trace.Span, gateResult *html.GateResult, unmatched *validate.UnmatchedReport) error {
 	switch fmtType {
 	case ReportJSON:
-		return writeJSONReport(path, steps, gateResult)
+		return writeJSONReport(path, steps, gateResult, unmatched)
 	case ReportJUnit:
-		return writeJUnitReport(path, steps, gateResult)
+		return writeJUnitReport(path, steps, gateResult, unmatched)
 	case ReportHTML:
-		return writeHTMLReport(path, steps, spans, gateResult)
+		return writeHTMLReport(path, steps, spans, gateResult, unmatched)
 	default:
 		return fmt.Errorf("Unsupported report format: %s", fmtType)
 	}
@@ -48,9 +49,15 @@ type CoverageSummary struct {
 	ConditionsPass   int               `json:"conditionsPass"`
 	ConditionsFail   int               `json:"conditionsFail"`
 	ConditionsSkip   int               `json:"conditionsSkip"`
+	ConditionsWarn   int               `json:"conditionsWarn"`
 	UncoveredSteps   []string          `json:"uncoveredSteps"`
 	CoverageRate     float64           `json:"coverageRate"`
 	ServiceCoverage  map[string]int    `json:"serviceCoverage"`
+	// Unexpected interactions: spans not covered by any step
+	SpansTotal             int                      `json:"spansTotal"`
+	UnmatchedSpans         int                      `json:"unmatchedSpans"`
+	AllowedUnmatched       int                      `json:"allowedUnmatched"`
+	UnexpectedInteractions []validate.UnmatchedSpan `json:"unexpectedInteractions,omitempty"`
 	// Baseline comparison fields
 	BaselineStepsCoverage    float64 `json:"baselineStepsCoverage,omitempty"`
 	StepsDeltaAbs           float64 `json:"stepsDeltaAbs,omitempty"`
@@ -61,8 +68,9 @@ type CoverageSummary struct {
 }
 
 // writeJSONReport 写入 JSON 格式报告
-func writeJSONReport(path string, steps []validate.StepResult, gateResult *html.GateResult) error {
+func writeJSONReport(path string, steps []validate.StepResult, gateResult *html.GateResult, unmatched *validate.UnmatchedReport) error {
 	summary := calculateCoverageSummary(steps)
+	applyUnmatched(&summary, unmatched)
 
 	// Add baseline comparison fields if available
 	if gateResult != nil && gateResult.Details != nil {
@@ -109,7 +117,7 @@ func writeJSONReport(path string, steps []validate.StepResult, gateResult *html.
 	for _, s := range steps {
 		if s.Status == "PASS" {
 			report.PassedSteps++
-		} else {
+		} else if s.Status != validate.StatusNotApplicable {
 			report.FailedSteps++
 			report.Success = false
 		}
@@ -124,7 +132,7 @@ func writeJSONReport(path string, steps []validate.StepResult, gateResult *html.
 }
 
 // writeJUnitReport 写入 JUnit XML 格式报告
-func writeJUnitReport(path string, steps []validate.StepResult, gateResult *html.GateResult) error {
+func writeJUnitReport(path string, steps []validate.StepResult, gateResult *html.GateResult, unmatched *validate.UnmatchedReport) error {
 	var sb strings.Builder
 	fails := 0
 	for _, s := range steps {
@@ -134,6 +142,7 @@ func writeJUnitReport(path string, steps []validate.StepResult, gateResult *html
 	}
 
 	summary := calculateCoverageSummary(steps)
+	applyUnmatched(&summary, unmatched)
 
 	// JUnit XML header
 	sb.WriteString(`<?xml version="1.0" encoding="UTF-8"?>`)
@@ -159,8 +168,18 @@ func writeJUnitReport(path string, steps []validate.StepResult, gateResult *html
 	sb.WriteString("\n")
 	sb.WriteString(fmt.Sprintf(`    <property name="coverage.conditionsSkip" value="%d"/>`, summary.ConditionsSkip))
 	sb.WriteString("\n")
+	sb.WriteString(fmt.Sprintf(`    <property name="coverage.conditionsWarn" value="%d"/>`, summary.ConditionsWarn))
+	sb.WriteString("\n")
 	sb.WriteString(fmt.Sprintf(`    <property name="coverage.coverageRate" value="%.2f"/>`, summary.CoverageRate))
 	sb.WriteString("\n")
+	if unmatched != nil {
+		sb.WriteString(fmt.Sprintf(`    <property name="coverage.spansTotal" value="%d"/>`, summary.SpansTotal))
+		sb.WriteString("\n")
+		sb.WriteString(fmt.Sprintf(`    <property name="coverage.unmatchedSpans" value="%d"/>`, summary.UnmatchedSpans))
+		sb.WriteString("\n")
+		sb.WriteString(fmt.Sprintf(`    <property name="coverage.allowedUnmatched" value="%d"/>`, summary.AllowedUnmatched))
+		sb.WriteString("\n")
+	}
 
 	// Add baseline comparison properties if available
 	if gateResult != nil && gateResult.Details != nil {
@@ -201,6 +220,10 @@ func writeJUnitReport(path string, steps []validate.StepResult, gateResult *html
 			sb.WriteString(fmt.Sprintf(`    <failure message="%s" type="ValidationFailure">%s</failure>`,
 				xmlEscape(s.Message), xmlEscape(s.Message)))
 			sb.WriteString("\n  ")
+		} else if s.Status == validate.StatusNotApplicable {
+			sb.WriteString("\n")
+			sb.WriteString(fmt.Sprintf(`    <skipped message="%s"/>`, xmlEscape(s.Message)))
+			sb.WriteString("\n  ")
 		}
 		
 		// 添加条件详情到 system-out
@@ -230,7 +253,7 @@ func writeJUnitReport(path string, steps []validate.StepResult, gateResult *html
 }
 
 // writeHTMLReport 写入 HTML 格式报告
-func writeHTMLReport(path string, steps []validate.StepResult, spans []trace.Span, gateResult *html.GateResult) error {
+func writeHTMLReport(path string, steps []validate.StepResult, spans []trace.Span, gateResult *html.GateResult, unmatched *validate.UnmatchedReport) error {
 	// Convert trace spans to HTML span info
 	var spanInfos []html.SpanInfo
 	for _, span := range spans {
@@ -244,6 +267,7 @@ func writeHTMLReport(path string, steps []validate.StepResult, spans []trace.Spa
 
 	// Build HTML data with gate result and CE edition
 	data := html.BuildHTMLData(steps, spanInfos, gateResult, "CE")
+	data.WithUnmatched(unmatched)
 
 	// Write HTML report
 	return html.WriteHTMLReport(path, data)
@@ -255,8 +279,13 @@ func calculateCoverageSummary(steps []validate.StepResult) CoverageSummary {
 		ServiceCoverage: make(map[string]int),
 		UncoveredSteps:  []string{},
 	}
+	notApplicable := 0
 
 	for _, step := range steps {
+		// forbid 规则的结果不是流程步骤，不计入覆盖度
+		if validate.IsForbidResult(step) {
+			continue
+		}
 		summary.StepsTotal++
 		
 		switch step.Status {
@@ -265,9 +294,12 @@ func calculateCoverageSummary(steps []validate.StepResult) CoverageSummary {
 		case "FAIL":
 			summary.StepsFail++
 			summary.UncoveredSteps = append(summary.UncoveredSteps, step.Step)
-		case "SKIP":
+		case "SKIP", validate.StatusNotApplicable:
 			summary.StepsSkip++
 		}
+		if step.Status == validate.StatusNotApplicable {
+			notApplicable++
+		}
 
 		// 统计服务覆盖度
 		if step.Call != "" {
@@ -288,18 +320,31 @@ func calculateCoverageSummary(steps []validate.StepResult) CoverageSummary {
 				summary.ConditionsFail++
 			case "SKIP":
 				summary.ConditionsSkip++
+			case "WARN":
+				summary.ConditionsWarn++
 			}
 		}
 	}
 
-	// 计算覆盖率
-	if summary.StepsTotal > 0 {
-		summary.CoverageRate = float64(summary.StepsPass) / float64(summary.StepsTotal) * 100
+	// 计算覆盖率：与门禁一致，N/A 步骤（可选或未触发的补偿）不计入分母
+	if applicable := summary.StepsTotal - notApplicable; applicable > 0 {
+		summary.CoverageRate = float64(summary.StepsPass) / float64(applicable) * 100
 	}
 
 	return summary
 }
 
+// applyUnmatched 将未声明调用（unexpected interactions）的统计写入覆盖度总结
+func applyUnmatched(summary *CoverageSummary, unmatched *validate.UnmatchedReport) {
+	if unmatched == nil {
+		return
+	}
+	summary.SpansTotal = unmatched.SpansTotal
+	summary.UnmatchedSpans = len(unmatched.Spans)
+	summary.AllowedUnmatched = unmatched.Allowed
+	summary.UnexpectedInteractions = unmatched.Spans
+}
+
 // xmlEscape 转义 XML 特殊字符
 func xmlEscape(s string) string {
 	r := strings.NewReplacer(
@@ -26,7 +26,7 @@ func TestCalculateCoverageSummary(t *testing.T) {
 		},
 		{
 			Step:   "失败步骤",
-			Call:   "serviceB.operation2", 
+			Call:   "serviceB.operation2",
 			Status: "FAIL",
 			Conditions: []validate.ConditionResult{
 				{Kind: "pre", Name: "条件3", Status: "FAIL"},
@@ -112,7 +112,7 @@ func TestWriteJSONReport(t *testing.T) {
 	}
 
 	tempFile := "/tmp/test-report.json"
-	err := writeJSONReport(tempFile, steps, nil) // Pass nil gateResult for basic test
+	err := writeJSONReport(tempFile, steps, nil, nil) // Pass nil gateResult for basic test
 	if err != nil {
 		t.Fatalf("writeJSONReport failed: %v", err)
 	}
@@ -125,13 +125,13 @@ func TestWriteJSONReport(t *testing.T) {
 	}
 
 	var report struct {
-		Timestamp   time.Time                `json:"timestamp"`
-		TotalSteps  int                      `json:"totalSteps"`
-		PassedSteps int                      `json:"passedSteps"`
-		FailedSteps int                      `json:"failedSteps"`
-		Success     bool                     `json:"success"`
-		Steps       []validate.StepResult    `json:"steps"`
-		Summary     CoverageSummary          `json:"summary"`
+		Timestamp   time.Time             `json:"timestamp"`
+		TotalSteps  int                   `json:"totalSteps"`
+		PassedSteps int                   `json:"passedSteps"`
+		FailedSteps int                   `json:"failedSteps"`
+		Success     bool                  `json:"success"`
+		Steps       []validate.StepResult `json:"steps"`
+		Summary     CoverageSummary       `json:"summary"`
 	}
 
 	err = json.Unmarshal(data, &report)
@@ -174,7 +174,7 @@ func TestWriteJUnitReport(t *testing.T) {
 		},
 		{
 			Step:    "失败步骤",
-			Call:    "failService.failOp", 
+			Call:    "failService.failOp",
 			Status:  "FAIL",
 			Message: "测试失败消息",
 			Conditions: []validate.ConditionResult{
@@ -184,7 +184,7 @@ func TestWriteJUnitReport(t *testing.T) {
 	}
 
 	tempFile := "/tmp/test-junit.xml"
-	err := writeJUnitReport(tempFile, steps, nil) // Pass nil gateResult for basic test
+	err := writeJUnitReport(tempFile, steps, nil, nil) // Pass nil gateResult for basic test
 	if err != nil {
 		t.Fatalf("writeJUnitReport failed: %v", err)
 	}
@@ -232,7 +232,7 @@ func TestWriteJUnitReport(t *testing.T) {
 		t.Error("JUnit XML should contain conditions in system-out")
 	}
 
-	// 验证覆盖度摘要在最终的system-out中  
+	// 验证覆盖度摘要在最终的system-out中
 	if !strings.Contains(content, `"stepsTotal": 2`) {
 		t.Error("JUnit XML should contain coverage summary in system-out")
 	}
@@ -269,7 +269,7 @@ func TestWriteReportFormats(t *testing.T) {
 
 	// 测试JSON格式
 	jsonFile := "/tmp/test-format.json"
-	err := WriteReport(jsonFile, ReportJSON, steps, nil, nil)
+	err := WriteReport(jsonFile, ReportJSON, steps, nil, nil, nil)
 	if err != nil {
 		t.Errorf("WriteReport JSON failed: %v", err)
 	} else {
@@ -278,7 +278,7 @@ func TestWriteReportFormats(t *testing.T) {
 
 	// 测试JUnit格式
 	xmlFile := "/tmp/test-format.xml"
-	err = WriteReport(xmlFile, ReportJUnit, steps, nil, nil)
+	err = WriteReport(xmlFile, ReportJUnit, steps, nil, nil, nil)
 	if err != nil {
 		t.Errorf("WriteReport JUnit failed: %v", err)
 	} else {
@@ -286,7 +286,7 @@ func TestWriteReportFormats(t *testing.T) {
 	}
 
 	// 测试不支持的格式
-	err = WriteReport("/tmp/test-unknown.txt", "unknown", steps, nil, nil)
+	err = WriteReport("/tmp/test-unknown.txt", "unknown", steps, nil, nil, nil)
 	if err == nil {
 		t.Error("WriteReport should fail for unknown format")
 	}
@@ -300,7 +300,7 @@ func TestEmptyStepsReport(t *testing.T) {
 	var steps []validate.StepResult
 
 	summary := calculateCoverageSummary(steps)
-	
+
 	if summary.StepsTotal != 0 {
 		t.Errorf("Expected StepsTotal 0 for empty steps, got %d", summary.StepsTotal)
 	}
@@ -318,7 +318,7 @@ func TestServiceCoverageExtraction(t *testing.T) {
 		{Call: "service1.op2", Status: "PASS"},
 		{Call: "service2.op1", Status: "FAIL"},
 		{Call: "invalid.call.format", Status: "PASS"}, // 应该被忽略
-		{Call: "", Status: "PASS"}, // 应该被忽略
+		{Call: "", Status: "PASS"},                    // 应该被忽略
 	}
 
 	summary := calculateCoverageSummary(steps)
@@ -326,7 +326,7 @@ func TestServiceCoverageExtraction(t *testing.T) {
 	expectedServices := map[string]int{
 		"service1": 2,
 		"service2": 1,
-		"invalid": 1, // invalid.call.format被解析为invalid服务
+		"invalid":  1, // invalid.call.format被解析为invalid服务
 	}
 
 	if len(summary.ServiceCoverage) != len(expectedServices) {
@@ -340,4 +340,61 @@ func TestServiceCoverageExtraction(t *testing.T) {
 			t.Errorf("Expected service %s count %d, got %d", service, expectedCount, actualCount)
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestCoverageSummary_UnmatchedCounts(t *testing.T) {
+	steps := []validate.StepResult{{Step: "s1", Call: "svc.op", Status: "PASS"}}
+	unmatched := &validate.UnmatchedReport{
+		SpansTotal: 3,
+		Allowed:    1,
+		Spans:      []validate.UnmatchedSpan{{SpanID: "x", Service: "svc", Name: "undeclared"}},
+	}
+
+	summary := calculateCoverageSummary(steps)
+	applyUnmatched(&summary, unmatched)
+	if summary.SpansTotal != 3 || summary.UnmatchedSpans != 1 || summary.AllowedUnmatched != 1 {
+		t.Errorf("unexpected unmatched summary: %+v", summary)
+	}
+	if len(summary.UnexpectedInteractions) != 1 || summary.UnexpectedInteractions[0].Name != "undeclared" {
+		t.Errorf("expected unexpected interaction listed, got %+v", summary.UnexpectedInteractions)
+	}
+}
+
+func TestCoverageSummary_SkipsForbidResults(t *testing.T) {
+	steps := []validate.StepResult{
+		{Step: "s1", Call: "svc.op", Status: "PASS"},
+		{Step: "s2", Call: "svc.other", Status: "FAIL"},
+		{Step: "forbid: no-direct-db", Call: "dbService.*", Status: "PASS"},
+		{Step: "forbid: no-refund", Call: "paymentService.refund", Status: "FAIL", Code: validate.CodeForbiddenCall},
+	}
+
+	summary := calculateCoverageSummary(steps)
+	if summary.StepsTotal != 2 || summary.StepsPass != 1 || summary.StepsFail != 1 {
+		t.Errorf("forbid results should not count as steps: %+v", summary)
+	}
+	if summary.CoverageRate != 50 {
+		t.Errorf("expected 50%% coverage, got %.1f", summary.CoverageRate)
+	}
+	if len(summary.UncoveredSteps) != 1 || summary.UncoveredSteps[0] != "s2" {
+		t.Errorf("expected only s2 uncovered, got %v", summary.UncoveredSteps)
+	}
+	if _, ok := summary.ServiceCoverage["paymentService"]; ok {
+		t.Errorf("forbid results should not count towards service coverage: %v", summary.ServiceCoverage)
+	}
+}
+
+func TestCoverageSummary_NotApplicableExcludedFromRate(t *testing.T) {
+	steps := []validate.StepResult{
+		{Step: "reserve", Call: "inventoryService.reserve", Status: "PASS"},
+		{Step: "charge", Call: "paymentService.charge", Status: "PASS"},
+		{Step: "release", Call: "inventoryService.release", Status: validate.StatusNotApplicable},
+	}
+
+	summary := calculateCoverageSummary(steps)
+	if summary.StepsTotal != 3 || summary.StepsSkip != 1 {
+		t.Errorf("unexpected step counts: %+v", summary)
+	}
+	if summary.CoverageRate != 100 {
+		t.Errorf("N/A steps should not lower coverage, got %.1f", summary.CoverageRate)
+	}
+}
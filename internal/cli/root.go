@@ -67,6 +67,10 @@ func Execute() {
 		runCIGate(os.Args[2:])
 	case "baseline":
 		runBaseline(os.Args[2:])
+	case "analyze":
+		runAnalyze(os.Args[2:])
+	case "lsp":
+		runLSP(os.Args[2:])
 	case "flowspec":
 		runFlowspec(os.Args[2:])
 	case "spec":
@@ -99,7 +103,7 @@ Usage:
 
 Domain commands:
   spec        Flow/Service specifications (discover | lint | validate | convert)
-  run         Runtime validation (validate)
+  run         Runtime validation (validate | analyze)
   workspace   Collaboration tooling (not yet available in CE)
   platform    Deployment & governance (not yet available in CE)
   plugin      Plugin management (not yet available in CE)
@@ -113,6 +117,8 @@ Top-level aliases:
   discover   ≙ spec discover
   ci-gate    Composite CI gate (lint + validate, CE)
   baseline   Baseline recorder (record)
+  analyze    Conformance statistics and behavioral variants across many traces
+  lsp        Language Server for FlowSpec/ServiceSpec files (stdio)
 
 Key flags:
   --format <human|json|ndjson|junit|html>  Command-specific machine readable output
@@ -180,7 +186,7 @@ Usage:
 
 Commands:
   discover  From trace to initial ServiceSpec + FlowSpec
-    --trace <file> --out <path> [--title <text>]
+    --trace <file|dir> --out <path> [--title <text>] [--format flow|graph] [--merge [--dry-run]]
   lint      Static checks (structure + coherence + variables + parallel reachability)
     --flow <file> [--schema]
   validate  Alias of lint for spec-level validation
@@ -197,7 +203,7 @@ func printRunHelp() {
 	fmt.Print(`Run domain (CE)
 
 Usage:
-  choreoatlas run validate [options]
+  choreoatlas run <validate|analyze> [options]
 
 validate options:
   --flow <file> --trace <file>
@@ -206,6 +212,10 @@ validate options:
   --report-format <json|junit|html> --report-out <file> [--summary]
   --causality <strict|temporal|off>
 
+analyze options:
+  --flow <file> --traces <dir>
+  --format <human|json> [--out <file>] [--rare-threshold <float>]
+
 Notes:
   - --summary writes GitHub Step Summary when GITHUB_STEP_SUMMARY is present.
   - With --format json stdout emits exactly one JSON object; with ndjson one JSON object per line.
@@ -297,6 +307,8 @@ func runRunGroup(args []string) {
     switch sub {
     case "validate":
         runValidate(rest)
+    case "analyze":
+        runAnalyze(rest)
     default:
         fmt.Fprintf(os.Stderr, "Unknown run subcommand: %s\n\n", sub)
         printRunHelp()
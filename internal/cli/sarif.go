@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/choreoatlas2025/cli/internal/report/sarif"
+	"github.com/choreoatlas2025/cli/internal/validate"
+)
+
+// sarifOutput 收集 --format sarif 的结果。SARIF 写入 stdout，
+// 其余控制台输出在此期间转到 stderr，以保证 stdout 是合法的 JSON。
+type sarifOutput struct {
+	out     *os.File
+	builder *sarif.Builder
+	flushed bool
+}
+
+// activeSARIF 是正在收集的输出；嵌套调用的命令（如 ci-gate 调用 validate）共用同一个 SARIF 文档
+var activeSARIF *sarifOutput
+
+// startSARIF 校验 --format；为 sarif 时开始收集，text 时返回 nil
+func startSARIF(format string) *sarifOutput {
+	switch format {
+	case "", "text":
+		return nil
+	case "sarif":
+	default:
+		exitErr(fmt.Errorf("Unsupported output format: %s (supported: text|sarif)", format))
+	}
+	if activeSARIF != nil {
+		return activeSARIF
+	}
+	activeSARIF = &sarifOutput{out: os.Stdout, builder: sarif.New(Version)}
+	os.Stdout = os.Stderr
+	return activeSARIF
+}
+
+func (s *sarifOutput) addLint(flowPath string, issues []validate.LintIssue) {
+	if s != nil {
+		s.builder.AddLintIssues(flowPath, issues)
+	}
+}
+
+func (s *sarifOutput) addResults(flowPath string, results []validate.StepResult) {
+	if s != nil {
+		s.builder.AddStepResults(flowPath, results)
+	}
+}
+
+// flush 写出 SARIF 日志并恢复 stdout；只在第一次调用时写出
+func (s *sarifOutput) flush() {
+	if s == nil || s.flushed {
+		return
+	}
+	s.flushed = true
+	activeSARIF = nil
+	os.Stdout = s.out
+	if err := s.builder.Write(s.out); err != nil {
+		exitErr(fmt.Errorf("failed to write SARIF: %w", err))
+	}
+}
@@ -0,0 +1,56 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestSARIFStdoutIsSingleJSONDocument(t *testing.T) {
+	repoRoot, err := filepath.Abs(filepath.Join("..", ".."))
+	if err != nil {
+		t.Fatalf("failed to resolve repo root: %v", err)
+	}
+	flowPath := filepath.Join(repoRoot, "examples", "flows", "order-fulfillment.flowspec.yaml")
+
+	binPath := filepath.Join(t.TempDir(), "choreoatlas.testbin")
+	build := exec.Command("go", "build", "-o", binPath, "./cmd/choreoatlas")
+	build.Dir = repoRoot
+	build.Env = os.Environ()
+	if output, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("failed to build choreoatlas binary: %v\n%s", err, output)
+	}
+
+	for _, command := range []string{"validate", "ci-gate"} {
+		for _, traceFile := range []string{"successful-order.trace.json", "failed-inventory.trace.json"} {
+			t.Run(command+"/"+traceFile, func(t *testing.T) {
+				tracePath := filepath.Join(repoRoot, "examples", "traces", traceFile)
+				cmd := exec.Command(binPath, command, "--flow", flowPath, "--trace", tracePath, "--format", "sarif")
+				cmd.Dir = repoRoot
+
+				stdout, err := cmd.Output() // stderr 中的控制台输出不影响 stdout
+				var exitErr *exec.ExitError
+				if err != nil && !errors.As(err, &exitErr) {
+					t.Fatalf("failed to run %s: %v", command, err)
+				}
+
+				var log struct {
+					Version string            `json:"version"`
+					Runs    []json.RawMessage `json:"runs"`
+				}
+				if err := json.Unmarshal(stdout, &log); err != nil {
+					t.Fatalf("stdout is not a single JSON document: %v\n%s", err, stdout)
+				}
+				if log.Version != "2.1.0" || len(log.Runs) != 1 {
+					t.Errorf("unexpected SARIF log: version %q, %d runs", log.Version, len(log.Runs))
+				}
+			})
+		}
+	}
+}
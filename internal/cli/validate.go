@@ -8,6 +8,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/choreoatlas2025/cli/internal/baseline"
 	"github.com/choreoatlas2025/cli/internal/cli/exitcode"
@@ -31,12 +32,19 @@ func runValidate(args []string) {
 	causalityMode := fs.String("causality", "temporal", "Causality check mode: strict|temporal|off (default: temporal)")
 	causalityTolerance := fs.Int("causality-tolerance", 50, "Causality constraint tolerance in milliseconds (default: 50ms)")
 	baselineMissing := fs.String("baseline-missing", "fail", "Baseline missing strategy: fail|treat-as-absolute")
+	explain := fs.Bool("explain", false, "List the nearest candidate spans for failed steps and why they were rejected")
+	unmatchedKinds := fs.String("unmatched-kinds", "", "Only report unmatched spans of these kinds, comma-separated (e.g. server,producer)")
+	unmatchedDepth := fs.Int("unmatched-max-depth", -1, "Only report unmatched spans up to this depth in the span tree (-1: unlimited)")
+	strictUnmatched := fs.Bool("strict-unmatched", false, "Fail the gate when spans are not covered by any step or allowUnmatched pattern")
+	configPath := fs.String("config", "", "Project config file (default: nearest "+validate.ProjectConfigFile+")")
+	format := fs.String("format", "text", "Output format: text|sarif (SARIF is written to stdout, console output to stderr)")
 	_ = fs.Parse(args)
 
 	// Input parameter validation
 	if *tracePath == "" {
 		exitErr(errors.New("--trace parameter is required"))
 	}
+	so := startSARIF(*format)
 
 	flow, err := spec.LoadFlowSpec(*flowPath)
 	if err != nil {
@@ -48,18 +56,18 @@ func runValidate(args []string) {
 	}
 
 	// lint 检查
-	issues, err := validate.LintFlow(*flowPath, flow, opIndex)
+	issues, err := validate.LintFlow(*flowPath, flow, opIndex, loadLintConfig(*configPath, *flowPath))
 	if err != nil {
 		exitErr(err)
 	}
 	for _, is := range issues {
-		fmt.Printf("[LINT-%s] %s\n", is.Level, is.Msg)
+		printLintIssue("LINT-", is)
 	}
-	for _, is := range issues {
-		if is.Level == "ERROR" {
-			fmt.Println("Lint contains ERROR, terminating Validate")
-			os.Exit(exitcode.InputError)
-		}
+	so.addLint(*flowPath, issues)
+	if hasLintError(issues) {
+		fmt.Println("Lint contains ERROR, terminating Validate")
+		so.flush()
+		os.Exit(exitcode.InputError)
 	}
 
 	// Load trace data
@@ -87,6 +95,18 @@ func runValidate(args []string) {
 	validate.GlobalCausalityToleranceMs = int64(*causalityTolerance)
 
 	results, ok := validate.ValidateAgainstTrace(flow, opIndex, tr)
+	if *explain {
+		validate.ExplainFailures(flow, opIndex, tr, results)
+	}
+
+	// Unexpected interactions: spans no step accounts for
+	var kinds []string
+	for _, k := range strings.Split(*unmatchedKinds, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			kinds = append(kinds, k)
+		}
+	}
+	unmatched := validate.FindUnmatchedSpans(flow, tr, results, validate.UnmatchedOptions{Kinds: kinds, MaxDepth: *unmatchedDepth})
 
 	// Baseline gate check
 	var gateResult *baseline.GateResult
@@ -116,6 +136,13 @@ func runValidate(args []string) {
 		SkipAsFail:          *skipAsFail,
 	}
 	gateResult = baseline.EvaluateGate(results, thresholds, baselineData)
+	gateResult.Details["unmatchedSpans"] = len(unmatched.Spans)
+	if *strictUnmatched && len(unmatched.Spans) > 0 {
+		gateResult.Checked = true
+		gateResult.Passed = false
+		gateResult.Violations = append(gateResult.Violations,
+			fmt.Sprintf("%d unexpected interactions not declared by any step (strict unmatched mode)", len(unmatched.Spans)))
+	}
 
 	// Generate report (if format and path specified)
 	if *reportFormat != "" && *reportOut != "" {
@@ -135,13 +162,14 @@ func runValidate(args []string) {
 		var htmlGateResult *html.GateResult
 		if gateResult != nil {
 			htmlGateResult = &html.GateResult{
-				Checked: gateResult.Checked,
-				Passed:  gateResult.Passed,
-				Details: gateResult.Details,
+				Checked:    gateResult.Checked,
+				Passed:     gateResult.Passed,
+				Details:    gateResult.Details,
+				Violations: gateResult.Violations,
 			}
 		}
 
-		if err := WriteReport(*reportOut, format, results, tr.Spans, htmlGateResult); err != nil {
+		if err := WriteReport(*reportOut, format, results, tr.Spans, htmlGateResult, &unmatched); err != nil {
 			exitErr(fmt.Errorf("Failed to generate report: %w", err))
 		}
 		fmt.Printf("Report saved: %s (format: %s)\n", *reportOut, *reportFormat)
@@ -150,11 +178,33 @@ func runValidate(args []string) {
 	// Console output
 	for _, r := range results {
 		if r.Status == "PASS" {
-			fmt.Printf("[PASS] %s (%s)\n", r.Step, r.Call)
+			if r.MatchReason != "" && r.MatchReason != "span name" && r.MatchReason != "operation id" {
+				fmt.Printf("[PASS] %s (%s) - matched by %s\n", r.Step, r.Call, r.MatchReason)
+			} else {
+				fmt.Printf("[PASS] %s (%s)\n", r.Step, r.Call)
+			}
+		} else if r.Status == validate.StatusNotApplicable {
+			fmt.Printf("[N/A] %s (%s) - %s\n", r.Step, r.Call, r.Message)
 		} else {
-			fmt.Printf("[FAIL] %s (%s) - %s\n", r.Step, r.Call, r.Message)
+			fmt.Printf("[FAIL] %s (%s) - %s%s\n", r.Step, r.Call, r.Message, diagnosticSuffix(r.Code, r.Position))
+			printCandidates(r.Candidates, tr.Spans)
+			for _, v := range r.Violations {
+				fmt.Printf("  [DAG Violation] %s: %s\n", v.Type, v.Message)
+			}
+		}
+		for _, c := range r.Conditions {
+			if c.Status == "WARN" {
+				fmt.Printf("  [WARN] %s:%s (%s) - %s%s\n", c.Kind, c.Name, c.Severity, c.Message, diagnosticSuffix(c.Code, c.Position))
+			}
 		}
 	}
+	for _, u := range unmatched.Spans {
+		kind := u.Kind
+		if kind == "" {
+			kind = "unknown kind"
+		}
+		fmt.Printf("[UNEXPECTED] %s.%s (%s, depth %d) - span not declared by any step\n", u.Service, u.Name, kind, u.Depth)
+	}
 
 	// Gate result output and exit code determination
 	if gateResult != nil && gateResult.Checked {
@@ -203,13 +253,44 @@ func runValidate(args []string) {
 		}
 	}
 
+	gateFailed := gateResult != nil && gateResult.Checked && !gateResult.Passed
+	if ok && !gateFailed {
+		fmt.Println("Validate: OK")
+	}
+	// SARIF 在所有控制台输出之后写出，保证 stdout 只有 SARIF 文档
+	so.addResults(*flowPath, results)
+	so.flush()
+
 	// Exit code determination: validation failure or gate failure should exit non-zero
 	if !ok {
 		os.Exit(exitcode.ValidationFailed) // Validation failed
 	}
-	if gateResult != nil && gateResult.Checked && !gateResult.Passed {
+	if gateFailed {
 		os.Exit(exitcode.GateFailed) // Gate failed
 	}
-	
-	fmt.Println("Validate: OK")
-}
\ No newline at end of file
+}
+
+// printCandidates prints explain-mode candidates below a failed step
+func printCandidates(cands []validate.Candidate, spans []trace.Span) {
+	if len(cands) == 0 {
+		return
+	}
+	var traceStart int64
+	for i, sp := range spans {
+		if i == 0 || sp.StartNanos < traceStart {
+			traceStart = sp.StartNanos
+		}
+	}
+	fmt.Println("       nearest candidates:")
+	for i, c := range cands {
+		name := c.Name
+		if !strings.EqualFold(c.OperationID, c.Name) {
+			name += " [" + c.OperationID + "]"
+		}
+		fmt.Printf("       %d. %s.%s @ +%.1fms score=%.2f\n", i+1, c.Service, name,
+			float64(c.StartNanos-traceStart)/1e6, c.Score)
+		for _, why := range c.Rejected {
+			fmt.Printf("          - %s\n", why)
+		}
+	}
+}
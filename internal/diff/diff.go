@@ -0,0 +1,157 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package diff computes line based edit scripts and unified diffs for spec files.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op is the kind of a line edit
+type Op int
+
+const (
+	Equal Op = iota
+	Delete
+	Insert
+)
+
+// Edit is one line of an edit script
+type Edit struct {
+	Op   Op
+	Line string
+}
+
+// SplitLines splits text into lines without their trailing newline
+func SplitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// Lines returns a minimal edit script turning a into b (longest common subsequence)
+func Lines(a, b []string) []Edit {
+	// lcs[i][j] = LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var edits []Edit
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			edits = append(edits, Edit{Equal, a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			edits = append(edits, Edit{Delete, a[i]})
+			i++
+		default:
+			edits = append(edits, Edit{Insert, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		edits = append(edits, Edit{Delete, a[i]})
+	}
+	for ; j < len(b); j++ {
+		edits = append(edits, Edit{Insert, b[j]})
+	}
+	return edits
+}
+
+// Unified renders a unified diff with three lines of context; empty if a == b
+func Unified(fromName, toName, a, b string) string {
+	if a == b {
+		return ""
+	}
+	const context = 3
+	edits := Lines(SplitLines(a), SplitLines(b))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+
+	// Line numbers (1-based) of each edit in a and b
+	aLine, bLine := make([]int, len(edits)), make([]int, len(edits))
+	ai, bi := 1, 1
+	for k, e := range edits {
+		aLine[k], bLine[k] = ai, bi
+		if e.Op != Insert {
+			ai++
+		}
+		if e.Op != Delete {
+			bi++
+		}
+	}
+
+	for k := 0; k < len(edits); {
+		if edits[k].Op == Equal {
+			k++
+			continue
+		}
+		// Grow the hunk while changes are within 2*context lines of each other
+		start := max(k-context, 0)
+		end := k
+		for end < len(edits) {
+			if edits[end].Op != Equal {
+				end++
+				continue
+			}
+			run := end
+			for run < len(edits) && edits[run].Op == Equal {
+				run++
+			}
+			if run == len(edits) || run-end > 2*context {
+				end = min(end+context, len(edits))
+				break
+			}
+			end = run
+		}
+
+		aCount, bCount := 0, 0
+		for _, e := range edits[start:end] {
+			if e.Op != Insert {
+				aCount++
+			}
+			if e.Op != Delete {
+				bCount++
+			}
+		}
+		aStart, bStart := aLine[start], bLine[start]
+		if aCount == 0 {
+			aStart--
+		}
+		if bCount == 0 {
+			bStart--
+		}
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", aStart, aCount, bStart, bCount)
+		for _, e := range edits[start:end] {
+			prefix := " "
+			switch e.Op {
+			case Delete:
+				prefix = "-"
+			case Insert:
+				prefix = "+"
+			}
+			sb.WriteString(prefix + e.Line + "\n")
+		}
+		k = end
+	}
+	return sb.String()
+}
@@ -0,0 +1,39 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package diff
+
+import "testing"
+
+func TestUnified(t *testing.T) {
+	a := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	b := "a\nb\nX\nd\ne\nf\ng\nh\ni\nj\nk\n"
+	want := `--- old
++++ new
+@@ -1,6 +1,6 @@
+ a
+ b
+-c
++X
+ d
+ e
+ f
+@@ -8,3 +8,4 @@
+ h
+ i
+ j
++k
+`
+	if got := Unified("old", "new", a, b); got != want {
+		t.Errorf("unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+	if got := Unified("old", "new", a, a); got != "" {
+		t.Errorf("expected empty diff for equal input, got %q", got)
+	}
+}
+
+func TestUnified_EmptySide(t *testing.T) {
+	want := "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n"
+	if got := Unified("old", "new", "", "a\nb\n"); got != want {
+		t.Errorf("unexpected diff:\n%s", got)
+	}
+}
@@ -0,0 +1,445 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/choreoatlas2025/cli/internal/schemas"
+	"github.com/choreoatlas2025/cli/internal/spec"
+)
+
+// complete 按光标上下文给出补全：${var}、call 值、compensate 值、Schema 中的键与枚举值
+func (s *Server) complete(d *document, pos Position) []CompletionItem {
+	prefix := d.prefix(pos)
+	if d.kind == kindFlow {
+		if open := strings.LastIndex(prefix, "${"); open >= 0 && !strings.Contains(prefix[open:], "}") {
+			return s.completeVariables(d, pos.Line+1)
+		}
+	}
+	ctx := cursorContext(d.lines, pos.Line, prefix)
+	if ctx.value {
+		if d.kind == kindFlow && d.flow != nil {
+			switch {
+			case ctx.key == "call" && !ctx.under("forbid"):
+				return s.completeCalls(d)
+			case ctx.key == "compensate":
+				return completeSteps(d.flow)
+			}
+		}
+		return schemaValues(schemaFor(d.kind), append(ctx.path, ctx.key))
+	}
+	present := siblingKeys(d.lines, pos.Line, ctx.indent, ctx.itemStart)
+	var items []CompletionItem
+	for _, item := range schemaKeys(schemaFor(d.kind), ctx.path) {
+		if !present[item.Label] {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// completeCalls 列出所绑定 ServiceSpec 中的全部 alias.operationId
+func (s *Server) completeCalls(d *document) []CompletionItem {
+	_, opIndex, err := d.flow.BuildOperationIndex(d.path)
+	if err != nil {
+		s.logger.Printf("completion: %v", err)
+		return nil
+	}
+	var items []CompletionItem
+	for _, alias := range sortedKeys(opIndex) {
+		for _, id := range sortedKeys(opIndex[alias]) {
+			op := opIndex[alias][id]
+			items = append(items, CompletionItem{
+				Label:         alias + "." + id,
+				Kind:          kindFunction,
+				Detail:        op.Description,
+				Documentation: &MarkupContent{Kind: "markdown", Value: operationMarkdown(alias, op)},
+			})
+		}
+	}
+	return items
+}
+
+// completeSteps 列出全部步骤名 / 节点 ID
+func completeSteps(fs *spec.FlowSpec) []CompletionItem {
+	var items []CompletionItem
+	for _, c := range flowSteps(fs) {
+		items = append(items, CompletionItem{Label: c.name, Kind: kindReference, Detail: c.call})
+	}
+	return items
+}
+
+// completeVariables 列出光标所在步骤可引用的变量：初始变量与上游步骤的输出
+func (s *Server) completeVariables(d *document, line int) []CompletionItem {
+	fs := d.flow
+	if fs == nil {
+		return nil
+	}
+	cfg, _ := lintConfigFor(d.path)
+	var items []CompletionItem
+	for _, v := range cfg.InitialVars() {
+		items = append(items, CompletionItem{Label: v, Kind: kindVariable, Detail: "initial variable"})
+	}
+	for _, st := range upstreamSteps(fs, fs.Source.StepAt(line)) {
+		for _, name := range sortedKeys(st.output) {
+			items = append(items, CompletionItem{
+				Label:  name,
+				Kind:   kindVariable,
+				Detail: fmt.Sprintf("output of %s: %s", st.name, st.output[name]),
+			})
+		}
+	}
+	return items
+}
+
+// flowStep 是一个步骤或 graph 节点中补全与跳转关心的部分
+type flowStep struct {
+	name, call string
+	output     map[string]string
+}
+
+// flowSteps 按声明顺序列出全部步骤（含 parallel 子步骤）或节点
+func flowSteps(fs *spec.FlowSpec) []flowStep {
+	var out []flowStep
+	if fs.IsGraphMode() {
+		for _, n := range fs.Graph.Nodes {
+			out = append(out, flowStep{n.ID, n.Call, n.Output})
+		}
+		return out
+	}
+	for _, st := range fs.Flow {
+		if st.Step != "" {
+			out = append(out, flowStep{st.Step, st.Call, st.Output})
+		}
+		for _, pst := range st.Parallel {
+			out = append(out, flowStep{pst.Step, pst.Call, pst.Output})
+		}
+	}
+	return out
+}
+
+// upstreamSteps 返回 current 之前一定已完成的步骤：flow 中为之前的步骤组（不含并发的兄弟步骤），
+// graph 中为经 depends/edges 可达的祖先节点。current 为空（光标不在步骤内）时返回全部步骤。
+func upstreamSteps(fs *spec.FlowSpec, current string) []flowStep {
+	all := flowSteps(fs)
+	if current == "" {
+		return all
+	}
+	if fs.IsGraphMode() {
+		parents := map[string][]string{}
+		for _, e := range fs.Graph.Edges {
+			parents[e.To] = append(parents[e.To], e.From)
+		}
+		ancestors := map[string]bool{}
+		queue := []string{current}
+		for len(queue) > 0 {
+			cur := queue[0]
+			queue = queue[1:]
+			for _, p := range parents[cur] {
+				if !ancestors[p] {
+					ancestors[p] = true
+					queue = append(queue, p)
+				}
+			}
+		}
+		var out []flowStep
+		for _, st := range all {
+			if ancestors[st.name] {
+				out = append(out, st)
+			}
+		}
+		return out
+	}
+	var out []flowStep
+	for _, group := range fs.Flow {
+		members := group.Parallel
+		if len(members) == 0 {
+			members = []spec.FlowStep{group}
+		}
+		for _, m := range members {
+			if m.Step == current {
+				return out
+			}
+		}
+		for _, m := range members {
+			out = append(out, flowStep{m.Step, m.Call, m.Output})
+		}
+	}
+	return out
+}
+
+// context 描述光标在 YAML 结构中的位置
+type context struct {
+	path      []string // 光标所在映射的路径；"[]" 表示序列元素
+	key       string   // 值补全时光标所在的键
+	value     bool     // 光标在 `key: ` 之后
+	indent    int      // 键补全时键所在的列
+	itemStart bool     // 光标所在行以 "- " 开始新的序列元素
+}
+
+func (c context) under(key string) bool {
+	for _, p := range c.path {
+		if p == key {
+			return true
+		}
+	}
+	return false
+}
+
+// lineInfo 是一行 YAML 的缩进结构
+type lineInfo struct {
+	blank bool
+	dash  int    // "- " 所在列，没有时为 -1
+	col   int    // 键（或标量）开始的列
+	key   string // `key:` 中的键，不是键值行时为空
+	rest  string // 冒号之后的内容
+}
+
+var keyRe = regexp.MustCompile(`^("[^"]*"|'[^']*'|[^\s:#'"][^:#]*?):(?:\s|$)`)
+
+func parseLine(line string) lineInfo {
+	indent := len(line) - len(strings.TrimLeft(line, " "))
+	body := line[indent:]
+	if body == "" || strings.HasPrefix(body, "#") {
+		return lineInfo{blank: true, dash: -1}
+	}
+	li := lineInfo{dash: -1, col: indent}
+	if body == "-" || strings.HasPrefix(body, "- ") {
+		li.dash = indent
+		trimmed := strings.TrimLeft(body[1:], " ")
+		li.col = indent + len(body) - len(trimmed)
+		body = trimmed
+	}
+	if m := keyRe.FindStringSubmatch(body + " "); m != nil {
+		li.key = strings.Trim(m[1], `"'`)
+		li.rest = strings.TrimSpace(body[min(len(m[0]), len(body)):])
+	}
+	return li
+}
+
+// cursorContext 通过缩进向上查找父级键，得到光标所在的 YAML 路径
+func cursorContext(lines []string, lineIdx int, prefix string) context {
+	var ctx context
+	cur := parseLine(prefix)
+	ctx.indent = cur.col
+	if cur.blank && strings.TrimSpace(prefix) == "" {
+		ctx.indent = len(prefix)
+	}
+	if cur.key != "" && strings.Contains(prefix[cur.col:], ":") {
+		ctx.value, ctx.key = true, cur.key
+	}
+	var rev []string
+	col := ctx.indent
+	if cur.dash >= 0 {
+		ctx.itemStart = true
+		rev = append(rev, "[]")
+		col = cur.dash
+	}
+	for i := lineIdx - 1; i >= 0 && col > 0; i-- {
+		li := parseLine(lines[i])
+		if li.blank {
+			continue
+		}
+		if li.key != "" && li.col < col {
+			rev = append(rev, li.key)
+			col = li.col
+		}
+		if li.dash >= 0 && li.dash < col {
+			rev = append(rev, "[]")
+			col = li.dash
+		}
+	}
+	for i := len(rev) - 1; i >= 0; i-- {
+		ctx.path = append(ctx.path, rev[i])
+	}
+	return ctx
+}
+
+// siblingKeys 返回与光标处于同一映射、已经写出的键
+func siblingKeys(lines []string, lineIdx, col int, itemStart bool) map[string]bool {
+	keys := map[string]bool{}
+	if !itemStart {
+		for i := lineIdx - 1; i >= 0; i-- {
+			li := parseLine(lines[i])
+			if li.blank {
+				continue
+			}
+			if li.col < col {
+				break
+			}
+			if li.col == col && li.key != "" {
+				keys[li.key] = true
+				if li.dash >= 0 {
+					break // 序列元素的第一行
+				}
+			}
+		}
+	}
+	for i := lineIdx + 1; i < len(lines); i++ {
+		li := parseLine(lines[i])
+		if li.blank {
+			continue
+		}
+		if li.col < col || (li.dash >= 0 && li.dash < col) {
+			break
+		}
+		if li.col == col && li.key != "" {
+			keys[li.key] = true
+		}
+	}
+	return keys
+}
+
+// JSON Schema 导航
+
+type schema struct {
+	root map[string]any
+}
+
+var flowSchema, serviceSchema = loadSchema("flowspec.schema.json"), loadSchema("servicespec.schema.json")
+
+func loadSchema(name string) *schema {
+	b, err := schemas.FS.ReadFile(name)
+	if err != nil {
+		panic(err)
+	}
+	var root map[string]any
+	if err := json.Unmarshal(b, &root); err != nil {
+		panic(fmt.Sprintf("%s: %v", name, err))
+	}
+	return &schema{root: root}
+}
+
+func schemaFor(kind docKind) *schema {
+	if kind == kindService {
+		return serviceSchema
+	}
+	return flowSchema
+}
+
+// expand 展开 $ref 与 oneOf/anyOf/allOf，返回全部候选子模式
+func (s *schema) expand(node map[string]any) []map[string]any {
+	if node == nil {
+		return nil
+	}
+	if ref, ok := node["$ref"].(string); ok && strings.HasPrefix(ref, "#/") {
+		target := s.root
+		for _, part := range strings.Split(ref[2:], "/") {
+			next, _ := target[part].(map[string]any)
+			target = next
+		}
+		return s.expand(target)
+	}
+	out := []map[string]any{node}
+	for _, key := range []string{"oneOf", "anyOf", "allOf"} {
+		branches, _ := node[key].([]any)
+		for _, b := range branches {
+			if m, ok := b.(map[string]any); ok {
+				out = append(out, s.expand(m)...)
+			}
+		}
+	}
+	return out
+}
+
+// at 返回路径处的候选子模式
+func (s *schema) at(path []string) []map[string]any {
+	nodes := s.expand(s.root)
+	for _, part := range path {
+		var next []map[string]any
+		for _, n := range nodes {
+			if part == "[]" {
+				if items, ok := n["items"].(map[string]any); ok {
+					next = append(next, s.expand(items)...)
+				}
+				continue
+			}
+			if props, ok := n["properties"].(map[string]any); ok {
+				if p, ok := props[part].(map[string]any); ok {
+					next = append(next, s.expand(p)...)
+					continue
+				}
+			}
+			if ap, ok := n["additionalProperties"].(map[string]any); ok {
+				next = append(next, s.expand(ap)...)
+			}
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// schemaKeys 列出路径处映射允许的键
+func schemaKeys(s *schema, path []string) []CompletionItem {
+	seen := map[string]bool{}
+	var items []CompletionItem
+	for _, n := range s.at(path) {
+		props, _ := n["properties"].(map[string]any)
+		for _, key := range sortedKeys(props) {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			p, _ := props[key].(map[string]any)
+			item := CompletionItem{Label: key, Kind: kindProperty, InsertText: key + ": "}
+			for _, c := range s.expand(p) {
+				if t, ok := c["type"].(string); ok && item.Detail == "" {
+					item.Detail = t
+				}
+				if desc, ok := c["description"].(string); ok && item.Documentation == nil {
+					item.Documentation = &MarkupContent{Kind: "markdown", Value: desc}
+				}
+			}
+			if item.Detail == "object" || item.Detail == "array" {
+				item.InsertText = key + ":"
+			}
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// schemaValues 列出路径处的枚举值与布尔值
+func schemaValues(s *schema, path []string) []CompletionItem {
+	seen := map[string]bool{}
+	var items []CompletionItem
+	add := func(v string, kind int, doc string) {
+		if seen[v] {
+			return
+		}
+		seen[v] = true
+		item := CompletionItem{Label: v, Kind: kind}
+		if doc != "" {
+			item.Documentation = &MarkupContent{Kind: "markdown", Value: doc}
+		}
+		items = append(items, item)
+	}
+	for _, n := range s.at(path) {
+		desc, _ := n["description"].(string)
+		if enum, ok := n["enum"].([]any); ok {
+			for _, v := range enum {
+				add(fmt.Sprint(v), kindEnumMember, desc)
+			}
+		}
+		if n["type"] == "boolean" {
+			add("true", kindValue, desc)
+			add("false", kindValue, desc)
+		}
+	}
+	return items
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
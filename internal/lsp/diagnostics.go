@@ -0,0 +1,179 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package lsp
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+
+	"github.com/choreoatlas2025/cli/internal/schemas"
+	"github.com/choreoatlas2025/cli/internal/spec"
+	"github.com/choreoatlas2025/cli/internal/validate"
+)
+
+const diagnosticSource = "choreoatlas"
+
+// diagnose 依次检查 YAML 语法、JSON Schema 与 lint 规则（含 CEL 编译）
+func (s *Server) diagnose(d *document) []Diagnostic {
+	switch d.kind {
+	case kindFlow:
+		return s.diagnoseFlow(d)
+	case kindService:
+		return s.diagnoseService(d)
+	}
+	return nil
+}
+
+func (s *Server) diagnoseFlow(d *document) []Diagnostic {
+	if d.parseErr != nil {
+		return []Diagnostic{d.errorDiagnostic(d.parseErr, "")}
+	}
+	fs := d.flow
+	diags := d.schemaDiagnostics("flowspec.schema.json", fs.Source)
+
+	cfg, err := lintConfigFor(d.path)
+	if err != nil {
+		diags = append(diags, d.errorDiagnostic(err, ""))
+	}
+	_, opIndex, err := fs.BuildOperationIndex(d.path)
+	if err != nil {
+		return append(diags, Diagnostic{
+			Range:    tokenRange(d.lines, fs.Source.Lookup("services")),
+			Severity: severityError,
+			Source:   diagnosticSource,
+			Message:  err.Error(),
+		})
+	}
+	issues, err := validate.LintFlow(d.path, fs, opIndex, cfg)
+	if err != nil {
+		return append(diags, d.errorDiagnostic(err, ""))
+	}
+	// 其他文件中的问题（如 ServiceSpec 条件）在打开该文件时报告
+	return append(diags, d.issueDiagnostics(issues)...)
+}
+
+func (s *Server) diagnoseService(d *document) []Diagnostic {
+	if d.parseErr != nil {
+		return []Diagnostic{d.errorDiagnostic(d.parseErr, "")}
+	}
+	diags := d.schemaDiagnostics("servicespec.schema.json", d.serviceSrc)
+	cfg, err := lintConfigFor(d.path)
+	if err != nil {
+		diags = append(diags, d.errorDiagnostic(err, ""))
+	}
+	issues, err := validate.LintServiceSpec(d.path, []byte(d.text), cfg)
+	if err != nil {
+		return append(diags, d.errorDiagnostic(err, ""))
+	}
+	return append(diags, d.issueDiagnostics(issues)...)
+}
+
+// lintConfigFor 读取文件附近的项目配置；没有配置时返回 nil
+func lintConfigFor(path string) (*validate.LintConfig, error) {
+	cfgPath := validate.FindProjectConfig(path)
+	if cfgPath == "" {
+		return nil, nil
+	}
+	return validate.LoadLintConfig(cfgPath)
+}
+
+// issueDiagnostics 转换本文件中的 lint 问题
+func (d *document) issueDiagnostics(issues []validate.LintIssue) []Diagnostic {
+	var diags []Diagnostic
+	for _, is := range issues {
+		if is.Pos.File != "" && is.Pos.File != d.path {
+			continue
+		}
+		severity := severityWarning
+		if is.Level == "ERROR" {
+			severity = severityError
+		}
+		msg := is.Msg
+		if is.Hint != "" {
+			msg += "\nhint: " + is.Hint
+		}
+		diags = append(diags, Diagnostic{
+			Range:    tokenRange(d.lines, is.Pos),
+			Severity: severity,
+			Code:     is.Code,
+			Source:   diagnosticSource,
+			Message:  msg + " (" + validate.DiagnosticTitle(is.Code) + ")",
+		})
+	}
+	return diags
+}
+
+var errorLineRe = regexp.MustCompile(`line (\d+)`)
+
+// errorDiagnostic 将解析错误定位到错误信息中的行号，没有行号时定位到文件开头
+func (d *document) errorDiagnostic(err error, code string) Diagnostic {
+	diag := Diagnostic{Severity: severityError, Code: code, Source: diagnosticSource, Message: err.Error()}
+	if m := errorLineRe.FindStringSubmatch(err.Error()); m != nil {
+		if line, _ := strconv.Atoi(m[1]); line > 0 && line <= len(d.lines) {
+			diag.Range = Range{
+				Start: Position{Line: line - 1},
+				End:   Position{Line: line - 1, Character: utf16Len(d.line(line - 1))},
+			}
+		}
+	}
+	return diag
+}
+
+// schemaDiagnostics 用内置 JSON Schema 校验内容，每个出错位置报告一条
+func (d *document) schemaDiagnostics(schemaName string, src *spec.SourceMap) []Diagnostic {
+	err := spec.ValidateYAMLBytesWithSchemaFS([]byte(d.text), schemas.FS, schemaName)
+	if err == nil {
+		return nil
+	}
+	var ve *jsonschema.ValidationError
+	if !errors.As(err, &ve) {
+		return []Diagnostic{d.errorDiagnostic(err, "schema")}
+	}
+	var diags []Diagnostic
+	seen := map[string]bool{}
+	var walk func(e *jsonschema.ValidationError)
+	walk = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) > 0 {
+			for _, c := range e.Causes {
+				walk(c)
+			}
+			return
+		}
+		// oneOf 的各个分支会在同一位置各报一次，只保留第一条
+		if seen[e.InstanceLocation] {
+			return
+		}
+		seen[e.InstanceLocation] = true
+		diags = append(diags, Diagnostic{
+			Range:    tokenRange(d.lines, src.Lookup(instancePath(e.InstanceLocation)...)),
+			Severity: severityError,
+			Code:     "schema",
+			Source:   diagnosticSource,
+			Message:  "schema: " + e.Message,
+		})
+	}
+	walk(ve)
+	return diags
+}
+
+// instancePath 将 JSON Pointer（如 /flow/0/call）转为 SourceMap 路径
+func instancePath(pointer string) []any {
+	var path []any
+	for _, part := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if part == "" {
+			continue
+		}
+		part = strings.NewReplacer("~1", "/", "~0", "~").Replace(part)
+		if i, err := strconv.Atoi(part); err == nil {
+			path = append(path, i)
+		} else {
+			path = append(path, part)
+		}
+	}
+	return path
+}
@@ -0,0 +1,179 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package lsp
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/choreoatlas2025/cli/internal/spec"
+)
+
+type docKind int
+
+const (
+	kindUnknown docKind = iota
+	kindFlow
+	kindService
+)
+
+// document 是编辑器中打开的规约文件。flow / service 保留最近一次成功解析的结果，
+// 编辑中途 YAML 暂时无效时补全与跳转仍可使用。
+type document struct {
+	uri   string
+	path  string
+	text  string
+	lines []string
+	kind  docKind
+
+	flow       *spec.FlowSpec
+	service    *spec.ServiceSpecFile
+	serviceSrc *spec.SourceMap
+	parseErr   error
+}
+
+var topLevelKeyRe = regexp.MustCompile(`(?m)^(operations|services|flow|graph):`)
+
+// detectKind 按文件名判断规约类型，其余文件看顶层键
+func detectKind(path, text string) docKind {
+	lower := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lower, ".flowspec.yaml"), strings.HasSuffix(lower, ".flowspec.yml"):
+		return kindFlow
+	case strings.HasSuffix(lower, ".servicespec.yaml"), strings.HasSuffix(lower, ".servicespec.yml"):
+		return kindService
+	}
+	if m := topLevelKeyRe.FindStringSubmatch(text); m != nil {
+		if m[1] == "operations" {
+			return kindService
+		}
+		return kindFlow
+	}
+	return kindUnknown
+}
+
+func newDocument(uri, text string) *document {
+	d := &document{uri: uri, path: uriToPath(uri)}
+	d.setText(text)
+	return d
+}
+
+// setText 更新内容并重新解析
+func (d *document) setText(text string) {
+	d.text = text
+	d.lines = strings.Split(text, "\n")
+	for i, l := range d.lines {
+		d.lines[i] = strings.TrimSuffix(l, "\r")
+	}
+	d.kind = detectKind(d.path, text)
+	d.parseErr = nil
+	switch d.kind {
+	case kindFlow:
+		fs, err := spec.ParseFlowSpec(d.path, []byte(text))
+		if err != nil {
+			d.parseErr = err
+			return
+		}
+		d.flow = fs
+	case kindService:
+		ss, err := spec.ParseServiceSpec(d.path, []byte(text))
+		if err != nil {
+			d.parseErr = err
+			return
+		}
+		src, err := spec.NewSourceMap(d.path, []byte(text))
+		if err != nil {
+			d.parseErr = err
+			return
+		}
+		d.service, d.serviceSrc = ss, src
+	}
+}
+
+// applyChange 应用一次增量修改（rng 为 nil 时替换全文）
+func (d *document) applyChange(rng *Range, text string) {
+	if rng == nil {
+		d.setText(text)
+		return
+	}
+	start, end := d.offset(rng.Start), d.offset(rng.End)
+	if end < start {
+		start, end = end, start
+	}
+	d.setText(d.text[:start] + text + d.text[end:])
+}
+
+// offset 将 LSP 位置转为全文的字节偏移
+func (d *document) offset(p Position) int {
+	off := 0
+	for i := 0; i < p.Line; i++ {
+		nl := strings.IndexByte(d.text[off:], '\n')
+		if nl < 0 {
+			return len(d.text)
+		}
+		off += nl + 1
+	}
+	return off + byteOffset(d.line(p.Line), p.Character)
+}
+
+// line 返回第 i 行（从 0 开始），越界时为空串
+func (d *document) line(i int) string {
+	if i < 0 || i >= len(d.lines) {
+		return ""
+	}
+	return d.lines[i]
+}
+
+// prefix 返回光标所在行中光标之前的内容
+func (d *document) prefix(p Position) string {
+	l := d.line(p.Line)
+	return l[:byteOffset(l, p.Character)]
+}
+
+// byteOffset 将行内 UTF-16 偏移转为字节偏移
+func byteOffset(line string, character int) int {
+	units := 0
+	for i, r := range line {
+		if units >= character {
+			return i
+		}
+		units += len(utf16.Encode([]rune{r}))
+	}
+	return len(line)
+}
+
+// utf16Len 返回字符串的 UTF-16 码元数
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += len(utf16.Encode([]rune{r}))
+	}
+	return n
+}
+
+// tokenRange 将 YAML 位置（行、列按字符从 1 开始）转为覆盖该处单词的范围
+func tokenRange(lines []string, p spec.Position) Range {
+	if !p.IsValid() || p.Line > len(lines) {
+		return Range{}
+	}
+	l := lines[p.Line-1]
+	start := 0
+	for col := 1; col < p.Column && start < len(l); col++ {
+		_, size := utf8.DecodeRuneInString(l[start:])
+		start += size
+	}
+	end := start
+	for end < len(l) && l[end] != ' ' && l[end] != '\t' {
+		end++
+	}
+	if end > start+1 && l[end-1] == ':' {
+		end--
+	}
+	return Range{
+		Start: Position{Line: p.Line - 1, Character: utf16Len(l[:start])},
+		End:   Position{Line: p.Line - 1, Character: utf16Len(l[:end])},
+	}
+}
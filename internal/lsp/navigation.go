@@ -0,0 +1,203 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package lsp
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/choreoatlas2025/cli/internal/spec"
+)
+
+var (
+	callLineRe = regexp.MustCompile(`^\s*(?:-\s+)?call:\s*["']?([\w-]+)\.([\w-]+)`)
+	specLineRe = regexp.MustCompile(`^\s*(?:-\s+)?spec:\s*["']?([^"'#\s]+)`)
+	nameLineRe = regexp.MustCompile(`^\s*(?:-\s+)?(?:step|id):\s*["']?([^"'#]+?)["']?\s*(?:#.*)?$`)
+	varRefRe   = regexp.MustCompile(`\$\{\s*([a-zA-Z_][\w\-]*)[\w\-.]*\s*\}`)
+	wordRe     = regexp.MustCompile(`[\w-]+`)
+)
+
+// definition 跳转：call → ServiceSpec 中的 operation，spec → ServiceSpec 文件，
+// ${var} → 产生该变量的 output，compensate / depends → 对应步骤
+func (s *Server) definition(d *document, pos Position) *Location {
+	if d.kind != kindFlow || d.flow == nil {
+		return nil
+	}
+	fs := d.flow
+	line := d.line(pos.Line)
+	cursor := byteOffset(line, pos.Character)
+
+	if m := callLineRe.FindStringSubmatch(line); m != nil {
+		return s.operationLocation(d, m[1], m[2])
+	}
+	if m := specLineRe.FindStringSubmatch(line); m != nil {
+		return &Location{URI: pathToURI(spec.ResolvePath(d.path, m[1]))}
+	}
+	for _, m := range varRefRe.FindAllStringSubmatchIndex(line, -1) {
+		if cursor < m[0] || cursor > m[1] {
+			continue
+		}
+		name := line[m[2]:m[3]]
+		for _, st := range flowSteps(fs) {
+			if _, ok := st.output[name]; ok {
+				return d.location(fs.Source.Step(st.name, "output", name))
+			}
+		}
+		return nil
+	}
+
+	ctx := cursorContext(d.lines, pos.Line, line)
+	refersToStep := ctx.key == "compensate" || ctx.key == "depends" ||
+		(len(ctx.path) >= 2 && ctx.path[len(ctx.path)-2] == "depends")
+	if !refersToStep {
+		return nil
+	}
+	for _, w := range wordRe.FindAllStringIndex(line, -1) {
+		if cursor >= w[0] && cursor <= w[1] {
+			if _, ok := fs.StepByName(line[w[0]:w[1]]); ok {
+				return d.location(fs.Source.Step(line[w[0]:w[1]]))
+			}
+		}
+	}
+	return nil
+}
+
+// operationLocation 返回 alias 绑定的 ServiceSpec 中 operationId 的位置
+func (s *Server) operationLocation(d *document, alias, opID string) *Location {
+	bind, ok := d.flow.Services[alias]
+	if !ok {
+		return nil
+	}
+	path := spec.ResolvePath(d.path, bind.Spec)
+	text, err := s.readFile(path)
+	if err != nil {
+		return nil
+	}
+	ss, err := spec.ParseServiceSpec(path, []byte(text))
+	if err != nil {
+		return nil
+	}
+	src, err := spec.NewSourceMap(path, []byte(text))
+	if err != nil {
+		return nil
+	}
+	lines := strings.Split(text, "\n")
+	for i, op := range ss.Operations {
+		if op.OperationId == opID {
+			return &Location{URI: pathToURI(path), Range: tokenRange(lines, src.Lookup("operations", i, "operationId"))}
+		}
+	}
+	// 操作不存在时跳到文件开头
+	return &Location{URI: pathToURI(path)}
+}
+
+func (d *document) location(p spec.Position) *Location {
+	if !p.IsValid() {
+		return nil
+	}
+	return &Location{URI: d.uri, Range: tokenRange(d.lines, p)}
+}
+
+// hover：FlowSpec 中 call / step / id 所在行显示被调用操作的条件；
+// ServiceSpec 中 operationId 行显示该操作的条件，条件所在行显示条件本身
+func (s *Server) hover(d *document, pos Position) *Hover {
+	line := d.line(pos.Line)
+	switch d.kind {
+	case kindFlow:
+		if d.flow == nil {
+			return nil
+		}
+		alias, opID := "", ""
+		if m := callLineRe.FindStringSubmatch(line); m != nil {
+			alias, opID = m[1], m[2]
+		} else if m := nameLineRe.FindStringSubmatch(line); m != nil {
+			for _, st := range flowSteps(d.flow) {
+				if st.name == m[1] {
+					alias, opID, _ = strings.Cut(st.call, ".")
+				}
+			}
+		}
+		if alias == "" {
+			return nil
+		}
+		_, opIndex, err := d.flow.BuildOperationIndex(d.path)
+		if err != nil {
+			return nil
+		}
+		op, ok := opIndex[alias][opID]
+		if !ok {
+			return nil
+		}
+		return markdownHover(operationMarkdown(alias, op))
+	case kindService:
+		if d.service == nil {
+			return nil
+		}
+		for i, op := range d.service.Operations {
+			if d.serviceSrc.Lookup("operations", i, "operationId").Line == pos.Line+1 {
+				return markdownHover(operationMarkdown(d.service.Service, op))
+			}
+			for _, group := range conditionGroups(op) {
+				for _, name := range sortedKeys(group.conds) {
+					if d.serviceSrc.Lookup("operations", i, group.key, name).Line == pos.Line+1 {
+						return markdownHover(fmt.Sprintf("**%s** %s `%s`\n\n%s", op.OperationId, group.title, name, conditionMarkdown(group.conds[name])))
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+type conditionGroup struct {
+	key, title string
+	conds      map[string]spec.Condition
+}
+
+func conditionGroups(op spec.ServiceOperation) []conditionGroup {
+	return []conditionGroup{
+		{"preconditions", "precondition", op.Preconditions},
+		{"postconditions", "postcondition", op.Postconditions},
+	}
+}
+
+// operationMarkdown 列出操作的描述与全部条件
+func operationMarkdown(service string, op spec.ServiceOperation) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s.%s**\n", service, op.OperationId)
+	if op.Description != "" {
+		fmt.Fprintf(&b, "\n%s\n", op.Description)
+	}
+	for _, group := range conditionGroups(op) {
+		if len(group.conds) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s:\n", strings.ToUpper(group.key[:1])+group.key[1:])
+		for _, name := range sortedKeys(group.conds) {
+			fmt.Fprintf(&b, "- `%s`: %s\n", name, conditionMarkdown(group.conds[name]))
+		}
+	}
+	return b.String()
+}
+
+// conditionMarkdown 显示表达式，非默认的严重级别与缺失数据策略附在其后
+func conditionMarkdown(c spec.Condition) string {
+	s := "`" + c.Expr + "`"
+	var attrs []string
+	if c.Severity != "" && c.Severity != spec.SeverityError {
+		attrs = append(attrs, "severity: "+c.Severity)
+	}
+	if c.FailOnMissing() {
+		attrs = append(attrs, "onMissing: "+c.OnMissing)
+	}
+	if len(attrs) > 0 {
+		s += " *(" + strings.Join(attrs, ", ") + ")*"
+	}
+	return s
+}
+
+func markdownHover(value string) *Hover {
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: value}}
+}
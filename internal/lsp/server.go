@@ -0,0 +1,225 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package lsp implements a Language Server (stdio, JSON-RPC) for FlowSpec and
+// ServiceSpec files: completion, go-to-definition, hover and live diagnostics.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// Server 处理单个编辑器连接。请求按顺序逐条处理。
+type Server struct {
+	version string
+	docs    map[string]*document // URI -> 文档
+	out     io.Writer
+	logger  *log.Logger
+
+	shutdown bool
+}
+
+// NewServer 创建服务器；日志写入 logw（通常为 stderr，stdout 用于协议）
+func NewServer(version string, logw io.Writer) *Server {
+	return &Server{
+		version: version,
+		docs:    map[string]*document{},
+		logger:  log.New(logw, "choreoatlas-lsp: ", log.LstdFlags),
+	}
+}
+
+// ErrExitWithoutShutdown 表示客户端未发送 shutdown 就发送了 exit
+var ErrExitWithoutShutdown = errors.New("exit received before shutdown")
+
+// Run 从 in 读取消息并向 out 写出响应，直到收到 exit 或输入结束
+func (s *Server) Run(in io.Reader, out io.Writer) error {
+	s.out = out
+	r := bufio.NewReader(in)
+	for {
+		msg, err := readMessage(r)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if msg.Error != nil {
+			s.reply(nil, nil, msg.Error)
+			continue
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return ErrExitWithoutShutdown
+			}
+			return nil
+		}
+		result, rerr := s.handle(msg)
+		if msg.ID != nil {
+			s.reply(msg.ID, result, rerr)
+		}
+	}
+}
+
+func (s *Server) handle(msg *message) (any, *responseError) {
+	switch msg.Method {
+	case "initialize":
+		return s.initialize(), nil
+	case "initialized", "$/setTrace", "$/cancelRequest", "workspace/didChangeConfiguration":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var p didOpenParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, invalidParams(err)
+		}
+		d := newDocument(p.TextDocument.URI, p.TextDocument.Text)
+		s.docs[d.uri] = d
+		s.publish(d)
+	case "textDocument/didChange":
+		var p didChangeParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, invalidParams(err)
+		}
+		if d := s.docs[p.TextDocument.URI]; d != nil {
+			for _, c := range p.ContentChanges {
+				d.applyChange(c.Range, c.Text)
+			}
+			s.publish(d)
+		}
+	case "textDocument/didSave":
+		var p didSaveParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, invalidParams(err)
+		}
+		if d := s.docs[p.TextDocument.URI]; d != nil && p.Text != nil {
+			d.setText(*p.Text)
+		}
+		// 保存的文件可能被其他已打开的规约引用（ServiceSpec、项目配置），全部重新诊断
+		for _, d := range s.docs {
+			s.publish(d)
+		}
+	case "textDocument/didClose":
+		var p didCloseParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, invalidParams(err)
+		}
+		delete(s.docs, p.TextDocument.URI)
+		s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: p.TextDocument.URI, Diagnostics: []Diagnostic{}})
+
+	case "textDocument/completion", "textDocument/definition", "textDocument/hover":
+		var p textDocumentPositionParams
+		if err := json.Unmarshal(msg.Params, &p); err != nil {
+			return nil, invalidParams(err)
+		}
+		d := s.docs[p.TextDocument.URI]
+		if d == nil || d.kind == kindUnknown {
+			return nil, nil
+		}
+		switch msg.Method {
+		case "textDocument/completion":
+			return &CompletionList{Items: s.complete(d, p.Position)}, nil
+		case "textDocument/definition":
+			if loc := s.definition(d, p.Position); loc != nil {
+				return loc, nil
+			}
+		default:
+			if h := s.hover(d, p.Position); h != nil {
+				return h, nil
+			}
+		}
+
+	default:
+		if msg.ID != nil {
+			return nil, &responseError{Code: codeMethodNotFound, Message: "method not supported: " + msg.Method}
+		}
+	}
+	return nil, nil
+}
+
+func (s *Server) initialize() any {
+	return map[string]any{
+		"capabilities": map[string]any{
+			"textDocumentSync": map[string]any{
+				"openClose": true,
+				"change":    1, // full
+				"save":      map[string]any{"includeText": false},
+			},
+			"completionProvider": map[string]any{
+				"triggerCharacters": []string{".", "{", ":", " "},
+			},
+			"definitionProvider": true,
+			"hoverProvider":      true,
+		},
+		"serverInfo": map[string]any{"name": "choreoatlas", "version": s.version},
+	}
+}
+
+func invalidParams(err error) *responseError {
+	return &responseError{Code: codeInvalidParams, Message: err.Error()}
+}
+
+func (s *Server) reply(id *json.RawMessage, result any, rerr *responseError) {
+	msg := &message{ID: id, Error: rerr}
+	if id == nil {
+		null := json.RawMessage("null")
+		msg.ID = &null
+	}
+	if rerr == nil {
+		b, err := json.Marshal(result)
+		if err != nil {
+			msg.Error = &responseError{Code: codeInvalidRequest, Message: err.Error()}
+		} else {
+			msg.Result = b
+		}
+	}
+	s.write(msg)
+}
+
+func (s *Server) notify(method string, params any) {
+	b, err := json.Marshal(params)
+	if err != nil {
+		s.logger.Printf("encode %s: %v", method, err)
+		return
+	}
+	s.write(&message{Method: method, Params: b})
+}
+
+func (s *Server) write(msg *message) {
+	if err := writeMessage(s.out, msg); err != nil {
+		s.logger.Printf("write: %v", err)
+	}
+}
+
+// publish 计算并推送文档的诊断
+func (s *Server) publish(d *document) {
+	diags := s.diagnose(d)
+	if diags == nil {
+		diags = []Diagnostic{}
+	}
+	s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{URI: d.uri, Diagnostics: diags})
+}
+
+// readFile 返回文件内容，已在编辑器中打开的文件使用其未保存的内容
+func (s *Server) readFile(path string) (string, error) {
+	abs, _ := filepath.Abs(path)
+	for _, d := range s.docs {
+		if p, _ := filepath.Abs(d.path); p == abs {
+			return d.text, nil
+		}
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("cannot read %s: %w", path, err)
+	}
+	return string(b), nil
+}
@@ -0,0 +1,364 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const testService = `service: orderService
+operations:
+  - operationId: createOrder
+    description: Creates an order
+    postconditions:
+      created: "response.status == 201"
+      fast:
+        expr: "span.durationMs < 500"
+        severity: warn
+  - operationId: getOrder
+`
+
+const testFlow = `info:
+  title: order
+services:
+  orderService:
+    spec: ./order.servicespec.yaml
+flow:
+  - step: create
+    call: orderService.createOrder
+    output:
+      orderId: response.body.id
+  - step: fetch
+    call: orderService.getOrdr
+    input:
+      id: ${orderId}
+`
+
+// session 记录一次会话中服务器写出的消息
+type session struct {
+	t        *testing.T
+	dir      string
+	in       bytes.Buffer
+	nextID   int
+	messages []message
+}
+
+func newSession(t *testing.T) *session {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"order.servicespec.yaml": testService,
+		"order.flowspec.yaml":    testFlow,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := &session{t: t, dir: dir}
+	s.request("initialize", map[string]any{"capabilities": map[string]any{}})
+	s.notify("initialized", map[string]any{})
+	return s
+}
+
+func (s *session) uri(name string) string {
+	return pathToURI(filepath.Join(s.dir, name))
+}
+
+func (s *session) send(msg map[string]any) {
+	msg["jsonrpc"] = "2.0"
+	b, _ := json.Marshal(msg)
+	fmt.Fprintf(&s.in, "Content-Length: %d\r\n\r\n%s", len(b), b)
+}
+
+func (s *session) request(method string, params any) int {
+	s.nextID++
+	s.send(map[string]any{"id": s.nextID, "method": method, "params": params})
+	return s.nextID
+}
+
+func (s *session) notify(method string, params any) {
+	s.send(map[string]any{"method": method, "params": params})
+}
+
+func (s *session) open(name, text string) {
+	s.notify("textDocument/didOpen", map[string]any{
+		"textDocument": map[string]any{"uri": s.uri(name), "languageId": "yaml", "version": 1, "text": text},
+	})
+}
+
+func (s *session) at(method, name string, line, char int) int {
+	return s.request(method, map[string]any{
+		"textDocument": map[string]any{"uri": s.uri(name)},
+		"position":     map[string]any{"line": line, "character": char},
+	})
+}
+
+// run 发送 shutdown/exit 并执行服务器
+func (s *session) run() {
+	s.request("shutdown", nil)
+	s.notify("exit", nil)
+	var out bytes.Buffer
+	if err := NewServer("test", io.Discard).Run(&s.in, &out); err != nil {
+		s.t.Fatalf("Run: %v", err)
+	}
+	r := bufio.NewReader(&out)
+	for {
+		msg, err := readMessage(r)
+		if err != nil {
+			break
+		}
+		s.messages = append(s.messages, *msg)
+	}
+}
+
+func (s *session) result(id int, v any) {
+	s.t.Helper()
+	for _, m := range s.messages {
+		if m.ID != nil && string(*m.ID) == strconv.Itoa(id) {
+			if m.Error != nil {
+				s.t.Fatalf("request %d failed: %v", id, m.Error.Message)
+			}
+			if err := json.Unmarshal(m.Result, v); err != nil {
+				s.t.Fatalf("decode result %d: %v", id, err)
+			}
+			return
+		}
+	}
+	s.t.Fatalf("no response for request %d", id)
+}
+
+// diagnostics 返回 uri 最近一次发布的诊断
+func (s *session) diagnostics(name string) []Diagnostic {
+	var last []Diagnostic
+	for _, m := range s.messages {
+		if m.Method != "textDocument/publishDiagnostics" {
+			continue
+		}
+		var p publishDiagnosticsParams
+		_ = json.Unmarshal(m.Params, &p)
+		if p.URI == s.uri(name) {
+			last = p.Diagnostics
+		}
+	}
+	return last
+}
+
+func labels(items []CompletionItem) map[string]bool {
+	out := map[string]bool{}
+	for _, it := range items {
+		out[it.Label] = true
+	}
+	return out
+}
+
+func TestServer_Diagnostics(t *testing.T) {
+	s := newSession(t)
+	s.open("order.flowspec.yaml", testFlow)
+	s.open("order.servicespec.yaml", strings.Replace(testService, `"response.status == 201"`, `"response.status =="`, 1))
+	s.run()
+
+	var unknownOp *Diagnostic
+	for i, d := range s.diagnostics("order.flowspec.yaml") {
+		if d.Code == "CA-LINT-008" {
+			unknownOp = &s.diagnostics("order.flowspec.yaml")[i]
+		}
+	}
+	if unknownOp == nil {
+		t.Fatalf("expected unknown-operation diagnostic, got %+v", s.diagnostics("order.flowspec.yaml"))
+	}
+	if unknownOp.Severity != severityError || unknownOp.Range.Start.Line != 11 || unknownOp.Range.Start.Character != 10 {
+		t.Errorf("unknown operation diagnostic = %+v", unknownOp)
+	}
+
+	svc := s.diagnostics("order.servicespec.yaml")
+	if len(svc) != 1 || svc[0].Code != "CA-LINT-015" || svc[0].Range.Start.Line != 5 {
+		t.Errorf("expected CEL compile diagnostic on line 6, got %+v", svc)
+	}
+}
+
+func TestServer_DiagnosticsFollowEdits(t *testing.T) {
+	s := newSession(t)
+	s.open("order.flowspec.yaml", testFlow)
+	fixed := strings.Replace(testFlow, "getOrdr", "getOrder", 1)
+	s.notify("textDocument/didChange", map[string]any{
+		"textDocument":   map[string]any{"uri": s.uri("order.flowspec.yaml"), "version": 2},
+		"contentChanges": []map[string]any{{"text": fixed + "  - step: [\n"}},
+	})
+	s.run()
+	diags := s.diagnostics("order.flowspec.yaml")
+	if len(diags) != 1 || diags[0].Range.Start.Line == 0 && diags[0].Range.End.Line == 0 {
+		t.Fatalf("expected a single YAML syntax error with a line, got %+v", diags)
+	}
+
+	s = newSession(t)
+	s.open("order.flowspec.yaml", fixed)
+	s.run()
+	for _, d := range s.diagnostics("order.flowspec.yaml") {
+		if d.Severity == severityError {
+			t.Errorf("unexpected error after fix: %+v", d)
+		}
+	}
+}
+
+func TestServer_SchemaDiagnostics(t *testing.T) {
+	s := newSession(t)
+	s.open("order.flowspec.yaml", strings.Replace(testFlow, "    output:\n", "    retries: 3\n    output:\n", 1))
+	s.run()
+	for _, d := range s.diagnostics("order.flowspec.yaml") {
+		if d.Code == "schema" {
+			if d.Range.Start.Line != 6 {
+				t.Errorf("schema diagnostic should point at the step, got %+v", d)
+			}
+			return
+		}
+	}
+	t.Fatalf("expected schema diagnostic, got %+v", s.diagnostics("order.flowspec.yaml"))
+}
+
+func TestServer_Completion(t *testing.T) {
+	s := newSession(t)
+	flow := testFlow + "  - step: ship\n    call: \n    input:\n      ref: ${\n    \n"
+	s.open("order.flowspec.yaml", flow)
+	calls := s.at("textDocument/completion", "order.flowspec.yaml", 15, 10)
+	vars := s.at("textDocument/completion", "order.flowspec.yaml", 17, 13)
+	keys := s.at("textDocument/completion", "order.flowspec.yaml", 18, 4)
+	top := s.at("textDocument/completion", "order.flowspec.yaml", 19, 0)
+	s.run()
+
+	var list CompletionList
+	s.result(calls, &list)
+	if got := labels(list.Items); !got["orderService.createOrder"] || !got["orderService.getOrder"] {
+		t.Errorf("call completion = %v", got)
+	}
+	s.result(vars, &list)
+	if got := labels(list.Items); !got["orderId"] || !got["customerId"] {
+		t.Errorf("variable completion = %v", got)
+	}
+	s.result(keys, &list)
+	got := labels(list.Items)
+	if !got["output"] || !got["kind"] || !got["compensate"] {
+		t.Errorf("step key completion = %v", got)
+	}
+	if got["step"] || got["call"] || got["input"] {
+		t.Errorf("keys already present should not be offered: %v", got)
+	}
+	s.result(top, &list)
+	if got := labels(list.Items); !got["forbid"] || got["info"] {
+		t.Errorf("top-level key completion = %v", got)
+	}
+}
+
+func TestServer_VariablesOnlyFromUpstream(t *testing.T) {
+	s := newSession(t)
+	flow := `info:
+  title: order
+services:
+  orderService:
+    spec: ./order.servicespec.yaml
+graph:
+  nodes:
+    - id: a
+      call: orderService.createOrder
+      output:
+        fromA: response.body.id
+    - id: b
+      call: orderService.getOrder
+      output:
+        fromB: response.body
+    - id: c
+      call: orderService.getOrder
+      depends: [a]
+      input:
+        id: ${
+`
+	s.open("g.flowspec.yaml", flow)
+	id := s.at("textDocument/completion", "g.flowspec.yaml", 19, 14)
+	s.run()
+	var list CompletionList
+	s.result(id, &list)
+	if got := labels(list.Items); !got["fromA"] || got["fromB"] {
+		t.Errorf("graph variable completion = %v", got)
+	}
+}
+
+func TestServer_EnumCompletion(t *testing.T) {
+	s := newSession(t)
+	s.open("order.servicespec.yaml", testService+"    preconditions:\n      p:\n        expr: \"true\"\n        severity: \n")
+	id := s.at("textDocument/completion", "order.servicespec.yaml", 13, 18)
+	s.run()
+	var list CompletionList
+	s.result(id, &list)
+	if got := labels(list.Items); len(got) != 3 || !got["warn"] {
+		t.Errorf("severity completion = %v", got)
+	}
+}
+
+func TestServer_DefinitionAndHover(t *testing.T) {
+	s := newSession(t)
+	s.open("order.flowspec.yaml", testFlow)
+	s.open("order.servicespec.yaml", testService)
+	toOp := s.at("textDocument/definition", "order.flowspec.yaml", 7, 12)
+	toVar := s.at("textDocument/definition", "order.flowspec.yaml", 13, 12)
+	hoverStep := s.at("textDocument/hover", "order.flowspec.yaml", 6, 10)
+	hoverCond := s.at("textDocument/hover", "order.servicespec.yaml", 6, 8)
+	s.run()
+
+	var loc Location
+	s.result(toOp, &loc)
+	if loc.URI != s.uri("order.servicespec.yaml") || loc.Range.Start.Line != 2 || loc.Range.Start.Character != 17 {
+		t.Errorf("definition of call = %+v", loc)
+	}
+	s.result(toVar, &loc)
+	if loc.URI != s.uri("order.flowspec.yaml") || loc.Range.Start.Line != 9 {
+		t.Errorf("definition of ${orderId} = %+v", loc)
+	}
+
+	var h Hover
+	s.result(hoverStep, &h)
+	for _, want := range []string{"orderService.createOrder", "Creates an order", "`response.status == 201`", "severity: warn"} {
+		if !strings.Contains(h.Contents.Value, want) {
+			t.Errorf("step hover missing %q:\n%s", want, h.Contents.Value)
+		}
+	}
+	s.result(hoverCond, &h)
+	if !strings.Contains(h.Contents.Value, "postcondition `fast`") || !strings.Contains(h.Contents.Value, "span.durationMs < 500") {
+		t.Errorf("condition hover = %q", h.Contents.Value)
+	}
+}
+
+func TestCursorContext(t *testing.T) {
+	lines := strings.Split(`flow:
+  - parallel:
+      - step: a
+        call: s.a
+        `, "\n")
+	ctx := cursorContext(lines, 4, lines[4])
+	if strings.Join(ctx.path, "/") != "flow/[]/parallel/[]" || ctx.value {
+		t.Errorf("path = %v value=%v", ctx.path, ctx.value)
+	}
+	ctx = cursorContext(lines, 3, "        call: s")
+	if !ctx.value || ctx.key != "call" || strings.Join(ctx.path, "/") != "flow/[]/parallel/[]" {
+		t.Errorf("value context = %+v", ctx)
+	}
+	ctx = cursorContext(lines, 4, "      - ")
+	if !ctx.itemStart || strings.Join(ctx.path, "/") != "flow/[]/parallel/[]" {
+		t.Errorf("new item context = %+v", ctx)
+	}
+}
+
+func TestRun_ExitWithoutShutdown(t *testing.T) {
+	var in bytes.Buffer
+	in.WriteString("Content-Length: 33\r\n\r\n{\"jsonrpc\":\"2.0\",\"method\":\"exit\"}")
+	if err := NewServer("test", io.Discard).Run(&in, io.Discard); err != ErrExitWithoutShutdown {
+		t.Errorf("Run = %v, want ErrExitWithoutShutdown", err)
+	}
+}
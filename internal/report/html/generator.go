@@ -22,6 +22,7 @@ type HTMLData struct {
 	Graph      interface{}         `json:"graph,omitempty"` // For DAG mode
 	GateResult *GateResult         `json:"gateResult,omitempty"`
 	Edition    string              `json:"edition"`         // Edition badge: CE/Pro/Pro Privacy
+	Unexpected []validate.UnmatchedSpan `json:"unexpected,omitempty"` // Unexpected interactions
 }
 
 // CoverageSummary represents coverage statistics for HTML display
@@ -30,13 +31,17 @@ type CoverageSummary struct {
 	StepsPass       int     `json:"stepsPass"`
 	StepsFail       int     `json:"stepsFail"`
 	StepsSkip       int     `json:"stepsSkip"`
-	StepsCoverage   float64 `json:"stepsCoverage"`   // stepsPass / stepsTotal
+	StepsCoverage   float64 `json:"stepsCoverage"`   // stepsPass / applicable steps (N/A excluded)
 	ConditionsTotal int     `json:"conditionsTotal"`
 	ConditionsPass  int     `json:"conditionsPass"`
 	ConditionsFail  int     `json:"conditionsFail"`
 	ConditionsSkip  int     `json:"conditionsSkip"`
+	ConditionsWarn  int     `json:"conditionsWarn"`  // warn/info conditions that did not hold (do not fail steps)
 	ConditionsRate  float64 `json:"conditionsRate"`  // conditionsPass / (conditionsPass + conditionsFail)
 	DurationNanos   int64   `json:"durationNanos"`
+	SpansTotal       int    `json:"spansTotal"`
+	UnmatchedSpans   int    `json:"unmatchedSpans"`   // spans not covered by any step
+	AllowedUnmatched int    `json:"allowedUnmatched"` // unmatched spans covered by allowUnmatched
 }
 
 // SpanInfo represents span information for timeline rendering
@@ -49,9 +54,10 @@ type SpanInfo struct {
 
 // GateResult represents baseline gate evaluation result
 type GateResult struct {
-	Checked bool                   `json:"checked"`
-	Passed  bool                   `json:"passed"`
-	Details map[string]interface{} `json:"details"`
+	Checked    bool                   `json:"checked"`
+	Passed     bool                   `json:"passed"`
+	Details    map[string]interface{} `json:"details"`
+	Violations []string               `json:"violations,omitempty"`
 }
 
 // WriteHTMLReport generates and writes an HTML report file
@@ -83,21 +89,39 @@ func BuildHTMLData(steps []validate.StepResult, spans []SpanInfo, gateResult *Ga
 	}
 }
 
+// WithUnmatched adds the unexpected interactions analysis to the report data
+func (d *HTMLData) WithUnmatched(u *validate.UnmatchedReport) {
+	if u == nil {
+		return
+	}
+	d.Summary.SpansTotal = u.SpansTotal
+	d.Summary.UnmatchedSpans = len(u.Spans)
+	d.Summary.AllowedUnmatched = u.Allowed
+	d.Unexpected = u.Spans
+}
+
 // calculateSummary computes coverage summary from step results
 func calculateSummary(steps []validate.StepResult, spans []SpanInfo) CoverageSummary {
 	summary := CoverageSummary{}
 	
-	// Count steps
-	summary.StepsTotal = len(steps)
+	// Count steps (forbid rule results are not flow steps)
+	notApplicable := 0
 	for _, step := range steps {
+		if validate.IsForbidResult(step) {
+			continue
+		}
+		summary.StepsTotal++
 		switch step.Status {
 		case "PASS":
 			summary.StepsPass++
 		case "FAIL":
 			summary.StepsFail++
-		case "SKIP":
+		case "SKIP", validate.StatusNotApplicable:
 			summary.StepsSkip++
 		}
+		if step.Status == validate.StatusNotApplicable {
+			notApplicable++
+		}
 	}
 	
 	// Count conditions
@@ -111,13 +135,15 @@ func calculateSummary(steps []validate.StepResult, spans []SpanInfo) CoverageSum
 				summary.ConditionsFail++
 			case "SKIP":
 				summary.ConditionsSkip++
+			case "WARN":
+				summary.ConditionsWarn++
 			}
 		}
 	}
 	
-	// Calculate rates
-	if summary.StepsTotal > 0 {
-		summary.StepsCoverage = float64(summary.StepsPass) / float64(summary.StepsTotal)
+	// Calculate rates; N/A steps are left out of the denominator, as in the baseline gate
+	if applicable := summary.StepsTotal - notApplicable; applicable > 0 {
+		summary.StepsCoverage = float64(summary.StepsPass) / float64(applicable)
 	}
 	
 	conditionsEvaluated := summary.ConditionsPass + summary.ConditionsFail
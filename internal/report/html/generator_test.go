@@ -289,4 +289,36 @@ func TestEmptyData(t *testing.T) {
 	if data.Summary.DurationNanos != 0 {
 		t.Errorf("Expected DurationNanos 0 for empty data, got %d", data.Summary.DurationNanos)
 	}
-}
\ No newline at end of file
+}
+
+func TestCalculateSummary_SkipsForbidResults(t *testing.T) {
+	steps := []validate.StepResult{
+		{Step: "s1", Status: "PASS"},
+		{Step: "s2", Status: "FAIL"},
+		{Step: "forbid: no-direct-db", Status: "PASS"},
+		{Step: "forbid: no-refund", Status: "FAIL", Code: validate.CodeForbiddenCall},
+	}
+
+	summary := calculateSummary(steps, nil)
+	if summary.StepsTotal != 2 || summary.StepsPass != 1 || summary.StepsFail != 1 {
+		t.Errorf("forbid results should not count as steps: %+v", summary)
+	}
+	if summary.StepsCoverage != 0.5 {
+		t.Errorf("Expected StepsCoverage 0.5, got %f", summary.StepsCoverage)
+	}
+}
+
+func TestCalculateSummary_NotApplicableExcludedFromCoverage(t *testing.T) {
+	steps := []validate.StepResult{
+		{Step: "reserve", Status: "PASS"},
+		{Step: "release", Status: validate.StatusNotApplicable},
+	}
+
+	summary := calculateSummary(steps, nil)
+	if summary.StepsTotal != 2 || summary.StepsSkip != 1 {
+		t.Errorf("unexpected step counts: %+v", summary)
+	}
+	if summary.StepsCoverage != 1 {
+		t.Errorf("Expected StepsCoverage 1 with N/A steps excluded, got %f", summary.StepsCoverage)
+	}
+}
@@ -0,0 +1,282 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package sarif renders lint issues and validation results as a SARIF 2.1.0 log
+// for GitHub code scanning and other SARIF viewers.
+package sarif
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/choreoatlas2025/cli/internal/spec"
+	"github.com/choreoatlas2025/cli/internal/validate"
+)
+
+const (
+	schemaURI      = "https://json.schemastore.org/sarif-2.1.0.json"
+	informationURI = "https://github.com/choreoatlas2025/cli"
+	rulesHelpURI   = "https://github.com/choreoatlas2025/cli/blob/main/docs/reference/cli/"
+)
+
+// Log is the top-level SARIF document
+type Log struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []Run  `json:"runs"`
+}
+
+type Run struct {
+	Tool    Tool     `json:"tool"`
+	Results []Result `json:"results"`
+}
+
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+type Driver struct {
+	Name           string `json:"name"`
+	Version        string `json:"version,omitempty"`
+	InformationURI string `json:"informationUri"`
+	Rules          []Rule `json:"rules"`
+}
+
+type Rule struct {
+	ID                   string        `json:"id"`
+	Name                 string        `json:"name,omitempty"`
+	ShortDescription     Message       `json:"shortDescription"`
+	FullDescription      *Message      `json:"fullDescription,omitempty"`
+	HelpURI              string        `json:"helpUri,omitempty"`
+	DefaultConfiguration Configuration `json:"defaultConfiguration"`
+}
+
+type Configuration struct {
+	Enabled bool   `json:"enabled"`
+	Level   string `json:"level"`
+}
+
+type Message struct {
+	Text string `json:"text"`
+}
+
+type Result struct {
+	RuleID     string         `json:"ruleId"`
+	RuleIndex  int            `json:"ruleIndex"`
+	Level      string         `json:"level"`
+	Message    Message        `json:"message"`
+	Locations  []Location     `json:"locations"`
+	Properties map[string]any `json:"properties,omitempty"`
+}
+
+type Location struct {
+	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+}
+
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           *Region          `json:"region,omitempty"`
+}
+
+type ArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type Region struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// Builder 收集结果并生成单个 run 的 SARIF 日志
+type Builder struct {
+	run       Run
+	ruleIndex map[string]int
+}
+
+// New 创建 Builder，规则表包含全部 lint 规则与校验诊断码
+func New(toolVersion string) *Builder {
+	b := &Builder{
+		run: Run{
+			Tool: Tool{Driver: Driver{
+				Name:           "ChoreoAtlas",
+				Version:        toolVersion,
+				InformationURI: informationURI,
+			}},
+			Results: []Result{},
+		},
+		ruleIndex: map[string]int{},
+	}
+	for _, r := range validate.LintRules() {
+		b.addRule(Rule{
+			ID:                   r.Code,
+			Name:                 r.Name,
+			ShortDescription:     Message{Text: validate.DiagnosticTitle(r.Code)},
+			FullDescription:      &Message{Text: r.Description},
+			HelpURI:              rulesHelpURI + "lint.md#rules",
+			DefaultConfiguration: Configuration{Enabled: !r.Disabled, Level: level(r.Level)},
+		})
+	}
+	for _, code := range validate.DiagnosticCodes() {
+		if _, ok := b.ruleIndex[code]; ok {
+			continue
+		}
+		lvl := "error"
+		if code == validate.CodeConditionSkipped {
+			lvl = "note"
+		}
+		b.addRule(Rule{
+			ID:                   code,
+			ShortDescription:     Message{Text: validate.DiagnosticTitle(code)},
+			HelpURI:              rulesHelpURI + "validate.md#diagnostics",
+			DefaultConfiguration: Configuration{Enabled: true, Level: lvl},
+		})
+	}
+	return b
+}
+
+func (b *Builder) addRule(r Rule) {
+	b.ruleIndex[r.ID] = len(b.run.Tool.Driver.Rules)
+	b.run.Tool.Driver.Rules = append(b.run.Tool.Driver.Rules, r)
+}
+
+// AddLintIssues 添加静态检查问题；没有位置的问题定位到 flowPath
+func (b *Builder) AddLintIssues(flowPath string, issues []validate.LintIssue) {
+	for _, is := range issues {
+		text := is.Msg
+		if is.Hint != "" {
+			text += "\nHint: " + is.Hint
+		}
+		var props map[string]any
+		if is.Ref != "" {
+			props = map[string]any{"step": is.Ref}
+		}
+		b.add(is.Code, level(is.Level), text, is.Pos, flowPath, props)
+	}
+}
+
+// AddStepResults 添加失败的步骤以及未通过 / 跳过的条件。
+// 仅因条件失败而失败的步骤由其条件结果表示，不再单独上报。
+func (b *Builder) AddStepResults(flowPath string, results []validate.StepResult) {
+	for _, r := range results {
+		stepPos := spec.Position{File: flowPath}
+		if r.Position != nil {
+			stepPos = *r.Position
+		}
+		props := map[string]any{"step": r.Step}
+		if r.SpanID != "" {
+			props["spanId"] = r.SpanID
+		}
+		if r.Status == "FAIL" && !(r.Code == validate.CodeConditionFailed && hasConditionFailure(r.Conditions)) {
+			code := r.Code
+			if code == "" {
+				code = validate.CodeStepNotObserved
+			}
+			b.add(code, "error", fmt.Sprintf("step %s (%s): %s", r.Step, r.Call, r.Message), stepPos, flowPath, props)
+		}
+		for _, c := range r.Conditions {
+			var lvl string
+			switch c.Status {
+			case "FAIL":
+				lvl = "error"
+			case "WARN":
+				lvl = "warning"
+				if c.Severity == spec.SeverityInfo {
+					lvl = "note"
+				}
+			case "SKIP":
+				lvl = "note"
+			default:
+				continue
+			}
+			pos := stepPos
+			if c.Position != nil {
+				pos = *c.Position
+			}
+			cprops := map[string]any{"step": r.Step, "condition": c.Kind + ":" + c.Name, "expr": c.Expr}
+			b.add(c.Code, lvl, fmt.Sprintf("step %s %scondition %s: %s (%s)", r.Step, c.Kind, c.Name, c.Message, c.Expr), pos, flowPath, cprops)
+		}
+	}
+}
+
+func hasConditionFailure(conds []validate.ConditionResult) bool {
+	for _, c := range conds {
+		if c.Status == "FAIL" {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Builder) add(code, lvl, text string, pos spec.Position, fallbackFile string, props map[string]any) {
+	idx, ok := b.ruleIndex[code]
+	if !ok {
+		b.addRule(Rule{ID: code, ShortDescription: Message{Text: code}, DefaultConfiguration: Configuration{Enabled: true, Level: lvl}})
+		idx = b.ruleIndex[code]
+	}
+	file := pos.File
+	if file == "" {
+		file = fallbackFile
+	}
+	loc := Location{PhysicalLocation: PhysicalLocation{ArtifactLocation: ArtifactLocation{URI: artifactURI(file)}}}
+	if pos.IsValid() {
+		loc.PhysicalLocation.Region = &Region{StartLine: pos.Line, StartColumn: pos.Column}
+	}
+	b.run.Results = append(b.run.Results, Result{
+		RuleID:     code,
+		RuleIndex:  idx,
+		Level:      lvl,
+		Message:    Message{Text: text},
+		Locations:  []Location{loc},
+		Properties: props,
+	})
+}
+
+// Log 返回 SARIF 日志
+func (b *Builder) Log() *Log {
+	return &Log{Schema: schemaURI, Version: "2.1.0", Runs: []Run{b.run}}
+}
+
+// Write 以缩进 JSON 输出 SARIF 日志
+func (b *Builder) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(b.Log())
+}
+
+// level 将 lint 级别映射为 SARIF level
+func level(l string) string {
+	switch strings.ToUpper(l) {
+	case "ERROR":
+		return "error"
+	case "WARN":
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// artifactURI 返回相对于当前目录（CI 中通常为仓库根目录）的正斜杠路径；
+// 当前目录之外的文件使用 file:// 绝对 URI
+func artifactURI(file string) string {
+	if file == "" {
+		return ""
+	}
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, abs); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	uri := filepath.ToSlash(abs)
+	if !strings.HasPrefix(uri, "/") {
+		uri = "/" + uri // Windows drive letter
+	}
+	return "file://" + uri
+}
@@ -0,0 +1,135 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package sarif
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/choreoatlas2025/cli/internal/spec"
+	"github.com/choreoatlas2025/cli/internal/validate"
+)
+
+func TestRulesRegistered(t *testing.T) {
+	log := New("1.2.3").Log()
+	driver := log.Runs[0].Tool.Driver
+	if driver.Version != "1.2.3" {
+		t.Errorf("driver version = %q", driver.Version)
+	}
+	byID := map[string]Rule{}
+	for _, r := range driver.Rules {
+		byID[r.ID] = r
+	}
+	for _, lr := range validate.LintRules() {
+		r, ok := byID[lr.Code]
+		if !ok {
+			t.Fatalf("lint rule %s missing", lr.Code)
+		}
+		if r.Name != lr.Name || r.DefaultConfiguration.Enabled == lr.Disabled {
+			t.Errorf("rule %s = %+v", lr.Code, r)
+		}
+	}
+	if r := byID[validate.CodeConditionSkipped]; r.DefaultConfiguration.Level != "note" {
+		t.Errorf("condition skipped level = %q, want note", r.DefaultConfiguration.Level)
+	}
+	if r := byID[validate.CodeStepNotObserved]; !strings.HasSuffix(r.HelpURI, "validate.md#diagnostics") {
+		t.Errorf("step not observed helpUri = %q", r.HelpURI)
+	}
+}
+
+func TestAddLintIssues(t *testing.T) {
+	b := New("dev")
+	b.AddLintIssues("flows/a.flowspec.yaml", []validate.LintIssue{
+		{Level: "ERROR", Code: validate.CodeUnknownVariable, Msg: "unknown variable", Hint: "declare it",
+			Ref: "fetch", Pos: spec.Position{File: "flows/a.flowspec.yaml", Line: 12, Column: 5}},
+		{Level: "WARN", Code: validate.CodeEmptyTitle, Msg: "empty title"},
+	})
+	log := b.Log()
+	res := log.Runs[0].Results
+	if len(res) != 2 {
+		t.Fatalf("results = %d, want 2", len(res))
+	}
+
+	first := res[0]
+	if first.Level != "error" || first.Message.Text != "unknown variable\nHint: declare it" {
+		t.Errorf("first result = %+v", first)
+	}
+	if log.Runs[0].Tool.Driver.Rules[first.RuleIndex].ID != validate.CodeUnknownVariable {
+		t.Errorf("ruleIndex %d does not point at %s", first.RuleIndex, validate.CodeUnknownVariable)
+	}
+	loc := first.Locations[0].PhysicalLocation
+	if loc.ArtifactLocation.URI != "flows/a.flowspec.yaml" || loc.Region == nil || loc.Region.StartLine != 12 || loc.Region.StartColumn != 5 {
+		t.Errorf("location = %+v region=%+v", loc, loc.Region)
+	}
+	if first.Properties["step"] != "fetch" {
+		t.Errorf("properties = %v", first.Properties)
+	}
+
+	second := res[1]
+	if second.Level != "warning" || second.Locations[0].PhysicalLocation.Region != nil {
+		t.Errorf("second result = %+v", second)
+	}
+	if second.Locations[0].PhysicalLocation.ArtifactLocation.URI != "flows/a.flowspec.yaml" {
+		t.Errorf("issue without position should fall back to the flow file")
+	}
+}
+
+func TestAddStepResults(t *testing.T) {
+	stepPos := &spec.Position{File: "a.flowspec.yaml", Line: 8, Column: 5}
+	condPos := &spec.Position{File: "order.servicespec.yaml", Line: 20, Column: 9}
+	b := New("dev")
+	b.AddStepResults("a.flowspec.yaml", []validate.StepResult{
+		{Step: "ok", Call: "svc.get", Status: "PASS"},
+		{Step: "missing", Call: "svc.create", Status: "FAIL", Code: validate.CodeStepNotObserved,
+			Message: "no matching span found in trace", Position: stepPos},
+		// 仅因条件失败而失败的步骤只上报条件
+		{Step: "create", Call: "svc.create", Status: "FAIL", Code: validate.CodeConditionFailed, Position: stepPos,
+			Conditions: []validate.ConditionResult{
+				{Kind: "post", Name: "status", Expr: "response.status == 201", Status: "FAIL", Code: validate.CodeConditionFailed, Position: condPos},
+				{Kind: "post", Name: "latency", Status: "WARN", Severity: spec.SeverityInfo, Code: validate.CodeConditionFailed},
+				{Kind: "pre", Name: "body", Status: "SKIP", Code: validate.CodeConditionSkipped},
+				{Kind: "pre", Name: "id", Status: "PASS"},
+			}},
+	})
+	res := b.Log().Runs[0].Results
+	var got []string
+	for _, r := range res {
+		got = append(got, r.RuleID+"/"+r.Level)
+	}
+	want := []string{
+		validate.CodeStepNotObserved + "/error",
+		validate.CodeConditionFailed + "/error",
+		validate.CodeConditionFailed + "/note",
+		validate.CodeConditionSkipped + "/note",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("results = %v, want %v", got, want)
+	}
+	if uri := res[1].Locations[0].PhysicalLocation.ArtifactLocation.URI; uri != "order.servicespec.yaml" {
+		t.Errorf("condition uri = %q, want the ServiceSpec", uri)
+	}
+	if r := res[2].Locations[0].PhysicalLocation.Region; r == nil || r.StartLine != 8 {
+		t.Errorf("condition without position should use the step position, got %+v", r)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+	if err := New("dev").Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if doc["version"] != "2.1.0" || doc["$schema"] == nil {
+		t.Errorf("header = %v %v", doc["version"], doc["$schema"])
+	}
+	// 没有结果时 results 仍需输出为空数组
+	if !strings.Contains(buf.String(), `"results": []`) {
+		t.Errorf("expected empty results array:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package spec
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 条件严重级别：error 失败时步骤 FAIL；warn/info 只在报告中提示
+const (
+	SeverityError = "error"
+	SeverityWarn  = "warn"
+	SeverityInfo  = "info"
+)
+
+// 条件引用的数据缺失（求值时报错）时的处理：skip 记为 SKIP（默认），fail 记为失败
+const (
+	OnMissingSkip = "skip"
+	OnMissingFail = "fail"
+)
+
+// Condition 是 ServiceSpec 中的一个 pre/postcondition。
+// YAML 中可写作字符串（仅表达式），或对象 {expr, severity, onMissing}。
+type Condition struct {
+	Expr      string `yaml:"expr"`
+	Severity  string `yaml:"severity,omitempty"`  // error（默认）| warn | info
+	OnMissing string `yaml:"onMissing,omitempty"` // skip（默认）| fail
+
+	Pos Position `yaml:"-"` // 表达式在 ServiceSpec 中的位置（用于诊断）
+}
+
+// SeverityLevel 返回生效的严重级别
+func (c Condition) SeverityLevel() string {
+	if c.Severity == "" {
+		return SeverityError
+	}
+	return c.Severity
+}
+
+// FailOnMissing 报告数据缺失时是否记为失败
+func (c Condition) FailOnMissing() bool {
+	return c.OnMissing == OnMissingFail
+}
+
+// conditionFields 用于对象形式的编解码（避免递归调用自定义方法）
+type conditionFields Condition
+
+func (c *Condition) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*c = Condition{Expr: node.Value, Pos: Position{Line: node.Line, Column: node.Column}}
+		return nil
+	}
+	var f conditionFields
+	if err := node.Decode(&f); err != nil {
+		return err
+	}
+	switch f.Severity {
+	case "", SeverityError, SeverityWarn, SeverityInfo:
+	default:
+		return fmt.Errorf("line %d: invalid condition severity %q (expected error, warn or info)", node.Line, f.Severity)
+	}
+	switch f.OnMissing {
+	case "", OnMissingSkip, OnMissingFail:
+	default:
+		return fmt.Errorf("line %d: invalid condition onMissing %q (expected skip or fail)", node.Line, f.OnMissing)
+	}
+	if f.Expr == "" {
+		return fmt.Errorf("line %d: condition object requires expr", node.Line)
+	}
+	*c = Condition(f)
+	if expr := mappingValue(node, "expr"); expr != nil {
+		c.Pos = Position{Line: expr.Line, Column: expr.Column}
+	}
+	return nil
+}
+
+// MarshalYAML 仅有表达式时输出为字符串，保持简单写法
+func (c Condition) MarshalYAML() (any, error) {
+	if c.Severity == "" && c.OnMissing == "" {
+		return c.Expr, nil
+	}
+	return conditionFields{Expr: c.Expr, Severity: c.Severity, OnMissing: c.OnMissing}, nil
+}
@@ -0,0 +1,65 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package spec
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestCondition_StringAndObjectForms(t *testing.T) {
+	src := `
+service: orders
+operations:
+  - operationId: create
+    preconditions:
+      method: http.method == 'POST'
+    postconditions:
+      created:
+        expr: response.status == 201
+      fast:
+        expr: span.durationMs < 500
+        severity: warn
+        onMissing: fail
+`
+	var ss ServiceSpecFile
+	if err := yaml.Unmarshal([]byte(src), &ss); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	op := ss.Operations[0]
+	if c := op.Preconditions["method"]; c.Expr != "http.method == 'POST'" || c.SeverityLevel() != SeverityError || c.FailOnMissing() {
+		t.Errorf("string form = %+v", c)
+	}
+	if c := op.Postconditions["created"]; c.Expr != "response.status == 201" || c.SeverityLevel() != SeverityError {
+		t.Errorf("object form without severity = %+v", c)
+	}
+	if c := op.Postconditions["fast"]; c.SeverityLevel() != SeverityWarn || !c.FailOnMissing() {
+		t.Errorf("object form = %+v", c)
+	}
+
+	out, err := yaml.Marshal(&ss)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	for _, want := range []string{"method: http.method == 'POST'", "created: response.status == 201", "severity: warn", "onMissing: fail"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestCondition_RejectsInvalidObjects(t *testing.T) {
+	for src, want := range map[string]string{
+		"{expr: a, severity: fatal}": `invalid condition severity "fatal"`,
+		"{expr: a, onMissing: pass}": `invalid condition onMissing "pass"`,
+		"{severity: warn}":           "condition object requires expr",
+	} {
+		var c Condition
+		err := yaml.Unmarshal([]byte(src), &c)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: got %v, want %q", src, err, want)
+		}
+	}
+}
@@ -80,7 +80,7 @@ func ConvertGraphToFlow(fs *FlowSpec) *FlowSpec {
 }
 
 func nodeToStep(n GraphNode) FlowStep {
-    return FlowStep{ Step: n.ID, Call: n.Call, Input: n.Input, Output: n.Output, Meta: n.Meta }
+    return FlowStep{ Step: n.ID, Call: n.Call, Input: n.Input, Output: n.Output, Meta: n.Meta, Compensate: n.Compensate, Kind: n.Kind, Destination: n.Destination, Match: n.Match, Optional: n.Optional }
 }
 
 // WriteFlowSpec writes FlowSpec as YAML to file
@@ -0,0 +1,181 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package spec
+
+import (
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FlowEdits 描述 lint --fix 对 FlowSpec 的机械修改
+type FlowEdits struct {
+	Version   string              // 非空时写入缺失的 info.version
+	Calls     map[string]string   // step / node 名 -> 新的 call
+	DropInput map[string][]string // step / node 名 -> 要移除的 input 键，"body.x" 表示 body 下的键 x
+}
+
+// EditFlowSpec applies edits to FlowSpec YAML, keeping comments and formatting
+// of the untouched parts. Steps that do not exist are ignored.
+func EditFlowSpec(b []byte, e FlowEdits) ([]byte, error) {
+	doc, err := parseYAMLDoc(b)
+	if err != nil {
+		return nil, err
+	}
+	root := doc.Content[0]
+
+	if e.Version != "" {
+		info := mappingValue(root, "info")
+		if info == nil || info.Kind != yaml.MappingNode {
+			info = &yaml.Node{Kind: yaml.MappingNode}
+			root.Content = append([]*yaml.Node{{Kind: yaml.ScalarNode, Tag: "!!str", Value: "info"}, info}, root.Content...)
+		}
+		if mappingValue(info, "version") == nil {
+			insertMappingValue(info, "title", "version", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: e.Version, Style: yaml.DoubleQuotedStyle})
+		}
+	}
+
+	steps := flowStepNodes(root)
+	for name, call := range e.Calls {
+		if v := mappingValue(steps[name], "call"); v != nil {
+			v.Value = call
+		}
+	}
+	for name, keys := range e.DropInput {
+		st := steps[name]
+		input := mappingValue(st, "input")
+		if input == nil {
+			continue
+		}
+		for _, k := range keys {
+			if !deleteMappingKey(input, k) && strings.HasPrefix(k, "body.") {
+				body := mappingValue(input, "body")
+				deleteMappingKey(body, strings.TrimPrefix(k, "body."))
+				if body != nil && body.Kind == yaml.MappingNode && len(body.Content) == 0 {
+					deleteMappingKey(input, "body")
+				}
+			}
+		}
+		if input.Kind == yaml.MappingNode && len(input.Content) == 0 {
+			deleteMappingKey(st, "input")
+		}
+	}
+	return encodeYAMLPreserving(b, doc)
+}
+
+// ServiceEdits 描述 lint --fix 对 ServiceSpec 的机械修改
+type ServiceEdits struct {
+	Stubs         []ServiceOperation              // 追加的操作（已存在的 operationId 跳过）
+	Preconditions map[string]map[string]Condition // operationId -> 条件名 -> 条件，已有同名条件时保留原条件
+}
+
+// EditServiceSpec applies edits to ServiceSpec YAML, keeping comments and
+// formatting of the untouched parts.
+func EditServiceSpec(b []byte, e ServiceEdits) ([]byte, error) {
+	doc, err := parseYAMLDoc(b)
+	if err != nil {
+		return nil, err
+	}
+	root := doc.Content[0]
+	ops := mappingValue(root, "operations")
+	if ops == nil || ops.Kind != yaml.SequenceNode {
+		ops = &yaml.Node{Kind: yaml.SequenceNode}
+		setMappingValue(root, "operations", ops)
+	}
+	byID := make(map[string]*yaml.Node)
+	for _, op := range ops.Content {
+		byID[scalarValue(op, "operationId")] = op
+	}
+
+	for _, stub := range e.Stubs {
+		if byID[stub.OperationId] != nil {
+			continue
+		}
+		n, err := serviceOperationNode(stub)
+		if err != nil {
+			return nil, err
+		}
+		ops.Content = append(ops.Content, n)
+		byID[stub.OperationId] = n
+	}
+
+	for _, id := range sortedMapKeys(e.Preconditions) {
+		op := byID[id]
+		if op == nil {
+			continue
+		}
+		conds := mappingValue(op, "preconditions")
+		if conds == nil || conds.Kind != yaml.MappingNode {
+			conds = &yaml.Node{Kind: yaml.MappingNode}
+			setMappingValue(op, "preconditions", conds)
+		}
+		for _, name := range sortedMapKeys(e.Preconditions[id]) {
+			if mappingValue(conds, name) != nil {
+				continue
+			}
+			var v yaml.Node
+			if err := v.Encode(e.Preconditions[id][name]); err != nil {
+				return nil, err
+			}
+			setMappingValue(conds, name, &v)
+		}
+	}
+	return encodeYAMLPreserving(b, doc)
+}
+
+// flowStepNodes 按名称索引 flow 步骤（含并发子步骤）与 graph 节点
+func flowStepNodes(root *yaml.Node) map[string]*yaml.Node {
+	steps := make(map[string]*yaml.Node)
+	if flow := mappingValue(root, "flow"); flow != nil && flow.Kind == yaml.SequenceNode {
+		for _, entry := range flow.Content {
+			for _, st := range flowEntrySteps(entry) {
+				steps[scalarValue(st, "step")] = st
+			}
+			if name := scalarValue(entry, "step"); name != "" {
+				steps[name] = entry
+			}
+		}
+	}
+	if nodes := mappingValue(mappingValue(root, "graph"), "nodes"); nodes != nil && nodes.Kind == yaml.SequenceNode {
+		for _, n := range nodes.Content {
+			steps[scalarValue(n, "id")] = n
+		}
+	}
+	return steps
+}
+
+// insertMappingValue 在 after 键之后插入键值，after 不存在时追加到末尾
+func insertMappingValue(m *yaml.Node, after, key string, v *yaml.Node) {
+	pair := []*yaml.Node{{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, v}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == after {
+			m.Content = append(m.Content[:i+2], append(pair, m.Content[i+2:]...)...)
+			return
+		}
+	}
+	m.Content = append(m.Content, pair...)
+}
+
+// deleteMappingKey 删除键值对，返回键是否存在
+func deleteMappingKey(m *yaml.Node, key string) bool {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return false
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content = append(m.Content[:i], m.Content[i+2:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func sortedMapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,97 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package spec
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEditFlowSpec_GraphNodes(t *testing.T) {
+	src := `# orders
+info:
+  title: Orders
+  description: hand-written
+
+services:
+  orderService:
+    spec: ./order.servicespec.yaml
+
+graph:
+  nodes:
+    # entry point
+    - id: create
+      call: orderService.create_order
+      input:
+        otel.scope: orders
+    - id: ship
+      call: orderService.ship
+      depends: [create]
+`
+	out, err := EditFlowSpec([]byte(src), FlowEdits{
+		Version:   "0.1.0",
+		Calls:     map[string]string{"create": "orderService.createOrder", "missing": "x.y"},
+		DropInput: map[string][]string{"create": {"otel.scope"}},
+	})
+	if err != nil {
+		t.Fatalf("EditFlowSpec: %v", err)
+	}
+	want := `# orders
+info:
+  title: Orders
+  version: "0.1.0"
+  description: hand-written
+
+services:
+  orderService:
+    spec: ./order.servicespec.yaml
+
+graph:
+  nodes:
+    # entry point
+    - id: create
+      call: orderService.createOrder
+    - id: ship
+      call: orderService.ship
+      depends: [create]
+`
+	if string(out) != want {
+		t.Errorf("unexpected result:\n%s", out)
+	}
+}
+
+func TestEditServiceSpec(t *testing.T) {
+	src := `service: orderService
+operations:
+  # keep me
+  - operationId: createOrder
+    preconditions:
+      http.method: "request.method == 'POST'"
+`
+	out, err := EditServiceSpec([]byte(src), ServiceEdits{
+		Stubs: []ServiceOperation{{OperationId: "createOrder"}, {OperationId: "cancelOrder", Description: "stub"}},
+		Preconditions: map[string]map[string]Condition{
+			"createOrder": {"http.method": {Expr: "true"}, "span.kind": {Expr: `attrs["span.kind"] == "server"`}},
+			"cancelOrder": {"http.route": {Expr: `hasAttr("http.route")`}},
+			"unknown":     {"x": {Expr: "true"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("EditServiceSpec: %v", err)
+	}
+	got := string(out)
+	if !strings.HasPrefix(got, src) {
+		t.Fatalf("existing content should be kept, got:\n%s", got)
+	}
+	for _, want := range []string{
+		`      span.kind: attrs["span.kind"] == "server"`,
+		"  - operationId: cancelOrder\n    description: stub\n    preconditions:\n      http.route: hasAttr(\"http.route\")\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+	if strings.Count(got, "operationId: createOrder") != 1 || strings.Contains(got, "unknown") {
+		t.Errorf("existing operations must not be stubbed again, unknown operations ignored:\n%s", got)
+	}
+}
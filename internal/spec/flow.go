@@ -16,6 +16,50 @@ type FlowSpec struct {
 	Services map[string]ServiceBinding `yaml:"services"`
 	Flow     []FlowStep                `yaml:"flow,omitempty"`    // Legacy flow format
 	Graph    *GraphSpec               `yaml:"graph,omitempty"`   // New DAG format
+	// AllowUnmatched lists `service.operation` glob patterns of spans that may
+	// appear in a trace without being declared by any step (e.g. "*.healthCheck")
+	AllowUnmatched []string `yaml:"allowUnmatched,omitempty"`
+	// Forbid lists negative assertions checked over the trace's call graph
+	Forbid []ForbidRule `yaml:"forbid,omitempty"`
+	// Source keeps YAML node positions for diagnostics (nil when not loaded from a file)
+	Source *SourceMap `yaml:"-"`
+}
+
+// ForbidRule forbids calls matching a `service.operation` glob pattern.
+// Without before/after the call is forbidden altogether; with them it is only
+// forbidden before the anchor completed or after the anchor started.
+type ForbidRule struct {
+	Name    string        `yaml:"name,omitempty"`
+	Call    string        `yaml:"call"`
+	When    string        `yaml:"when,omitempty"` // CEL scope over `span` and its caller `parent`
+	Before  *ForbidAnchor `yaml:"before,omitempty"`
+	After   *ForbidAnchor `yaml:"after,omitempty"`
+	Message string        `yaml:"message,omitempty"`
+}
+
+// ForbidAnchor identifies the spans a forbidden ordering refers to.
+// In YAML it is either a call pattern or a {call, when} mapping.
+type ForbidAnchor struct {
+	Call string `yaml:"call"`
+	When string `yaml:"when,omitempty"` // CEL predicate over the anchor `span`
+}
+
+// UnmarshalYAML accepts the short form `before: "service.operation"`
+func (a *ForbidAnchor) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		a.Call = node.Value
+		return nil
+	}
+	type plain ForbidAnchor
+	return node.Decode((*plain)(a))
+}
+
+// RuleName returns the rule name, defaulting to its call pattern
+func (r ForbidRule) RuleName() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.Call
 }
 
 // FlowInfo contains basic flow information
@@ -38,6 +82,34 @@ type FlowStep struct {
 	Output   map[string]string      `yaml:"output,omitempty"`         // Output mappings e.g. { newUserResponse: "response.body" }
 	Meta     map[string]interface{} `yaml:"meta,omitempty"`           // Reserved for metadata
 	Parallel []FlowStep             `yaml:"parallel,omitempty"`       // Parallel step group
+	Compensate string               `yaml:"compensate,omitempty"`     // Step that compensates this one when a later step fails (saga)
+	Kind        string              `yaml:"kind,omitempty"`           // call (default) | publish | consume
+	Destination string              `yaml:"destination,omitempty"`    // Messaging destination (topic/queue) for publish/consume steps
+	Match       *SpanMatcher        `yaml:"match,omitempty"`          // Attribute based span matching instead of name matching
+	Optional    bool                `yaml:"optional,omitempty"`       // Step may be absent from a trace (reported as N/A)
+}
+
+// SpanMatcher selects spans by attributes instead of span name.
+// All configured criteria must hold.
+type SpanMatcher struct {
+	HTTPRoute  string            `yaml:"http.route,omitempty"`
+	HTTPMethod string            `yaml:"http.method,omitempty"`
+	RPCMethod  string            `yaml:"rpc.method,omitempty"`
+	Attributes map[string]string `yaml:"attributes,omitempty"` // Attribute equality
+	Regex      map[string]string `yaml:"regex,omitempty"`      // Attribute must match the regular expression
+	CEL        string            `yaml:"cel,omitempty"`        // Boolean CEL predicate over `span`
+}
+
+// Step kinds
+const (
+	StepKindCall    = "call"
+	StepKindPublish = "publish"
+	StepKindConsume = "consume"
+)
+
+// IsMessaging reports whether the step is matched via messaging spans
+func (st FlowStep) IsMessaging() bool {
+	return st.Kind == StepKindPublish || st.Kind == StepKindConsume
 }
 
 // GraphSpec represents DAG format flow specification
@@ -55,6 +127,11 @@ type GraphNode struct {
 	Input   map[string]any         `yaml:"input,omitempty"`
 	Output  map[string]string      `yaml:"output,omitempty"`
 	Meta    map[string]interface{} `yaml:"meta,omitempty"`
+	Compensate string              `yaml:"compensate,omitempty"` // Node that compensates this one when a later node fails (saga)
+	Kind        string             `yaml:"kind,omitempty"`        // call (default) | publish | consume
+	Destination string             `yaml:"destination,omitempty"` // Messaging destination for publish/consume nodes
+	Match       *SpanMatcher       `yaml:"match,omitempty"`       // Attribute based span matching instead of name matching
+	Optional    bool               `yaml:"optional,omitempty"`    // Node may be absent from a trace (reported as N/A)
 }
 
 // GraphEdge represents an edge in the DAG
@@ -70,9 +147,15 @@ func LoadFlowSpec(path string) (*FlowSpec, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to read flowspec: %w", err)
 	}
+	return ParseFlowSpec(path, b)
+}
 
+// ParseFlowSpec parses flow specification content; path is used for positions
+// and for resolving service spec paths (e.g. an unsaved editor buffer)
+func ParseFlowSpec(path string, b []byte) (*FlowSpec, error) {
 	// Try to parse with graph format first (preferred)
 	var fs FlowSpec
+	var err error
 	if err := yaml.Unmarshal(b, &fs); err != nil {
 		return nil, fmt.Errorf("failed to parse flowspec: %w", err)
 	}
@@ -91,6 +174,9 @@ func LoadFlowSpec(path string) (*FlowSpec, error) {
 		fs.Graph.EnsureEdges()
 	}
 
+	if fs.Source, err = NewSourceMap(path, b); err != nil {
+		return nil, fmt.Errorf("failed to parse flowspec: %w", err)
+	}
 	return &fs, nil
 }
 
@@ -149,6 +235,104 @@ func (fs *FlowSpec) GetStepNames() []string {
 	return names
 }
 
+// Compensations returns the saga compensation links declared via `compensate`,
+// keyed by the compensated step/node name
+func (fs *FlowSpec) Compensations() map[string]string {
+	comps := make(map[string]string)
+	if fs.IsGraphMode() {
+		for _, node := range fs.Graph.Nodes {
+			if node.Compensate != "" {
+				comps[node.ID] = node.Compensate
+			}
+		}
+		return comps
+	}
+	for _, step := range fs.Flow {
+		if step.Compensate != "" {
+			comps[step.Step] = step.Compensate
+		}
+		for _, pst := range step.Parallel {
+			if pst.Compensate != "" {
+				comps[pst.Step] = pst.Compensate
+			}
+		}
+	}
+	return comps
+}
+
+// StepByName looks up a step (including parallel children) or graph node by name
+func (fs *FlowSpec) StepByName(name string) (FlowStep, bool) {
+	if fs.IsGraphMode() {
+		for _, node := range fs.Graph.Nodes {
+			if node.ID == name {
+				return nodeToStep(node), true
+			}
+		}
+		return FlowStep{}, false
+	}
+	for _, step := range fs.Flow {
+		if step.Step == name {
+			return step, true
+		}
+		for _, pst := range step.Parallel {
+			if pst.Step == name {
+				return pst, true
+			}
+		}
+	}
+	return FlowStep{}, false
+}
+
+// WithoutSteps returns a copy of the flowspec with the named steps/nodes removed.
+// Dependencies and edges pointing at removed nodes are dropped as well.
+func (fs *FlowSpec) WithoutSteps(names map[string]bool) *FlowSpec {
+	out := &FlowSpec{Info: fs.Info, Services: fs.Services, AllowUnmatched: fs.AllowUnmatched, Forbid: fs.Forbid}
+	if fs.IsGraphMode() {
+		fs.Graph.EnsureEdges()
+		g := &GraphSpec{}
+		for _, node := range fs.Graph.Nodes {
+			if names[node.ID] {
+				continue
+			}
+			var deps []string
+			for _, dep := range node.Depends {
+				if !names[dep] {
+					deps = append(deps, dep)
+				}
+			}
+			node.Depends = deps
+			g.Nodes = append(g.Nodes, node)
+		}
+		for _, edge := range fs.Graph.Edges {
+			if !names[edge.From] && !names[edge.To] {
+				g.Edges = append(g.Edges, edge)
+			}
+		}
+		g.EnsureEdges()
+		out.Graph = g
+		return out
+	}
+	for _, step := range fs.Flow {
+		if names[step.Step] {
+			continue
+		}
+		if len(step.Parallel) > 0 {
+			var children []FlowStep
+			for _, pst := range step.Parallel {
+				if !names[pst.Step] {
+					children = append(children, pst)
+				}
+			}
+			if len(children) == 0 {
+				continue
+			}
+			step.Parallel = children
+		}
+		out.Flow = append(out.Flow, step)
+	}
+	return out
+}
+
 // ValidateGraphStructure validates the DAG structure
 func (gs *GraphSpec) ValidateGraphStructure() error {
 	if gs == nil {
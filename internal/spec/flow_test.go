@@ -4,6 +4,8 @@ package spec
 
 import (
 	"testing"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestGraphStructureValidation(t *testing.T) {
@@ -203,4 +205,32 @@ func TestGetStepNames(t *testing.T) {
 			t.Errorf("Expected name '%s' at index %d, got '%s'", expectedNames[i], i, name)
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestForbidAnchorShortForm(t *testing.T) {
+	src := `
+forbid:
+  - call: "paymentService.refund"
+    after: "shippingService.createShipment"
+  - call: "paymentService.processPayment"
+    before:
+      call: "riskService.assess"
+      when: "span.attributes['risk.decision'] == 'approve'"
+`
+	var fs FlowSpec
+	if err := yaml.Unmarshal([]byte(src), &fs); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(fs.Forbid) != 2 {
+		t.Fatalf("expected 2 rules, got %d", len(fs.Forbid))
+	}
+	if a := fs.Forbid[0].After; a == nil || a.Call != "shippingService.createShipment" {
+		t.Errorf("expected short-form anchor, got %+v", a)
+	}
+	if b := fs.Forbid[1].Before; b == nil || b.Call != "riskService.assess" || b.When == "" {
+		t.Errorf("expected mapping anchor, got %+v", b)
+	}
+	if fs.Forbid[0].RuleName() != "paymentService.refund" {
+		t.Errorf("expected call as default rule name, got %q", fs.Forbid[0].RuleName())
+	}
+}
@@ -0,0 +1,330 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package spec
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConditionConfidence 低于该置信度的归纳条件以注释形式输出，待人工审阅
+const DefaultConditionConfidence = 0.8
+
+// minConditionObservations 归纳取值集合/范围所需的观测数，不足时按比例降低置信度
+const minConditionObservations = 3
+
+// maxEnumValues 不同取值超过该数量时不再归纳为枚举
+const maxEnumValues = 5
+
+// InferredCondition 是从多个 span 观测中归纳出的条件
+type InferredCondition struct {
+	Kind       string // "pre" | "post"
+	Expr       string
+	Confidence float64 // 满足条件的观测占比 × 证据充分度（0..1）
+	Observed   int     // 含该属性的 span 数
+	Total      int     // 该操作的 span 数
+}
+
+// Enabled 报告条件是否足够可信、可直接启用
+func (c InferredCondition) Enabled() bool {
+	return c.Confidence >= DefaultConditionConfidence
+}
+
+// 常见 ID 形状
+var valueShapes = []*regexp.Regexp{
+	regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`), // UUID
+	regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{26}$`),                                                      // ULID
+}
+
+// inferCondition 从某属性的全部观测值归纳出一个条件：
+// - 调用标识（http.method、rpc.method 等）：取值或取值集合
+// - 状态码：主要的状态类（2xx、4xx...）
+// - 字符串：Bearer token、ID 形状（UUID/ULID）、枚举或非空
+// - 数值：取值范围；布尔：取值或类型
+// 收窄到观测值的条件（枚举、范围、单值）需要足够的观测数，否则降低置信度；
+// 仅在部分 span 中出现的属性视为可选，条件以存在性为前提。
+func inferCondition(key string, values []any, total int) (InferredCondition, bool) {
+	kind := "post"
+	if isRequestAttribute(key) {
+		kind = "pre"
+	} else if !isResponseAttribute(key) {
+		return InferredCondition{}, false
+	}
+	if len(values) == 0 {
+		return InferredCondition{}, false
+	}
+
+	ref := attributeRef(key)
+	matched := len(values) // 满足条件的观测数
+	narrowing := false     // 是否收窄到观测到的取值
+	var expr string
+
+	strs, allStrings := stringValues(values)
+	nums, allNumbers := numberValues(values)
+	switch {
+	case isIdentityAttribute(key) && allStrings:
+		expr = equalsAny(ref, strs)
+
+	case isStatusAttribute(key, values[0]) && allNumbers:
+		class, n := dominantStatusClass(nums)
+		if statusKeys[strings.ToLower(key)] {
+			ref = "response.status"
+		}
+		expr = fmt.Sprintf("%s >= %d && %s < %d", ref, class*100, ref, class*100+100)
+		matched = n
+
+	case allStrings && countMatching(values, func(v any) bool { return isBearerToken(key, v) }) > 0:
+		expr = ref + " =~ /Bearer .+/"
+		matched = countMatching(values, func(v any) bool { return isBearerToken(key, v) })
+
+	case allStrings:
+		distinct := distinctStrings(strs)
+		if re := commonShape(strs); re != nil {
+			expr = fmt.Sprintf("%s =~ /%s/", ref, re.String())
+		} else if len(distinct) <= maxEnumValues && len(distinct) < len(strs) {
+			expr = equalsAny(ref, strs)
+			narrowing = true
+		} else {
+			expr = ref + ` != ""`
+			matched = countMatching(values, func(v any) bool { return v != "" })
+		}
+
+	case allNumbers:
+		lo, hi := nums[0], nums[0]
+		for _, n := range nums {
+			lo, hi = math.Min(lo, n), math.Max(hi, n)
+		}
+		if lo == hi {
+			expr = fmt.Sprintf("%s == %s", ref, formatNumber(lo))
+		} else {
+			expr = fmt.Sprintf("%s >= %s && %s <= %s", ref, formatNumber(lo), ref, formatNumber(hi))
+		}
+		narrowing = true
+
+	default:
+		// 混合或复合类型：按主要类型做类型检查
+		types := make(map[string]int)
+		best := ""
+		for _, v := range values {
+			t := celTypeName(v)
+			types[t]++
+			if best == "" || types[t] > types[best] || (types[t] == types[best] && t < best) {
+				best = t
+			}
+		}
+		if best == "bool" && len(types) == 1 {
+			if b := values[0].(bool); countMatching(values, func(v any) bool { return v == b }) == len(values) {
+				expr = fmt.Sprintf("%s == %t", ref, b)
+				narrowing = true
+				break
+			}
+		}
+		expr = fmt.Sprintf("type(%s) == %s", ref, best)
+		matched = types[best]
+	}
+
+	confidence := float64(matched) / float64(len(values))
+	if narrowing && total < minConditionObservations {
+		confidence *= float64(total) / minConditionObservations
+	}
+	if len(values) < total {
+		expr = fmt.Sprintf("!(%s in attrs) || (%s)", celString(key), expr)
+	}
+	return InferredCondition{Kind: kind, Expr: expr, Confidence: confidence, Observed: len(values), Total: total}, true
+}
+
+// statusKeys 为投影到 response.status 的属性
+var statusKeys = map[string]bool{"response.status": true, "http.status_code": true, "statuscode": true}
+
+// isIdentityAttribute 报告属性是否标识调用本身（HTTP/RPC/消息）
+func isIdentityAttribute(key string) bool {
+	switch strings.ToLower(key) {
+	case "http.method", "http.route", "http.target",
+		"rpc.system", "rpc.service", "rpc.method",
+		"messaging.system", "messaging.operation", "messaging.destination.name":
+		return true
+	}
+	return false
+}
+
+func stringValues(values []any) ([]string, bool) {
+	strs := make([]string, 0, len(values))
+	for _, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			return nil, false
+		}
+		strs = append(strs, s)
+	}
+	return strs, true
+}
+
+func numberValues(values []any) ([]float64, bool) {
+	nums := make([]float64, 0, len(values))
+	for _, v := range values {
+		switch n := v.(type) {
+		case int:
+			nums = append(nums, float64(n))
+		case int64:
+			nums = append(nums, float64(n))
+		case float64:
+			nums = append(nums, n)
+		default:
+			return nil, false
+		}
+	}
+	return nums, true
+}
+
+func distinctStrings(strs []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, s := range strs {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// equalsAny 生成 ref == 'a' 或 ref in ['a', 'b']
+func equalsAny(ref string, strs []string) string {
+	distinct := distinctStrings(strs)
+	if len(distinct) == 1 {
+		return fmt.Sprintf("%s == %s", ref, celString(distinct[0]))
+	}
+	quoted := make([]string, len(distinct))
+	for i, s := range distinct {
+		quoted[i] = celString(s)
+	}
+	return fmt.Sprintf("%s in [%s]", ref, strings.Join(quoted, ", "))
+}
+
+// dominantStatusClass 返回出现最多的状态类（2 表示 2xx）及其次数
+func dominantStatusClass(codes []float64) (int, int) {
+	counts := make(map[int]int)
+	best := 0
+	for _, c := range codes {
+		class := int(c) / 100
+		counts[class]++
+		if best == 0 || counts[class] > counts[best] || (counts[class] == counts[best] && class < best) {
+			best = class
+		}
+	}
+	return best, counts[best]
+}
+
+// commonShape 返回所有取值都符合的 ID 形状
+func commonShape(strs []string) *regexp.Regexp {
+	for _, re := range valueShapes {
+		all := true
+		for _, s := range strs {
+			if !re.MatchString(s) {
+				all = false
+				break
+			}
+		}
+		if all {
+			return re
+		}
+	}
+	return nil
+}
+
+func countMatching(values []any, pred func(any) bool) int {
+	n := 0
+	for _, v := range values {
+		if pred(v) {
+			n++
+		}
+	}
+	return n
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// celTypeName 返回取值在 CEL 中的类型名
+func celTypeName(v any) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case int, int64:
+		return "int"
+	case float64:
+		return "double"
+	case []any:
+		return "list"
+	case map[string]any:
+		return "map"
+	}
+	return "dyn"
+}
+
+// serviceOperationNode 将操作编码为 yaml.Node，归纳条件附带置信度注释，
+// 低置信度条件以注释形式输出，待人工审阅后启用
+func serviceOperationNode(op ServiceOperation) (*yaml.Node, error) {
+	var n yaml.Node
+	if err := n.Encode(op); err != nil {
+		return nil, err
+	}
+	if len(op.Inferred) == 0 {
+		return &n, nil
+	}
+	names := make([]string, 0, len(op.Inferred))
+	for name := range op.Inferred {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, section := range []struct{ kind, key string }{{"pre", "preconditions"}, {"post", "postconditions"}} {
+		conds := mappingValue(&n, section.key)
+		var disabled []string
+		for _, name := range names {
+			c := op.Inferred[name]
+			if c.Kind != section.kind {
+				continue
+			}
+			note := fmt.Sprintf("confidence %.2f, seen in %d/%d spans", c.Confidence, c.Observed, c.Total)
+			if c.Enabled() {
+				for i := 0; conds != nil && i+1 < len(conds.Content); i += 2 {
+					if conds.Content[i].Value == name {
+						conds.Content[i+1].LineComment = note
+					}
+				}
+				continue
+			}
+			disabled = append(disabled, fmt.Sprintf("%s: %s  # %s", name, c.Expr, note))
+		}
+		if len(disabled) == 0 {
+			continue
+		}
+		// 追加在条件表末尾；整张表都被注释时挂在操作最后一个键之后
+		if conds != nil && len(conds.Content) > 0 {
+			last := conds.Content[len(conds.Content)-2]
+			last.FootComment = strings.Join(disabled, "\n")
+			continue
+		}
+		lines := []string{section.key + ": # low confidence, review before enabling"}
+		for _, d := range disabled {
+			lines = append(lines, "  "+d)
+		}
+		lastKey := n.Content[len(n.Content)-2]
+		if lastKey.FootComment != "" {
+			lines = append(strings.Split(lastKey.FootComment, "\n"), lines...)
+		}
+		lastKey.FootComment = strings.Join(lines, "\n")
+	}
+	return &n, nil
+}
@@ -0,0 +1,95 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package spec
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/choreoatlas2025/cli/internal/trace"
+)
+
+func TestInferCondition_Generalizes(t *testing.T) {
+	cases := []struct {
+		name     string
+		key      string
+		values   []any
+		total    int
+		expr     string
+		enabled  bool
+		kindWant string
+	}{
+		{"status class", "http.status_code", []any{200.0, 201.0, 204.0}, 3,
+			"response.status >= 200 && response.status < 300", true, "post"},
+		{"mixed status classes", "http.status_code", []any{200.0, 200.0, 404.0, 500.0, 200.0}, 5,
+			"response.status >= 200 && response.status < 300", false, "post"},
+		{"uuid shape", "response.body.id", []any{"0b7d8f0e-2a9b-4c53-9d0c-0c9f3f1b6f21", "6f1c2a54-9a37-4d3e-8f33-2e4c9fcb7d10"}, 2,
+			"attrs['response.body.id'] =~ /^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$/", true, "post"},
+		{"ulid shape", "response.body.ref", []any{"01ARZ3NDEKTSV4RRFFQ69G5FAV"}, 1,
+			"attrs['response.body.ref'] =~ /^[0-9A-HJKMNP-TV-Z]{26}$/", true, "post"},
+		{"enum", "request.body.tier", []any{"gold", "silver", "gold", "gold"}, 4,
+			"attrs['request.body.tier'] in ['gold', 'silver']", true, "pre"},
+		{"range", "response.body.total", []any{10.0, 99.5, 42.0}, 3,
+			"attrs['response.body.total'] >= 10 && attrs['response.body.total'] <= 99.5", true, "post"},
+		{"range from one trace is low confidence", "response.body.total", []any{10.0}, 1,
+			"attrs['response.body.total'] == 10", false, "post"},
+		{"optional field", "request.body.coupon", []any{"X1", "X2"}, 4,
+			"!('request.body.coupon' in attrs) || (attrs['request.body.coupon'] != \"\")", true, "pre"},
+		{"type check", "response.body.items", []any{[]any{1.0}, []any{}, []any{2.0, 3.0}}, 3,
+			"type(attrs['response.body.items']) == list", true, "post"},
+		{"identity", "http.method", []any{"POST"}, 1, "http.method == 'POST'", true, "pre"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, ok := inferCondition(tc.key, tc.values, tc.total)
+			if !ok {
+				t.Fatalf("no condition inferred")
+			}
+			if c.Expr != tc.expr || c.Kind != tc.kindWant {
+				t.Errorf("got %s %q, want %s %q", c.Kind, c.Expr, tc.kindWant, tc.expr)
+			}
+			if c.Enabled() != tc.enabled {
+				t.Errorf("enabled = %v (confidence %.2f), want %v", c.Enabled(), c.Confidence, tc.enabled)
+			}
+		})
+	}
+
+	if _, ok := inferCondition("user_agent.original", []any{"curl"}, 1); ok {
+		t.Error("attributes that are neither request nor response data should be ignored")
+	}
+}
+
+func TestMarshalServiceSpec_CommentsLowConfidence(t *testing.T) {
+	var spans []trace.Span
+	for _, code := range []float64{200, 200, 503} {
+		spans = append(spans, trace.Span{
+			Name: "GET /orders", Service: "orders",
+			Attributes: map[string]any{"http.method": "GET", "http.route": "/orders", "http.status_code": code},
+		})
+	}
+	ops := groupSpansByService(spans)["orders"]
+	out, err := MarshalServiceSpec(&ServiceSpecFile{Service: "orders", Operations: ops})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	got := string(out)
+	for _, want := range []string{
+		"req_method: http.method == 'GET' # confidence 1.00, seen in 3/3 spans",
+		"# postconditions: # low confidence, review before enabling",
+		"#   resp_status_code: response.status >= 200 && response.status < 300  # confidence 0.67, seen in 3/3 spans",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+
+	var ss ServiceSpecFile
+	if err := yaml.Unmarshal(out, &ss); err != nil {
+		t.Fatalf("generated servicespec does not parse: %v", err)
+	}
+	if len(ss.Operations[0].Postconditions) != 0 {
+		t.Errorf("low confidence postcondition should be commented out: %v", ss.Operations[0].Postconditions)
+	}
+}
@@ -0,0 +1,362 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package spec
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/choreoatlas2025/cli/internal/diff"
+	"github.com/choreoatlas2025/cli/internal/trace"
+)
+
+// MergeReport lists what an incremental discover changed in a spec file
+type MergeReport struct {
+	Added []string // Steps/nodes/operations added
+	Stale []string // Existing entries no longer observed in the traces
+}
+
+// MergeFlowSpec merges a generated FlowSpec into an existing one. New services
+// and steps (or graph nodes) are appended; existing entries, their order and
+// comments are kept. Entries whose call was not generated are reported as stale.
+// Both documents must use the same format (flow or graph).
+func MergeFlowSpec(existing, generated []byte) ([]byte, *MergeReport, error) {
+	oldDoc, err := parseYAMLDoc(existing)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse existing flowspec: %w", err)
+	}
+	newDoc, err := parseYAMLDoc(generated)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse generated flowspec: %w", err)
+	}
+	oldRoot, newRoot := oldDoc.Content[0], newDoc.Content[0]
+	report := &MergeReport{}
+
+	// services: add bindings for new services only
+	if newServices := mappingValue(newRoot, "services"); newServices != nil {
+		oldServices := mappingValue(oldRoot, "services")
+		if oldServices == nil {
+			oldServices = &yaml.Node{Kind: yaml.MappingNode}
+			setMappingValue(oldRoot, "services", oldServices)
+		}
+		for i := 0; i+1 < len(newServices.Content); i += 2 {
+			if mappingValue(oldServices, newServices.Content[i].Value) == nil {
+				oldServices.Content = append(oldServices.Content, newServices.Content[i], newServices.Content[i+1])
+			}
+		}
+	}
+
+	if graph := mappingValue(oldRoot, "graph"); graph != nil {
+		newGraph := mappingValue(newRoot, "graph")
+		if newGraph == nil {
+			return nil, nil, fmt.Errorf("existing flowspec uses graph format but generated one does not")
+		}
+		mergeGraphNodes(mappingValue(graph, "nodes"), mappingValue(newGraph, "nodes"), report)
+	} else {
+		oldFlow, newFlow := mappingValue(oldRoot, "flow"), mappingValue(newRoot, "flow")
+		if newFlow == nil {
+			return nil, nil, fmt.Errorf("existing flowspec uses flow format but generated one does not")
+		}
+		if oldFlow == nil {
+			oldFlow = &yaml.Node{Kind: yaml.SequenceNode}
+			setMappingValue(oldRoot, "flow", oldFlow)
+		}
+		mergeFlowSteps(oldFlow, newFlow, report)
+	}
+
+	out, err := encodeYAMLPreserving(existing, oldDoc)
+	return out, report, err
+}
+
+// mergeFlowSteps appends generated steps whose call does not exist yet
+func mergeFlowSteps(oldFlow, newFlow *yaml.Node, report *MergeReport) {
+	calls := make(map[string]bool)
+	names := make(map[string]bool)
+	var existing []*yaml.Node
+	for _, entry := range oldFlow.Content {
+		existing = append(existing, flowEntrySteps(entry)...)
+	}
+	for _, st := range existing {
+		calls[normalizeCall(scalarValue(st, "call"))] = true
+		names[scalarValue(st, "step")] = true
+	}
+
+	generated := make(map[string]bool)
+	for _, entry := range newFlow.Content {
+		var fresh []*yaml.Node
+		steps := flowEntrySteps(entry)
+		for _, st := range steps {
+			call := normalizeCall(scalarValue(st, "call"))
+			generated[call] = true
+			if !calls[call] {
+				fresh = append(fresh, st)
+			}
+		}
+		if len(fresh) == 0 {
+			continue
+		}
+		// Keep a parallel group only if all of it is new
+		additions := fresh
+		if len(fresh) == len(steps) && mappingValue(entry, "parallel") != nil {
+			additions = []*yaml.Node{entry}
+		}
+		for _, st := range fresh {
+			renameUnique(st, "step", names)
+			calls[normalizeCall(scalarValue(st, "call"))] = true
+			report.Added = append(report.Added, fmt.Sprintf("step %s (%s)", scalarValue(st, "step"), scalarValue(st, "call")))
+		}
+		oldFlow.Content = append(oldFlow.Content, additions...)
+	}
+
+	for _, st := range existing {
+		if call := scalarValue(st, "call"); call != "" && !generated[normalizeCall(call)] {
+			report.Stale = append(report.Stale, fmt.Sprintf("step %s (%s)", scalarValue(st, "step"), call))
+		}
+	}
+}
+
+// mergeGraphNodes appends generated nodes whose call does not exist yet,
+// rewriting their dependencies to existing node IDs
+func mergeGraphNodes(oldNodes, newNodes *yaml.Node, report *MergeReport) {
+	if oldNodes == nil || newNodes == nil {
+		return
+	}
+	idOfCall := make(map[string]string)
+	ids := make(map[string]bool)
+	for _, n := range oldNodes.Content {
+		idOfCall[normalizeCall(scalarValue(n, "call"))] = scalarValue(n, "id")
+		ids[scalarValue(n, "id")] = true
+	}
+
+	generated := make(map[string]bool)
+	rename := make(map[string]string) // generated ID -> ID in the merged spec
+	for _, n := range newNodes.Content {
+		call := normalizeCall(scalarValue(n, "call"))
+		generated[call] = true
+		genID := scalarValue(n, "id")
+		if id, ok := idOfCall[call]; ok {
+			rename[genID] = id
+			continue
+		}
+		renameUnique(n, "id", ids)
+		rename[genID] = scalarValue(n, "id")
+		idOfCall[call] = scalarValue(n, "id")
+
+		if deps := mappingValue(n, "depends"); deps != nil {
+			var kept []*yaml.Node
+			for _, d := range deps.Content {
+				if id, ok := rename[d.Value]; ok {
+					d.Value = id
+					kept = append(kept, d)
+				}
+			}
+			deps.Content = kept
+		}
+		oldNodes.Content = append(oldNodes.Content, n)
+		report.Added = append(report.Added, fmt.Sprintf("node %s (%s)", scalarValue(n, "id"), scalarValue(n, "call")))
+	}
+
+	for _, n := range oldNodes.Content {
+		if call := scalarValue(n, "call"); !generated[normalizeCall(call)] {
+			report.Stale = append(report.Stale, fmt.Sprintf("node %s (%s)", scalarValue(n, "id"), call))
+		}
+	}
+}
+
+// MergeServiceSpec appends generated operations missing from an existing
+// ServiceSpec; existing operations and their conditions are left untouched.
+func MergeServiceSpec(existing []byte, generated []ServiceOperation) ([]byte, *MergeReport, error) {
+	doc, err := parseYAMLDoc(existing)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse existing servicespec: %w", err)
+	}
+	root := doc.Content[0]
+	ops := mappingValue(root, "operations")
+	if ops == nil || ops.Kind != yaml.SequenceNode {
+		ops = &yaml.Node{Kind: yaml.SequenceNode}
+		setMappingValue(root, "operations", ops)
+	}
+
+	report := &MergeReport{}
+	known := make(map[string]bool)
+	for _, op := range ops.Content {
+		known[strings.ToLower(scalarValue(op, "operationId"))] = true
+	}
+	observed := make(map[string]bool)
+	sorted := append([]ServiceOperation(nil), generated...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].OperationId < sorted[j].OperationId })
+	for _, op := range sorted {
+		observed[strings.ToLower(op.OperationId)] = true
+		if known[strings.ToLower(op.OperationId)] {
+			continue
+		}
+		n, err := serviceOperationNode(op)
+		if err != nil {
+			return nil, nil, err
+		}
+		ops.Content = append(ops.Content, n)
+		report.Added = append(report.Added, "operation "+op.OperationId)
+	}
+	for _, op := range ops.Content {
+		if id := scalarValue(op, "operationId"); !observed[strings.ToLower(id)] {
+			report.Stale = append(report.Stale, "operation "+id)
+		}
+	}
+
+	out, err := encodeYAMLPreserving(existing, doc)
+	return out, report, err
+}
+
+// ServiceOperationsFromSpans derives the operations discover would generate, per service
+func ServiceOperationsFromSpans(spans []trace.Span) map[string][]ServiceOperation {
+	return groupSpansByService(spans)
+}
+
+// flowEntrySteps returns the step itself or the children of a parallel group
+func flowEntrySteps(entry *yaml.Node) []*yaml.Node {
+	if par := mappingValue(entry, "parallel"); par != nil {
+		return par.Content
+	}
+	return []*yaml.Node{entry}
+}
+
+func normalizeCall(call string) string {
+	return strings.ToLower(strings.TrimSpace(call))
+}
+
+// renameUnique suffixes the scalar at key until it is not in used, then records it
+func renameUnique(n *yaml.Node, key string, used map[string]bool) {
+	v := mappingValue(n, key)
+	if v == nil {
+		return
+	}
+	base := v.Value
+	for c := 2; used[v.Value]; c++ {
+		v.Value = fmt.Sprintf("%s-%d", base, c)
+	}
+	used[v.Value] = true
+}
+
+func parseYAMLDoc(b []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("expected a YAML mapping document")
+	}
+	return &doc, nil
+}
+
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func setMappingValue(m *yaml.Node, key string, v *yaml.Node) {
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, v)
+}
+
+func scalarValue(m *yaml.Node, key string) string {
+	if v := mappingValue(m, key); v != nil && v.Kind == yaml.ScalarNode {
+		return v.Value
+	}
+	return ""
+}
+
+// encodeYAMLPreserving encodes doc and re-applies the changes onto the original
+// text, so that blank lines and formatting that yaml.Node does not keep survive.
+// When the original does not round-trip the encoded document is returned as is.
+func encodeYAMLPreserving(original []byte, doc *yaml.Node) ([]byte, error) {
+	indent := detectIndent(original)
+	encode := func(n *yaml.Node) ([]byte, error) {
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(indent)
+		if err := enc.Encode(n); err != nil {
+			return nil, err
+		}
+		enc.Close()
+		return buf.Bytes(), nil
+	}
+	updated, err := encode(doc)
+	if err != nil {
+		return nil, err
+	}
+	origDoc, err := parseYAMLDoc(original)
+	if err != nil {
+		return updated, nil
+	}
+	encoded, err := encode(origDoc)
+	if err != nil {
+		return updated, nil
+	}
+
+	// Map each line of the re-encoded original to its source line, ignoring
+	// whitespace differences; lines only in the source (blank lines) pass through
+	orig := diff.SplitLines(string(original))
+	baseline := diff.SplitLines(string(encoded))
+	var toOrig []int
+	oi := 0
+	for _, e := range diff.Lines(normalizeLines(orig), normalizeLines(baseline)) {
+		switch e.Op {
+		case diff.Equal:
+			toOrig = append(toOrig, oi)
+			oi++
+		case diff.Delete:
+			oi++
+		case diff.Insert:
+			return updated, nil
+		}
+	}
+
+	// Replay the baseline -> updated edits onto the original lines
+	var out []string
+	next := 0 // next original line to emit
+	bi := 0   // current baseline line
+	for _, e := range diff.Lines(baseline, diff.SplitLines(string(updated))) {
+		switch e.Op {
+		case diff.Equal, diff.Delete:
+			for ; next <= toOrig[bi]; next++ {
+				if next < toOrig[bi] || e.Op == diff.Equal {
+					out = append(out, orig[next])
+				}
+			}
+			bi++
+		case diff.Insert:
+			out = append(out, e.Line)
+		}
+	}
+	out = append(out, orig[next:]...)
+	return []byte(strings.Join(out, "\n") + "\n"), nil
+}
+
+// detectIndent guesses the indentation width of a YAML document (default 2)
+func detectIndent(b []byte) int {
+	for _, line := range diff.SplitLines(string(b)) {
+		trimmed := strings.TrimLeft(line, " ")
+		if n := len(line) - len(trimmed); n > 0 && trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			return n
+		}
+	}
+	return 2
+}
+
+func normalizeLines(lines []string) []string {
+	norm := make([]string, len(lines))
+	for i, l := range lines {
+		norm[i] = strings.Join(strings.Fields(l), " ")
+	}
+	return norm
+}
@@ -0,0 +1,146 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package spec
+
+import (
+	"strings"
+	"testing"
+)
+
+const mergeExistingFlow = `info:
+  title: "Orders"  # hand-written title
+
+services:
+  orderService:
+    spec: "./services/orderService.servicespec.yaml"
+
+flow:
+  # reviewed by hand
+  - step: "Create order"
+    call: "orderService.createOrder"
+    meta:
+      conditions:
+        pre: "input.amount > 0"
+
+  - step: "Legacy"
+    call: "orderService.legacyCall"
+`
+
+const mergeGeneratedFlow = `info:
+  title: "Flow generated from trace"
+services:
+  orderService:
+    spec: "./services/orderService.servicespec.yaml"
+  shippingService:
+    spec: "./services/shippingService.servicespec.yaml"
+flow:
+  - step: "Step1-createOrder"
+    call: "orderService.createorder"
+  - step: "Create order"
+    call: "shippingService.createShipment"
+`
+
+func TestMergeFlowSpec_PreservesEditsAndAppends(t *testing.T) {
+	out, report, err := MergeFlowSpec([]byte(mergeExistingFlow), []byte(mergeGeneratedFlow))
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	got := string(out)
+
+	cut := strings.Index(mergeExistingFlow, "\nflow:")
+	for _, chunk := range []string{mergeExistingFlow[:cut], mergeExistingFlow[cut:]} {
+		if !strings.Contains(got, chunk) {
+			t.Errorf("existing content, comments or blank lines were changed:\n%s", got)
+		}
+	}
+	for _, want := range []string{
+		"  shippingService:\n    spec: \"./services/shippingService.servicespec.yaml\"\n\nflow:",
+		"  - step: \"Create order-2\"\n    call: \"shippingService.createShipment\"\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("merged spec missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "Step1-createOrder") {
+		t.Errorf("step with an existing call (case-insensitive) should not be added:\n%s", got)
+	}
+	if strings.Index(got, "Legacy") > strings.Index(got, "Create order-2") {
+		t.Errorf("new steps should be appended after existing ones:\n%s", got)
+	}
+
+	if len(report.Added) != 1 || !strings.Contains(report.Added[0], "Create order-2") {
+		t.Errorf("unexpected added entries: %v", report.Added)
+	}
+	if len(report.Stale) != 1 || !strings.Contains(report.Stale[0], "orderService.legacyCall") {
+		t.Errorf("unexpected stale entries: %v", report.Stale)
+	}
+
+	// Merging again is a no-op
+	again, report, err := MergeFlowSpec(out, []byte(mergeGeneratedFlow))
+	if err != nil {
+		t.Fatalf("second merge failed: %v", err)
+	}
+	if string(again) != got || len(report.Added) != 0 {
+		t.Errorf("second merge should not change the spec, added %v:\n%s", report.Added, again)
+	}
+}
+
+func TestMergeFlowSpec_GraphRemapsDependencies(t *testing.T) {
+	existing := `graph:
+  nodes:
+    - id: "create"  # renamed by hand
+      call: "orderService.createOrder"
+`
+	generated := `graph:
+  nodes:
+    - id: "createOrder"
+      call: "orderService.createOrder"
+    - id: "ship"
+      call: "shippingService.ship"
+      depends: ["createOrder", "unknown"]
+`
+	out, report, err := MergeFlowSpec([]byte(existing), []byte(generated))
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	got := string(out)
+	if !strings.HasPrefix(got, existing) {
+		t.Errorf("existing node was changed:\n%s", got)
+	}
+	if !strings.Contains(got, `depends: ["create"]`) {
+		t.Errorf("dependency should be remapped to the existing node ID:\n%s", got)
+	}
+	if len(report.Added) != 1 || len(report.Stale) != 0 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+
+	if _, _, err := MergeFlowSpec([]byte(existing), []byte(mergeGeneratedFlow)); err == nil {
+		t.Error("expected an error when merging a flow format spec into a graph")
+	}
+}
+
+func TestMergeServiceSpec_KeepsConditions(t *testing.T) {
+	existing := `service: orderService
+operations:
+    # kept by hand
+    - operationId: legacyCall
+      preconditions:
+        has_id: "input.id != ''"
+`
+	out, report, err := MergeServiceSpec([]byte(existing), []ServiceOperation{
+		{OperationId: "createOrder", Postconditions: map[string]Condition{"ok": {Expr: "response.status == 201"}}},
+	})
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	want := existing + `    - operationId: createOrder
+      postconditions:
+        ok: response.status == 201
+`
+	if string(out) != want {
+		t.Errorf("unexpected merged servicespec:\n%s\nwant:\n%s", out, want)
+	}
+	if len(report.Added) != 1 || len(report.Stale) != 1 || report.Stale[0] != "operation legacyCall" {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
@@ -0,0 +1,204 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package spec
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/choreoatlas2025/cli/internal/trace"
+)
+
+// MinedActivity is a service operation observed across a trace corpus
+type MinedActivity struct {
+	ID          string // Unique step/node name
+	Service     string
+	OperationID string
+	Support     int      // Number of traces containing the activity
+	Optional    bool     // Not present in every trace
+	Duplicates  int      // Repeated calls within a trace that were dropped
+	Depends     []string // Activities that complete before it whenever both occur (transitively reduced)
+	MeanOffset  float64  // Mean start offset from the trace start, used for ordering
+}
+
+// MinedFlow is the behavioral model mined from several traces
+type MinedFlow struct {
+	Traces     int
+	Activities []MinedActivity // Ordered by MeanOffset
+	precedes   map[string]map[string]bool
+	cooccurs   map[string]map[string]bool
+}
+
+// Concurrent reports whether two activities co-occur without a consistent order
+func (m *MinedFlow) Concurrent(a, b string) bool {
+	return m.cooccurs[a][b] && !m.precedes[a][b] && !m.precedes[b][a]
+}
+
+type observation struct {
+	start, end int64
+	spanID     string
+}
+
+// MineFlow learns activities, their dependencies and optionality from traces.
+// Each `service.operationId` counts once per trace (later duplicate calls are
+// dropped). Activity a precedes b when, in every trace containing both, a ends
+// before b starts (within toleranceNanos) or a is an ancestor span of b.
+func MineFlow(traces []*trace.Trace, toleranceNanos int64) *MinedFlow {
+	m := &MinedFlow{
+		precedes: make(map[string]map[string]bool),
+		cooccurs: make(map[string]map[string]bool),
+	}
+	byKey := make(map[string]*MinedActivity)
+	before := make(map[string]map[string]int) // a -> b -> traces where a precedes b
+	both := make(map[string]map[string]int)   // a -> b -> traces containing both
+	inc := func(mm map[string]map[string]int, a, b string) {
+		if mm[a] == nil {
+			mm[a] = make(map[string]int)
+		}
+		mm[a][b]++
+	}
+
+	for _, tr := range traces {
+		obs := make(map[string]observation)
+		keyOf := make(map[string]string) // span ID -> activity key
+		parentOf := make(map[string]string)
+		var first int64
+		spans := append([]trace.Span(nil), tr.Spans...)
+		sort.SliceStable(spans, func(i, j int) bool { return spans[i].StartNanos < spans[j].StartNanos })
+		for i, sp := range spans {
+			if sp.Service == "" || sp.Name == "" {
+				continue
+			}
+			if len(obs) == 0 {
+				first = sp.StartNanos
+			}
+			opID := ComputeOperationID(sp)
+			key := sp.Service + "." + opID
+			act := byKey[key]
+			if act == nil {
+				act = &MinedActivity{Service: sp.Service, OperationID: opID}
+				byKey[key] = act
+			}
+			id := spanAttr(sp, "otlp.span_id")
+			if id == "" {
+				id = fmt.Sprintf("#%d", i) // spans without IDs cannot be ancestors
+			}
+			keyOf[id] = key
+			parentOf[id] = spanAttr(sp, "otlp.parent_span_id")
+			if _, seen := obs[key]; seen {
+				act.Duplicates++
+				continue
+			}
+			obs[key] = observation{start: sp.StartNanos, end: sp.EndNanos, spanID: id}
+			act.Support++
+			act.MeanOffset += float64(sp.StartNanos - first)
+		}
+		if len(obs) == 0 {
+			continue
+		}
+		m.Traces++
+
+		ancestors := func(id string) map[string]bool {
+			keys := make(map[string]bool)
+			for p, n := parentOf[id], 0; p != "" && n < len(parentOf); p, n = parentOf[p], n+1 {
+				if k, ok := keyOf[p]; ok {
+					keys[k] = true
+				}
+			}
+			return keys
+		}
+		for a, oa := range obs {
+			anc := ancestors(oa.spanID)
+			for b, ob := range obs {
+				if a == b {
+					continue
+				}
+				inc(both, a, b)
+				if anc[b] || (ob.start < oa.start && ob.end <= oa.start+toleranceNanos) {
+					inc(before, b, a)
+				}
+			}
+		}
+	}
+
+	for _, act := range byKey {
+		if act.Support > 0 {
+			act.MeanOffset /= float64(act.Support)
+		}
+		act.Optional = act.Support < m.Traces
+		m.Activities = append(m.Activities, *act)
+	}
+	sort.Slice(m.Activities, func(i, j int) bool {
+		a, b := m.Activities[i], m.Activities[j]
+		if a.MeanOffset != b.MeanOffset {
+			return a.MeanOffset < b.MeanOffset
+		}
+		return a.Service+"."+a.OperationID < b.Service+"."+b.OperationID
+	})
+
+	// Always-holding precedence, restricted to the mined order to stay acyclic
+	keys := make([]string, len(m.Activities))
+	for i, act := range m.Activities {
+		keys[i] = act.Service + "." + act.OperationID
+	}
+	ids := mineIDs(m.Activities)
+	for i := range m.Activities {
+		m.Activities[i].ID = ids[i]
+		m.precedes[ids[i]] = make(map[string]bool)
+		m.cooccurs[ids[i]] = make(map[string]bool)
+	}
+	for i, a := range keys {
+		for j, b := range keys {
+			if both[a][b] == 0 {
+				continue
+			}
+			m.cooccurs[ids[i]][ids[j]] = true
+			if i < j && before[a][b] == both[a][b] {
+				m.precedes[ids[i]][ids[j]] = true
+			}
+		}
+	}
+
+	for j := range m.Activities {
+		for i := 0; i < j; i++ {
+			if !m.precedes[ids[i]][ids[j]] {
+				continue
+			}
+			// Transitive reduction through activities present in every trace
+			implied := false
+			for k := i + 1; k < j; k++ {
+				if !m.Activities[k].Optional && m.precedes[ids[i]][ids[k]] && m.precedes[ids[k]][ids[j]] {
+					implied = true
+					break
+				}
+			}
+			if !implied {
+				m.Activities[j].Depends = append(m.Activities[j].Depends, ids[i])
+			}
+		}
+	}
+	return m
+}
+
+// mineIDs names activities by operation ID, qualified by service when ambiguous
+func mineIDs(acts []MinedActivity) []string {
+	count := make(map[string]int)
+	for _, act := range acts {
+		count[act.OperationID]++
+	}
+	ids := make([]string, len(acts))
+	for i, act := range acts {
+		ids[i] = act.OperationID
+		if count[act.OperationID] > 1 {
+			ids[i] = act.Service + "-" + act.OperationID
+		}
+	}
+	return ids
+}
+
+func spanAttr(sp trace.Span, key string) string {
+	if v, ok := sp.Attributes[key].(string); ok {
+		return v
+	}
+	return ""
+}
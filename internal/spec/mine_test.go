@@ -0,0 +1,91 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package spec
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/choreoatlas2025/cli/internal/trace"
+)
+
+func mineSpan(service, name string, start, end int64) trace.Span {
+	return trace.Span{Service: service, Name: name, StartNanos: start, EndNanos: end}
+}
+
+func minedByID(m *MinedFlow) map[string]MinedActivity {
+	acts := make(map[string]MinedActivity)
+	for _, act := range m.Activities {
+		acts[act.ID] = act
+	}
+	return acts
+}
+
+func TestMineFlow_DependenciesConcurrencyOptional(t *testing.T) {
+	traces := []*trace.Trace{
+		{Spans: []trace.Span{
+			mineSpan("orderService", "createOrder", 0, 100),
+			mineSpan("riskService", "check", 110, 150),
+			mineSpan("inventoryService", "reserve", 160, 200),
+			mineSpan("paymentService", "pay", 300, 400),
+			mineSpan("shippingService", "ship", 500, 600),
+		}},
+		{Spans: []trace.Span{
+			mineSpan("orderService", "createOrder", 0, 100),
+			mineSpan("inventoryService", "reserve", 110, 150),
+			mineSpan("riskService", "check", 160, 200),
+			mineSpan("paymentService", "pay", 300, 400),
+			mineSpan("paymentService", "pay", 410, 450), // retry
+		}},
+	}
+
+	m := MineFlow(traces, 0)
+	if m.Traces != 2 || len(m.Activities) != 5 {
+		t.Fatalf("expected 5 activities from 2 traces, got %d from %d", len(m.Activities), m.Traces)
+	}
+	if m.Activities[0].ID != "createorder" {
+		t.Errorf("expected createorder first, got %s", m.Activities[0].ID)
+	}
+	acts := minedByID(m)
+
+	if !m.Concurrent("reserve", "check") {
+		t.Error("expected reserve and check to be concurrent (order differs across traces)")
+	}
+	if m.Concurrent("createorder", "pay") {
+		t.Error("expected createorder to precede pay")
+	}
+	if got := acts["pay"].Depends; !reflect.DeepEqual(got, []string{"check", "reserve"}) && !reflect.DeepEqual(got, []string{"reserve", "check"}) {
+		t.Errorf("expected pay to depend on reserve and check only (transitive reduction), got %v", got)
+	}
+	if acts["pay"].Duplicates != 1 || acts["pay"].Support != 2 {
+		t.Errorf("expected retry to be dropped as duplicate, got %+v", acts["pay"])
+	}
+	if !acts["ship"].Optional || acts["ship"].Support != 1 || acts["createorder"].Optional {
+		t.Errorf("expected only ship to be optional, got ship=%+v create=%+v", acts["ship"], acts["createorder"])
+	}
+}
+
+func TestMineFlow_ParentChildPrecedence(t *testing.T) {
+	withIDs := func(sp trace.Span, id, parent string) trace.Span {
+		sp.Attributes = map[string]any{"otlp.span_id": id}
+		if parent != "" {
+			sp.Attributes["otlp.parent_span_id"] = parent
+		}
+		return sp
+	}
+	// The orchestrator span encloses its children; nesting still implies precedence
+	traces := []*trace.Trace{{Spans: []trace.Span{
+		withIDs(mineSpan("orderService", "createOrder", 0, 1000), "root", ""),
+		withIDs(mineSpan("inventoryService", "reserve", 100, 200), "a", "root"),
+		withIDs(mineSpan("paymentService", "pay", 300, 400), "b", "root"),
+	}}}
+
+	m := MineFlow(traces, 0)
+	acts := minedByID(m)
+	if !reflect.DeepEqual(acts["reserve"].Depends, []string{"createorder"}) {
+		t.Errorf("expected reserve to depend on its parent, got %v", acts["reserve"].Depends)
+	}
+	if !reflect.DeepEqual(acts["pay"].Depends, []string{"reserve"}) {
+		t.Errorf("expected pay to depend on reserve, got %v", acts["pay"].Depends)
+	}
+}
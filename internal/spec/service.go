@@ -7,6 +7,7 @@ import (
     "os"
     "path/filepath"
     "regexp"
+    "sort"
     "strings"
 
     "gopkg.in/yaml.v3"
@@ -23,8 +24,12 @@ type ServiceSpecFile struct {
 type ServiceOperation struct {
 	OperationId    string            `yaml:"operationId"`
 	Description    string            `yaml:"description,omitempty"`
-	Preconditions  map[string]string `yaml:"preconditions,omitempty"`  // 可 CEL 表达式（预留）
-	Postconditions map[string]string `yaml:"postconditions,omitempty"` // 可 CEL 表达式（预留）
+	Preconditions  map[string]Condition `yaml:"preconditions,omitempty"`  // CEL 表达式，可带严重级别（见 Condition）
+	Postconditions map[string]Condition `yaml:"postconditions,omitempty"` // CEL 表达式，可带严重级别（见 Condition）
+
+	// Inferred 记录 discover 归纳出的条件及置信度（按条件名，不序列化）；
+	// 低置信度条件只出现在这里，输出时被注释掉
+	Inferred map[string]InferredCondition `yaml:"-"`
 }
 
 // LoadServiceSpec 从文件加载服务规约
@@ -33,10 +38,24 @@ func LoadServiceSpec(path string) (*ServiceSpecFile, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to read servicespec: %w", err)
 	}
+	return ParseServiceSpec(path, b)
+}
+
+// ParseServiceSpec 解析服务规约内容，path 用于条件位置
+func ParseServiceSpec(path string, b []byte) (*ServiceSpecFile, error) {
 	var ss ServiceSpecFile
 	if err := yaml.Unmarshal(b, &ss); err != nil {
 		return nil, fmt.Errorf("failed to parse servicespec: %w", err)
 	}
+	// 条件位置在解码时记录行列，这里补充文件路径
+	for _, op := range ss.Operations {
+		for _, conds := range []map[string]Condition{op.Preconditions, op.Postconditions} {
+			for name, c := range conds {
+				c.Pos.File = path
+				conds[name] = c
+			}
+		}
+	}
 	return &ss, nil
 }
 
@@ -57,8 +76,8 @@ func GenerateServiceSpecs(spans []trace.Span, outDir string) error {
 			Operations: operations,
 		}
 		
-		// 序列化为 YAML
-		data, err := yaml.Marshal(spec)
+		// 序列化为 YAML（带归纳条件的置信度注释）
+		data, err := MarshalServiceSpec(spec)
 		if err != nil {
 			return fmt.Errorf("failed to serialize ServiceSpec for service %s: %w", serviceName, err)
 		}
@@ -76,6 +95,24 @@ func GenerateServiceSpecs(spans []trace.Span, outDir string) error {
 	return nil
 }
 
+// MarshalServiceSpec 序列化 ServiceSpec，归纳条件附带置信度注释（低置信度的被注释掉）
+func MarshalServiceSpec(ss *ServiceSpecFile) ([]byte, error) {
+	var doc yaml.Node
+	if err := doc.Encode(ss); err != nil {
+		return nil, err
+	}
+	if ops := mappingValue(&doc, "operations"); ops != nil {
+		for i, op := range ss.Operations {
+			n, err := serviceOperationNode(op)
+			if err != nil {
+				return nil, err
+			}
+			ops.Content[i] = n
+		}
+	}
+	return yaml.Marshal(&doc)
+}
+
 // groupSpansByService 按服务分组 spans 并生成操作
 func groupSpansByService(spans []trace.Span) map[string][]ServiceOperation {
     serviceOps := make(map[string][]ServiceOperation)
@@ -119,25 +156,43 @@ func groupSpansByService(spans []trace.Span) map[string][]ServiceOperation {
 	return serviceOps
 }
 
-// generateServiceOperation 从 span 列表生成单个 ServiceOperation
+// generateServiceOperation 从 span 列表生成单个 ServiceOperation：
+// 汇总各属性在所有 span 中的观测值，归纳为带置信度的条件（见 inferCondition）
 func generateServiceOperation(opName string, spans []trace.Span) ServiceOperation {
-	preconditions := make(map[string]string)
-	postconditions := make(map[string]string)
-	
-	// 从所有相关 spans 的 attributes 中提取条件
+	preconditions := make(map[string]Condition)
+	postconditions := make(map[string]Condition)
+	inferred := make(map[string]InferredCondition)
+
+	observed := make(map[string][]any)
+	var keys []string
 	for _, span := range spans {
 		for key, value := range span.Attributes {
-			if celExpr := buildCELExpression(key, value); celExpr != "" {
-				if isRequestAttribute(key) {
-					// 请求相关属性生成前置条件
-					conditionName := generateConditionName("req", key)
-					preconditions[conditionName] = celExpr
-				} else if isResponseAttribute(key) {
-					// 响应相关属性生成后置条件
-					conditionName := generateConditionName("resp", key)
-					postconditions[conditionName] = celExpr
-				}
+			if _, seen := observed[key]; !seen {
+				keys = append(keys, key)
 			}
+			observed[key] = append(observed[key], value)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		cond, ok := inferCondition(key, observed[key], len(spans))
+		if !ok {
+			continue
+		}
+		prefix := "resp"
+		if cond.Kind == "pre" {
+			prefix = "req"
+		}
+		conditionName := generateConditionName(prefix, key)
+		inferred[conditionName] = cond
+		if !cond.Enabled() {
+			continue
+		}
+		if cond.Kind == "pre" {
+			preconditions[conditionName] = Condition{Expr: cond.Expr}
+		} else {
+			postconditions[conditionName] = Condition{Expr: cond.Expr}
 		}
 	}
 	
@@ -146,52 +201,42 @@ func generateServiceOperation(opName string, spans []trace.Span) ServiceOperatio
 		Description:    fmt.Sprintf("Auto-generated %s operation from trace", opName),
 		Preconditions:  preconditions,
 		Postconditions: postconditions,
+		Inferred:       inferred,
 	}
 }
 
-// buildCELExpression 根据属性键值生成 CEL 表达式
-func buildCELExpression(key string, value interface{}) string {
-    keyLower := strings.ToLower(key)
+var celIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
 
-    // Special cases for common HTTP request attributes
-    if keyLower == "http.method" {
-        if s, ok := value.(string); ok && s != "" {
-            return fmt.Sprintf("http.method == '%s'", s)
-        }
-    }
-    if keyLower == "http.route" || keyLower == "http.target" {
-        if s, ok := value.(string); ok && s != "" {
-            return fmt.Sprintf("http.route == '%s'", s)
-        }
-    }
+var celReserved = map[string]bool{
+	"true": true, "false": true, "null": true, "in": true, "as": true, "break": true,
+	"const": true, "continue": true, "else": true, "for": true, "function": true, "if": true,
+	"import": true, "let": true, "loop": true, "package": true, "namespace": true,
+	"return": true, "var": true, "void": true, "while": true,
+}
 
-    // 状态码检查
-    if isStatusAttribute(key, value) {
-        switch v := value.(type) {
-        case int:
-            return fmt.Sprintf("response.status == %d", v)
-        case int64:
-            return fmt.Sprintf("response.status == %d", int(v))
-        case float64:
-            return fmt.Sprintf("response.status == %d", int(v))
-        }
-    }
-	
-	// Bearer token 检查
-	if isBearerToken(key, value) {
-		return "request.headers.authorization =~ /Bearer .+/"
-	}
-	
-	// 字符串非空检查
-	if strVal, ok := value.(string); ok && strVal != "" {
-		if strings.Contains(keyLower, "request") || strings.Contains(keyLower, "body") {
-			return fmt.Sprintf("request.body.%s != \"\"", extractFieldName(key))
-		} else if strings.Contains(keyLower, "response") {
-			return fmt.Sprintf("response.body.%s != \"\"", extractFieldName(key))
+// attributeRef 返回引用 span 属性的 CEL 表达式：可用点号访问的 http/rpc/messaging
+// 属性使用命名空间（http.method），其余使用 attrs['key']
+func attributeRef(key string) string {
+	parts := strings.Split(key, ".")
+	switch parts[0] {
+	case "http", "rpc", "messaging":
+		dotted := len(parts) > 1
+		for _, p := range parts[1:] {
+			if !celIdentifier.MatchString(p) || celReserved[p] {
+				dotted = false
+				break
+			}
+		}
+		if dotted {
+			return key
 		}
 	}
-	
-	return ""
+	return "attrs[" + celString(key) + "]"
+}
+
+// celString 将字符串渲染为单引号 CEL 字面量
+func celString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
 }
 
 // 辅助函数
@@ -223,7 +268,8 @@ func isBearerToken(key string, value interface{}) bool {
 
 func isRequestAttribute(key string) bool {
     keyLower := strings.ToLower(key)
-    requestPatterns := []string{"request.", "http.method", "http.url", "http.route", "http.target"}
+    requestPatterns := []string{"request.", "http.method", "http.url", "http.route", "http.target",
+        "rpc.system", "rpc.service", "rpc.method", "messaging.system", "messaging.operation", "messaging.destination.name"}
 	
 	for _, pattern := range requestPatterns {
 		if strings.Contains(keyLower, pattern) {
@@ -235,7 +281,7 @@ func isRequestAttribute(key string) bool {
 
 func isResponseAttribute(key string) bool {
 	keyLower := strings.ToLower(key)
-	responsePatterns := []string{"response.", "http.status_code"}
+	responsePatterns := []string{"response.", "http.status_code", "rpc.grpc.status_code"}
 	
 	for _, pattern := range responsePatterns {
 		if strings.Contains(keyLower, pattern) {
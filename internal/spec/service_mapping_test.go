@@ -46,7 +46,8 @@ func TestGenerateServiceSpecs_HTTPAttributesToConditions(t *testing.T) {
     // Preconditions should include http.method and http.route
     foundMethod := false
     foundRoute := false
-    for _, expr := range op.Preconditions {
+    for _, cond := range op.Preconditions {
+        expr := cond.Expr
         if expr == "http.method == 'GET'" {
             foundMethod = true
         }
@@ -57,15 +58,16 @@ func TestGenerateServiceSpecs_HTTPAttributesToConditions(t *testing.T) {
     if !foundMethod || !foundRoute {
         t.Fatalf("expected preconditions for http.method and http.route, got: %#v", op.Preconditions)
     }
-    // Postconditions should include response.status == 200
+    // Postconditions should include the 2xx status class
     foundStatus := false
-    for _, expr := range op.Postconditions {
-        if expr == "response.status == 200" {
+    for _, cond := range op.Postconditions {
+        expr := cond.Expr
+        if expr == "response.status >= 200 && response.status < 300" {
             foundStatus = true
         }
     }
     if !foundStatus {
-        t.Fatalf("expected postcondition for response.status == 200, got: %#v", op.Postconditions)
+        t.Fatalf("expected postcondition for the 2xx status class, got: %#v", op.Postconditions)
     }
 }
 
@@ -0,0 +1,170 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package spec
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Position 是诊断在 YAML 源文件中的位置（行、列从 1 开始；未知时为零值）
+type Position struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+}
+
+// IsValid 报告位置是否指向具体的行
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String 格式为 file:line:col（缺少部分时省略）
+func (p Position) String() string {
+	switch {
+	case !p.IsValid():
+		return p.File
+	case p.File == "":
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// SourceMap 保留规约解析时的 yaml.Node，用于将诊断定位到源码
+type SourceMap struct {
+	File string
+	root *yaml.Node
+	data []byte
+}
+
+// NewSourceMap 解析 YAML 内容并保留节点位置
+func NewSourceMap(file string, data []byte) (*SourceMap, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	root := &doc
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		root = doc.Content[0]
+	}
+	return &SourceMap{File: file, root: root, data: data}, nil
+}
+
+// Bytes 返回解析时的原始内容（可能是编辑器中尚未保存的内容）
+func (m *SourceMap) Bytes() []byte {
+	if m == nil {
+		return nil
+	}
+	return m.data
+}
+
+// Lookup 按路径定位节点：string 为映射键，int 为序列下标。
+// 标量值定位到值本身，其余定位到键；路径不存在时退回到最深的已知节点。
+func (m *SourceMap) Lookup(path ...any) Position {
+	if m == nil || m.root == nil {
+		return Position{}
+	}
+	return m.lookupFrom(m.root, m.root, path)
+}
+
+// Step 定位 flow（含 parallel）中名为 name 的步骤或 graph 中 id 为 name 的节点，
+// 再按 path 定位其字段
+func (m *SourceMap) Step(name string, path ...any) Position {
+	if m == nil || m.root == nil {
+		return Position{}
+	}
+	if node := findStepNode(mappingValue(m.root, "flow"), name); node != nil {
+		return m.lookupFrom(node, node, path)
+	}
+	if graph := mappingValue(m.root, "graph"); graph != nil {
+		if nodes := mappingValue(graph, "nodes"); nodes != nil && nodes.Kind == yaml.SequenceNode {
+			for _, node := range nodes.Content {
+				if scalarValue(node, "id") == name {
+					return m.lookupFrom(node, node, path)
+				}
+			}
+		}
+	}
+	return Position{File: m.File}
+}
+
+// StepAt 返回第 line 行（从 1 开始）所在的步骤名或 graph 节点 ID：即在该行之前开始的最后一个步骤，
+// parallel 中的子步骤优先于其所在的组（编辑中尚未写完的行也归入上方的步骤）。
+// 该行在全部步骤之前时返回空串。
+func (m *SourceMap) StepAt(line int) string {
+	if m == nil || m.root == nil {
+		return ""
+	}
+	if name := stepAt(mappingValue(m.root, "flow"), "step", line); name != "" {
+		return name
+	}
+	if graph := mappingValue(m.root, "graph"); graph != nil {
+		return stepAt(mappingValue(graph, "nodes"), "id", line)
+	}
+	return ""
+}
+
+func stepAt(seq *yaml.Node, key string, line int) string {
+	if seq == nil || seq.Kind != yaml.SequenceNode {
+		return ""
+	}
+	var found *yaml.Node
+	for _, entry := range seq.Content {
+		if entry.Line > line {
+			break
+		}
+		found = entry
+	}
+	if found == nil {
+		return ""
+	}
+	if name := stepAt(mappingValue(found, "parallel"), key, line); name != "" {
+		return name
+	}
+	return scalarValue(found, key)
+}
+
+func findStepNode(seq *yaml.Node, name string) *yaml.Node {
+	if seq == nil || seq.Kind != yaml.SequenceNode {
+		return nil
+	}
+	for _, entry := range seq.Content {
+		if scalarValue(entry, "step") == name {
+			return entry
+		}
+		if found := findStepNode(mappingValue(entry, "parallel"), name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func (m *SourceMap) lookupFrom(node, anchor *yaml.Node, path []any) Position {
+	for _, p := range path {
+		var next, key *yaml.Node
+		switch k := p.(type) {
+		case string:
+			if node.Kind == yaml.MappingNode {
+				for i := 0; i+1 < len(node.Content); i += 2 {
+					if node.Content[i].Value == k {
+						key, next = node.Content[i], node.Content[i+1]
+						break
+					}
+				}
+			}
+		case int:
+			if node.Kind == yaml.SequenceNode && k >= 0 && k < len(node.Content) {
+				next = node.Content[k]
+			}
+		}
+		if next == nil {
+			break
+		}
+		node, anchor = next, next
+		if key != nil && next.Kind != yaml.ScalarNode {
+			anchor = key
+		}
+	}
+	return Position{File: m.File, Line: anchor.Line, Column: anchor.Column}
+}
@@ -0,0 +1,89 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package spec
+
+import "testing"
+
+func TestSourceMap_LookupAndStep(t *testing.T) {
+	src := []byte(`info:
+  title: demo
+flow:
+  - step: create
+    call: orderService.createOrder
+  - parallel:
+      - step: reserve
+        call: inventoryService.reserve
+graph:
+  nodes:
+    - id: pay
+      call: paymentService.charge
+`)
+	m, err := NewSourceMap("demo.flowspec.yaml", src)
+	if err != nil {
+		t.Fatalf("NewSourceMap: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		got       Position
+		line, col int
+	}{
+		{"scalar value", m.Lookup("info", "title"), 2, 10},
+		{"collection key", m.Lookup("flow"), 3, 1},
+		{"sequence item", m.Lookup("flow", 0, "call"), 5, 11},
+		{"missing path falls back", m.Lookup("flow", 0, "input", "body"), 4, 5},
+		{"step", m.Step("create"), 4, 5},
+		{"parallel step field", m.Step("reserve", "call"), 8, 15},
+		{"graph node", m.Step("pay", "call"), 12, 13},
+	}
+	for _, tt := range tests {
+		if tt.got.Line != tt.line || tt.got.Column != tt.col {
+			t.Errorf("%s: got %d:%d, want %d:%d", tt.name, tt.got.Line, tt.got.Column, tt.line, tt.col)
+		}
+		if tt.got.File != "demo.flowspec.yaml" {
+			t.Errorf("%s: file = %q", tt.name, tt.got.File)
+		}
+	}
+
+	if pos := m.Step("missing"); pos.IsValid() {
+		t.Errorf("unknown step should have no line, got %v", pos)
+	}
+	var nilMap *SourceMap
+	if pos := nilMap.Lookup("flow"); pos.IsValid() {
+		t.Errorf("nil SourceMap should return zero position, got %v", pos)
+	}
+	if s := (Position{File: "a.yaml", Line: 3, Column: 7}).String(); s != "a.yaml:3:7" {
+		t.Errorf("Position.String() = %q", s)
+	}
+}
+
+func TestSourceMap_StepAt(t *testing.T) {
+	src := []byte(`info:
+  title: demo
+flow:
+  - step: create
+    call: orderService.createOrder
+
+  - parallel:
+      - step: reserve
+        call: inventoryService.reserve
+      - step: charge
+        call: paymentService.charge
+`)
+	m, err := NewSourceMap("demo.flowspec.yaml", src)
+	if err != nil {
+		t.Fatalf("NewSourceMap: %v", err)
+	}
+	for line, want := range map[int]string{2: "", 4: "create", 5: "create", 6: "create", 8: "reserve", 11: "charge", 12: "charge"} {
+		if got := m.StepAt(line); got != want {
+			t.Errorf("StepAt(%d) = %q, want %q", line, got, want)
+		}
+	}
+	g, _ := NewSourceMap("g.flowspec.yaml", []byte("graph:\n  nodes:\n    - id: a\n      call: s.a\n    - id: b\n      call: s.b\n"))
+	if got := g.StepAt(6); got != "b" {
+		t.Errorf("graph StepAt(6) = %q, want b", got)
+	}
+	if got := string(g.Bytes()); got == "" {
+		t.Error("Bytes() should return the parsed content")
+	}
+}
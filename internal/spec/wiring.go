@@ -0,0 +1,258 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package spec
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/choreoatlas2025/cli/internal/trace"
+)
+
+// DataLink 表示一个值从生产者的输出流向后续调用的请求，用于生成 output 与 ${var} 引用
+type DataLink struct {
+	Variable string // 变量名（生产者 output 的键）
+	Producer string // 生产者 `service.operationId`
+	Source   string // 生产者 output 的映射表达式，如 response.body.orderId
+	Consumer string // 消费者 `service.operationId`
+	Param    string // 消费者 input 的键
+	Support  int    // 出现该关联的 trace 数
+}
+
+// 协议/遥测属性不参与数据关联
+var wiringSkipPrefixes = []string{
+	"otlp.", "otel.", "http.", "rpc.", "messaging.", "net.", "server.", "client.",
+	"network.", "user_agent.", "db.", "span.", "telemetry.", "service.", "peer.",
+}
+
+type attrValue struct {
+	path  string
+	value string
+}
+
+// InferDataFlow 通过值关联推断变量连线：某调用返回的值（属性或响应体中）在之后调用的
+// 请求属性中再次出现时，生成一条 DataLink。每条 trace 中同一操作只看首次调用；
+// 多条 trace 时，关联需在过半同时包含两端操作的 trace 中成立。
+func InferDataFlow(traces []*trace.Trace) []DataLink {
+	type linkKey struct{ producer, source, consumer, param string }
+	counts := make(map[linkKey]int)
+	both := make(map[[2]string]int) // (producer, consumer) -> traces containing both
+
+	for _, tr := range traces {
+		spans := append([]trace.Span(nil), tr.Spans...)
+		sort.SliceStable(spans, func(i, j int) bool { return spans[i].StartNanos < spans[j].StartNanos })
+
+		var keys []string
+		first := make(map[string]trace.Span)
+		for _, sp := range spans {
+			if sp.Service == "" || sp.Name == "" {
+				continue
+			}
+			key := sp.Service + "." + ComputeOperationID(sp)
+			if _, seen := first[key]; !seen {
+				first[key] = sp
+				keys = append(keys, key)
+			}
+		}
+		for _, p := range keys {
+			for _, c := range keys {
+				if p != c {
+					both[[2]string{p, c}]++
+				}
+			}
+		}
+
+		// 值 -> 最早产生它的调用
+		type origin struct{ key, path string }
+		produced := make(map[string]origin)
+		found := make(map[linkKey]bool)
+		for _, key := range keys {
+			values := flattenAttributes(first[key].Attributes)
+			// 先作为消费者：请求中的值由更早的调用产生
+			for _, av := range values {
+				if isResponseSide(av.path) {
+					continue
+				}
+				if o, ok := produced[av.value]; ok && o.key != key {
+					found[linkKey{o.key, o.path, key, av.path}] = true
+				}
+			}
+			for _, av := range values {
+				if isRequestSide(av.path) {
+					continue
+				}
+				if _, ok := produced[av.value]; !ok {
+					produced[av.value] = origin{key, av.path}
+				}
+			}
+		}
+		for lk := range found {
+			counts[lk]++
+		}
+	}
+
+	// 每个消费者参数只保留支持度最高的来源
+	best := make(map[[2]string]linkKey)
+	for lk, n := range counts {
+		if n*2 <= both[[2]string{lk.producer, lk.consumer}] {
+			continue
+		}
+		id := [2]string{lk.consumer, lk.param}
+		cur, ok := best[id]
+		if !ok || n > counts[cur] || (n == counts[cur] && lk.producer+lk.source < cur.producer+cur.source) {
+			best[id] = lk
+		}
+	}
+
+	chosen := make([]linkKey, 0, len(best))
+	for _, lk := range best {
+		chosen = append(chosen, lk)
+	}
+	sort.Slice(chosen, func(i, j int) bool {
+		a, b := chosen[i], chosen[j]
+		if a.consumer != b.consumer {
+			return a.consumer < b.consumer
+		}
+		return a.param < b.param
+	})
+
+	// 变量名：同一来源共用一个变量，不同来源重名时追加序号
+	vars := make(map[[2]string]string) // (producer, source) -> variable
+	used := make(map[string]bool)
+	links := make([]DataLink, 0, len(chosen))
+	for _, lk := range chosen {
+		src := [2]string{lk.producer, lk.source}
+		name, ok := vars[src]
+		if !ok {
+			base := variableName(lk.source)
+			name = base
+			for n := 2; used[name]; n++ {
+				name = fmt.Sprintf("%s%d", base, n)
+			}
+			used[name] = true
+			vars[src] = name
+		}
+		links = append(links, DataLink{
+			Variable: name,
+			Producer: lk.producer,
+			Source:   outputRef(lk.source),
+			Consumer: lk.consumer,
+			Param:    variableName(lk.param),
+			Support:  counts[lk],
+		})
+	}
+	return links
+}
+
+// flattenAttributes 展开属性（含响应体等嵌套 map），只保留足以区分的标量值
+func flattenAttributes(attrs map[string]any) []attrValue {
+	var out []attrValue
+	var walk func(path string, v any)
+	walk = func(path string, v any) {
+		switch t := v.(type) {
+		case map[string]any:
+			for k, vv := range t {
+				walk(path+"."+k, vv)
+			}
+		case string:
+			if s := strings.TrimSpace(t); len(s) >= 4 {
+				out = append(out, attrValue{path, "s:" + s})
+			}
+		case float64, int, int64:
+			f := toFloat(t)
+			// 小整数（数量、序号）容易巧合相等
+			if f != math.Trunc(f) || math.Abs(f) >= 1000 {
+				out = append(out, attrValue{path, "n:" + formatNumber(f)})
+			}
+		}
+	}
+	for k, v := range attrs {
+		skip := false
+		for _, p := range wiringSkipPrefixes {
+			if strings.HasPrefix(strings.ToLower(k), p) {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			walk(k, v)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].path < out[j].path })
+	return out
+}
+
+func toFloat(v any) float64 {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return 0
+}
+
+func isRequestSide(path string) bool {
+	return strings.Contains(strings.ToLower(path), "request")
+}
+
+func isResponseSide(path string) bool {
+	return strings.Contains(strings.ToLower(path), "response")
+}
+
+// outputRef 将属性路径转为 output 映射表达式：响应体字段用 response.body.x，其余用 attrs['key']
+func outputRef(path string) string {
+	if strings.HasPrefix(path, "response.body.") {
+		return path
+	}
+	return attributeRef(path)
+}
+
+// variableName 由属性路径生成 lowerCamel 变量名：order.id -> orderId，request.body.customer_id -> customerId
+func variableName(path string) string {
+	var segs []string
+	for _, s := range strings.Split(path, ".") {
+		switch strings.ToLower(s) {
+		case "", "request", "response", "body", "attributes":
+			continue
+		}
+		segs = append(segs, s)
+	}
+	if len(segs) == 0 {
+		return "value"
+	}
+	last := segs[len(segs)-1]
+	if len(segs) > 1 && strings.EqualFold(last, "id") {
+		last = segs[len(segs)-2] + "_id"
+	}
+
+	var sb strings.Builder
+	upper := false
+	for _, r := range last {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = sb.Len() > 0
+			continue
+		}
+		if sb.Len() == 0 {
+			if unicode.IsDigit(r) {
+				sb.WriteRune('v')
+			}
+			sb.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+	if sb.Len() == 0 {
+		return "value"
+	}
+	return sb.String()
+}
@@ -0,0 +1,57 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package spec
+
+import (
+	"testing"
+
+	"github.com/choreoatlas2025/cli/internal/trace"
+)
+
+func TestInferDataFlow(t *testing.T) {
+	mk := func(orderID string, qty int, echoed bool) *trace.Trace {
+		ship := map[string]any{"request.body.quantity": qty}
+		if echoed {
+			ship["order.id"] = orderID
+		}
+		return &trace.Trace{Spans: []trace.Span{
+			{Service: "orders", Name: "create", StartNanos: 0, Attributes: map[string]any{
+				"order.id": orderID, "response.body.quantity": qty, "http.route": "/orders"}},
+			{Service: "billing", Name: "charge", StartNanos: 10, Attributes: map[string]any{
+				"request.body.order_ref": orderID, "http.route": "/orders"}},
+			{Service: "shipping", Name: "ship", StartNanos: 20, Attributes: ship},
+		}}
+	}
+
+	// order.id reaches shipping in only one of three traces: not a majority
+	links := InferDataFlow([]*trace.Trace{mk("ORD-1", 2, true), mk("ORD-2", 3, false), mk("ORD-3", 2, false)})
+	if len(links) != 1 {
+		t.Fatalf("expected a single link, got %+v", links)
+	}
+	want := DataLink{Variable: "orderId", Producer: "orders.create", Source: "attrs['order.id']",
+		Consumer: "billing.charge", Param: "orderRef", Support: 3}
+	if links[0] != want {
+		t.Errorf("got %+v, want %+v", links[0], want)
+	}
+
+	// With a majority the echoed attribute is wired too, sharing the variable
+	links = InferDataFlow([]*trace.Trace{mk("ORD-1", 2, true), mk("ORD-2", 3, true), mk("ORD-3", 2, false)})
+	if len(links) != 2 || links[1].Consumer != "shipping.ship" || links[1].Variable != "orderId" || links[1].Param != "orderId" {
+		t.Errorf("expected shipping to consume ${orderId}, got %+v", links)
+	}
+}
+
+func TestVariableName(t *testing.T) {
+	for path, want := range map[string]string{
+		"order.id":                 "orderId",
+		"response.body.orderId":    "orderId",
+		"request.body.customer_id": "customerId",
+		"request.headers.x-tenant": "xTenant",
+		"response.body.2fa":        "v2fa",
+		"response.body":            "value",
+	} {
+		if got := variableName(path); got != want {
+			t.Errorf("variableName(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
@@ -20,6 +20,14 @@ type Span struct {
 	StartNanos int64                  `json:"startNanos,omitempty"`
 	EndNanos   int64                  `json:"endNanos,omitempty"`
 	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	Kind       string                 `json:"kind,omitempty"`  // server | client | producer | consumer | internal
+	Links      []SpanLink             `json:"links,omitempty"` // 异步因果（如消息消费者指向生产者）
+}
+
+// SpanLink 表示指向另一个 span 的链接
+type SpanLink struct {
+	TraceID string `json:"traceId,omitempty"`
+	SpanID  string `json:"spanId"`
 }
 
 // LoadFromFile 从文件加载追踪数据
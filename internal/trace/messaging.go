@@ -0,0 +1,49 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package trace
+
+import "strings"
+
+// Messaging roles derived from OpenTelemetry messaging semantic conventions
+const (
+	MessagingPublish = "publish"
+	MessagingConsume = "consume"
+)
+
+// MessagingRole 返回 span 在消息交互中的角色（publish / consume），非消息 span 返回空串
+func (s Span) MessagingRole() string {
+	op := strings.ToLower(s.stringAttr("messaging.operation.type", "messaging.operation"))
+	switch op {
+	case "publish", "create", "send":
+		return MessagingPublish
+	case "receive", "process", "deliver", "settle":
+		return MessagingConsume
+	}
+	switch s.Kind {
+	case "producer":
+		return MessagingPublish
+	case "consumer":
+		return MessagingConsume
+	}
+	return ""
+}
+
+// MessagingDestination 返回消息目的地（topic / queue）
+func (s Span) MessagingDestination() string {
+	return s.stringAttr("messaging.destination.name", "messaging.destination")
+}
+
+// MessageID 返回消息 ID，用于在缺少 span link 时关联生产者与消费者
+func (s Span) MessageID() string {
+	return s.stringAttr("messaging.message.id", "messaging.message_id")
+}
+
+// stringAttr 按顺序返回第一个非空字符串属性
+func (s Span) stringAttr(keys ...string) string {
+	for _, k := range keys {
+		if v, ok := s.Attributes[k].(string); ok && v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -21,6 +21,46 @@ type OTLPSpan struct {
 	Attributes        []OTLPAttribute        `json:"attributes,omitempty"`
 	Events            []OTLPEvent            `json:"events,omitempty"`
 	Status            OTLPStatus             `json:"status,omitempty"`
+	Kind              OTLPSpanKind           `json:"kind,omitempty"`
+	Links             []OTLPLink             `json:"links,omitempty"`
+}
+
+// OTLPSpanKind OTLP SpanKind 枚举；OTLP/JSON 中可以是整数，也可以是枚举名（如 "SPAN_KIND_SERVER"）
+type OTLPSpanKind int
+
+var otlpSpanKindNames = map[string]OTLPSpanKind{
+	"SPAN_KIND_UNSPECIFIED": 0,
+	"SPAN_KIND_INTERNAL":    1,
+	"SPAN_KIND_SERVER":      2,
+	"SPAN_KIND_CLIENT":      3,
+	"SPAN_KIND_PRODUCER":    4,
+	"SPAN_KIND_CONSUMER":    5,
+}
+
+// UnmarshalJSON 接受整数或枚举名
+func (k *OTLPSpanKind) UnmarshalJSON(b []byte) error {
+	var n int
+	if err := json.Unmarshal(b, &n); err == nil {
+		*k = OTLPSpanKind(n)
+		return nil
+	}
+	var name string
+	if err := json.Unmarshal(b, &name); err != nil {
+		return fmt.Errorf("invalid span kind %s", b)
+	}
+	kind, ok := otlpSpanKindNames[strings.ToUpper(name)]
+	if !ok {
+		return fmt.Errorf("unknown span kind %q", name)
+	}
+	*k = kind
+	return nil
+}
+
+// OTLPLink OTLP span 链接定义
+type OTLPLink struct {
+	TraceID    string          `json:"traceId"`
+	SpanID     string          `json:"spanId"`
+	Attributes []OTLPAttribute `json:"attributes,omitempty"`
 }
 
 // OTLPAttribute OTLP 属性定义
@@ -176,15 +216,39 @@ func convertOTLPSpan(otlpSpan OTLPSpan, defaultService string) (Span, error) {
 		serviceName = "unknown-service"
 	}
 
+	var links []SpanLink
+	for _, l := range otlpSpan.Links {
+		links = append(links, SpanLink{TraceID: l.TraceID, SpanID: l.SpanID})
+	}
+
 	return Span{
 		Name:       otlpSpan.Name,
 		Service:    serviceName,
 		StartNanos: startNanos,
 		EndNanos:   endNanos,
 		Attributes: attributes,
+		Kind:       otlpSpanKind(otlpSpan.Kind),
+		Links:      links,
 	}, nil
 }
 
+// otlpSpanKind 将 OTLP SpanKind 枚举转换为字符串
+func otlpSpanKind(kind OTLPSpanKind) string {
+	switch kind {
+	case 1:
+		return "internal"
+	case 2:
+		return "server"
+	case 3:
+		return "client"
+	case 4:
+		return "producer"
+	case 5:
+		return "consumer"
+	}
+	return ""
+}
+
 // convertOTLPValue 转换 OTLP 值为 Go 原生类型
 func convertOTLPValue(value OTLPValue) any {
 	if value.StringValue != "" {
@@ -3,6 +3,7 @@
 package trace
 
 import (
+	"encoding/json"
 	"testing"
 )
 
@@ -258,4 +259,57 @@ func TestEmptyOTLPData(t *testing.T) {
 	if len(trace.Spans) != 0 {
 		t.Errorf("Expected 0 spans, got %d", len(trace.Spans))
 	}
-}
\ No newline at end of file
+}
+
+func TestConvertOTLPSpan_KindAndLinks(t *testing.T) {
+	otlpSpan := OTLPSpan{
+		TraceID:           "trace1",
+		SpanID:            "consumer1",
+		Name:              "orders.created process",
+		StartTimeUnixNano: "2000",
+		EndTimeUnixNano:   "3000",
+		Kind:              5,
+		Links:             []OTLPLink{{TraceID: "trace1", SpanID: "producer1"}},
+		Attributes: []OTLPAttribute{
+			{Key: "messaging.destination.name", Value: OTLPValue{StringValue: "orders.created"}},
+		},
+	}
+
+	span, err := convertOTLPSpan(otlpSpan, "inventory")
+	if err != nil {
+		t.Fatalf("convertOTLPSpan failed: %v", err)
+	}
+	if span.Kind != "consumer" {
+		t.Errorf("expected kind consumer, got %q", span.Kind)
+	}
+	if len(span.Links) != 1 || span.Links[0].SpanID != "producer1" {
+		t.Errorf("expected link to producer1, got %+v", span.Links)
+	}
+	if span.MessagingRole() != MessagingConsume {
+		t.Errorf("expected consume role, got %q", span.MessagingRole())
+	}
+	if span.MessagingDestination() != "orders.created" {
+		t.Errorf("expected destination orders.created, got %q", span.MessagingDestination())
+	}
+}
+
+func TestLoadFromOTLPJSON_KindNames(t *testing.T) {
+	tr, err := LoadFromOTLPJSON("testdata/otlp-kind-names.json")
+	if err != nil {
+		t.Fatalf("LoadFromOTLPJSON failed: %v", err)
+	}
+	want := []string{"server", "producer", "client"}
+	if len(tr.Spans) != len(want) {
+		t.Fatalf("expected %d spans, got %d", len(want), len(tr.Spans))
+	}
+	for i, sp := range tr.Spans {
+		if sp.Kind != want[i] {
+			t.Errorf("span %s: expected kind %q, got %q", sp.Name, want[i], sp.Kind)
+		}
+	}
+
+	var kind OTLPSpanKind
+	if err := json.Unmarshal([]byte(`"SPAN_KIND_BOGUS"`), &kind); err == nil {
+		t.Error("expected an unknown kind name to be rejected")
+	}
+}
@@ -0,0 +1,237 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package validate
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/choreoatlas2025/cli/internal/spec"
+	"github.com/choreoatlas2025/cli/internal/trace"
+)
+
+// DefaultRareVariantThreshold flags variants observed in fewer than 5% of traces
+const DefaultRareVariantThreshold = 0.05
+
+// MaxVariantExamples limits the trace names listed per variant
+const MaxVariantExamples = 5
+
+// NamedTrace is a trace together with the name it is reported under (usually its file)
+type NamedTrace struct {
+	Name  string
+	Trace *trace.Trace
+}
+
+// StepStats aggregates one step's behavior across a trace corpus
+type StepStats struct {
+	Step             string  `json:"step"`
+	Call             string  `json:"call"`
+	Present          int     `json:"present"`          // a span was matched
+	InOrder          int     `json:"inOrder"`          // present and not started before a present predecessor
+	ConditionsPassed int     `json:"conditionsPassed"` // present and no condition failed
+	Passed           int     `json:"passed"`           // step result PASS
+	PresentRate      float64 `json:"presentRate"`
+	InOrderRate      float64 `json:"inOrderRate"`
+	ConditionRate    float64 `json:"conditionRate"`
+	PassRate         float64 `json:"passRate"`
+}
+
+// Variant is a distinct sequence of observed steps (by matched span start time)
+type Variant struct {
+	Sequence  []string `json:"sequence"`
+	Count     int      `json:"count"`
+	Frequency float64  `json:"frequency"`
+	Rare      bool     `json:"rare,omitempty"`
+	Traces    []string `json:"traces"` // up to MaxVariantExamples trace names
+}
+
+// ConformanceReport is the statistical conformance of a trace corpus to a flow
+type ConformanceReport struct {
+	TracesTotal     int         `json:"tracesTotal"`
+	Conforming      int         `json:"conforming"` // traces where validation passed
+	ConformanceRate float64     `json:"conformanceRate"`
+	RareThreshold   float64     `json:"rareThreshold"`
+	Steps           []StepStats `json:"steps"`
+	Variants        []Variant   `json:"variants"`
+}
+
+// AnalyzeTraces validates every trace against the flow and aggregates per-step
+// presence, ordering and condition statistics plus behavioral variants.
+// Variants whose frequency is below rareThreshold are flagged as rare.
+func AnalyzeTraces(fs *spec.FlowSpec, opIndex map[string]map[string]spec.ServiceOperation, traces []NamedTrace, rareThreshold float64) ConformanceReport {
+	report := ConformanceReport{TracesTotal: len(traces), RareThreshold: rareThreshold}
+
+	steps := flowSteps(fs)
+	preds := stepPredecessors(fs)
+	stats := make(map[string]*StepStats)
+	for _, st := range steps {
+		report.Steps = append(report.Steps, StepStats{Step: st.Step, Call: st.Call})
+	}
+	for i := range report.Steps {
+		stats[report.Steps[i].Step] = &report.Steps[i]
+	}
+	order := make(map[string]int)
+	for i, st := range steps {
+		order[st.Step] = i
+	}
+
+	variants := make(map[string]*Variant)
+	for _, nt := range traces {
+		results, ok := ValidateAgainstTrace(fs, opIndex, nt.Trace)
+		if ok {
+			report.Conforming++
+		}
+
+		spansByID := make(map[string]trace.Span)
+		for _, sp := range nt.Trace.Spans {
+			spansByID[getSpanID(sp)] = sp
+		}
+		spanOf := make(map[string]trace.Span)
+		for _, r := range results {
+			if sp, found := spansByID[r.SpanID]; found && r.SpanID != "" {
+				if _, declared := stats[r.Step]; declared {
+					spanOf[r.Step] = sp
+				}
+			}
+		}
+
+		for _, r := range results {
+			s := stats[r.Step]
+			if s == nil {
+				continue // internal or forbid results
+			}
+			if r.Status == "PASS" {
+				s.Passed++
+			}
+			sp, present := spanOf[r.Step]
+			if !present {
+				continue
+			}
+			s.Present++
+			inOrder := true
+			for _, p := range preds[r.Step] {
+				if psp, ok := spanOf[p]; ok && psp.StartNanos > sp.StartNanos {
+					inOrder = false
+				}
+			}
+			if inOrder {
+				s.InOrder++
+			}
+			condsOK := true
+			for _, c := range r.Conditions {
+				if c.Status == "FAIL" {
+					condsOK = false
+				}
+			}
+			if condsOK {
+				s.ConditionsPassed++
+			}
+		}
+
+		// Variant: observed steps ordered by span start time, then flow order
+		var seq []string
+		for name := range spanOf {
+			seq = append(seq, name)
+		}
+		sort.Slice(seq, func(i, j int) bool {
+			a, b := spanOf[seq[i]], spanOf[seq[j]]
+			if a.StartNanos != b.StartNanos {
+				return a.StartNanos < b.StartNanos
+			}
+			return order[seq[i]] < order[seq[j]]
+		})
+		key := strings.Join(seq, "\x00")
+		v := variants[key]
+		if v == nil {
+			v = &Variant{Sequence: seq}
+			variants[key] = v
+		}
+		v.Count++
+		if len(v.Traces) < MaxVariantExamples {
+			v.Traces = append(v.Traces, nt.Name)
+		}
+	}
+
+	if report.TracesTotal > 0 {
+		total := float64(report.TracesTotal)
+		report.ConformanceRate = float64(report.Conforming) / total
+		for i := range report.Steps {
+			s := &report.Steps[i]
+			s.PresentRate = float64(s.Present) / total
+			s.InOrderRate = float64(s.InOrder) / total
+			s.ConditionRate = float64(s.ConditionsPassed) / total
+			s.PassRate = float64(s.Passed) / total
+		}
+	}
+
+	for _, v := range variants {
+		v.Frequency = float64(v.Count) / float64(report.TracesTotal)
+		v.Rare = v.Frequency < rareThreshold
+		report.Variants = append(report.Variants, *v)
+	}
+	sort.Slice(report.Variants, func(i, j int) bool {
+		a, b := report.Variants[i], report.Variants[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return strings.Join(a.Sequence, ",") < strings.Join(b.Sequence, ",")
+	})
+	return report
+}
+
+// flowSteps lists the steps (parallel children expanded) or graph nodes in declaration order
+func flowSteps(fs *spec.FlowSpec) []spec.FlowStep {
+	var steps []spec.FlowStep
+	if fs.IsGraphMode() {
+		for _, name := range fs.GetStepNames() {
+			st, _ := fs.StepByName(name)
+			steps = append(steps, st)
+		}
+		return steps
+	}
+	for _, entry := range fs.Flow {
+		if len(entry.Parallel) > 0 {
+			steps = append(steps, entry.Parallel...)
+		} else {
+			steps = append(steps, entry)
+		}
+	}
+	return steps
+}
+
+// stepPredecessors maps each step to the steps that must start before it:
+// graph dependencies, or the previous flow entry. Compensations are excluded
+// since they run in reverse order.
+func stepPredecessors(fs *spec.FlowSpec) map[string][]string {
+	preds := make(map[string][]string)
+	if fs.IsGraphMode() {
+		for _, node := range fs.Graph.Nodes {
+			preds[node.ID] = getPredecessors(node.ID, fs.Graph)
+		}
+		return preds
+	}
+
+	compSteps := make(map[string]bool)
+	for _, c := range fs.Compensations() {
+		compSteps[c] = true
+	}
+	var prev []string
+	for _, entry := range fs.Flow {
+		members := []spec.FlowStep{entry}
+		if len(entry.Parallel) > 0 {
+			members = entry.Parallel
+		}
+		var names []string
+		for _, st := range members {
+			if compSteps[st.Step] {
+				continue
+			}
+			preds[st.Step] = prev
+			names = append(names, st.Step)
+		}
+		if len(names) > 0 {
+			prev = names
+		}
+	}
+	return preds
+}
@@ -0,0 +1,103 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package validate
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/choreoatlas2025/cli/internal/spec"
+	"github.com/choreoatlas2025/cli/internal/trace"
+)
+
+func TestAnalyzeTraces_StepStatsAndVariants(t *testing.T) {
+	fs := &spec.FlowSpec{
+		Flow: []spec.FlowStep{
+			{Step: "create", Call: "orderService.createOrder"},
+			{Step: "assess", Call: "riskService.assess"},
+			{Step: "pay", Call: "paymentService.charge"},
+		},
+	}
+	full := func() *trace.Trace {
+		return &trace.Trace{Spans: []trace.Span{
+			{Service: "orderService", Name: "createOrder", StartNanos: 100, EndNanos: 150},
+			{Service: "riskService", Name: "assess", StartNanos: 200, EndNanos: 250},
+			{Service: "paymentService", Name: "charge", StartNanos: 300, EndNanos: 350},
+		}}
+	}
+	skipRisk := &trace.Trace{Spans: []trace.Span{
+		{Service: "orderService", Name: "createOrder", StartNanos: 100, EndNanos: 150},
+		{Service: "paymentService", Name: "charge", StartNanos: 300, EndNanos: 350},
+	}}
+
+	var traces []NamedTrace
+	for i := 0; i < 97; i++ {
+		traces = append(traces, NamedTrace{Name: fmt.Sprintf("ok-%02d.json", i), Trace: full()})
+	}
+	for i := 0; i < 3; i++ {
+		traces = append(traces, NamedTrace{Name: fmt.Sprintf("skip-%d.json", i), Trace: skipRisk})
+	}
+
+	report := AnalyzeTraces(fs, nil, traces, DefaultRareVariantThreshold)
+	if report.TracesTotal != 100 || report.Conforming != 97 {
+		t.Fatalf("unexpected totals: %d traces, %d conforming", report.TracesTotal, report.Conforming)
+	}
+	if len(report.Steps) != 3 {
+		t.Fatalf("expected 3 step stats, got %+v", report.Steps)
+	}
+	if assess := report.Steps[1]; assess.Step != "assess" || math.Abs(assess.PresentRate-0.97) > 1e-9 {
+		t.Errorf("expected assess present in 97%% of traces, got %+v", assess)
+	}
+	if pay := report.Steps[2]; pay.Present != 100 || pay.InOrder != 100 {
+		t.Errorf("expected pay present and in order everywhere, got %+v", pay)
+	}
+
+	if len(report.Variants) != 2 {
+		t.Fatalf("expected 2 variants, got %+v", report.Variants)
+	}
+	main, rare := report.Variants[0], report.Variants[1]
+	if main.Count != 97 || main.Rare || len(main.Sequence) != 3 {
+		t.Errorf("unexpected main variant: %+v", main)
+	}
+	if rare.Count != 3 || !rare.Rare || len(rare.Traces) != 3 || rare.Sequence[1] != "pay" {
+		t.Errorf("expected rare variant skipping assess, got %+v", rare)
+	}
+	if len(main.Traces) != MaxVariantExamples {
+		t.Errorf("expected %d example traces, got %d", MaxVariantExamples, len(main.Traces))
+	}
+}
+
+func TestAnalyzeTraces_OrderAndConditions(t *testing.T) {
+	fs := &spec.FlowSpec{
+		Flow: []spec.FlowStep{
+			{Step: "create", Call: "orderService.createOrder"},
+			{Step: "pay", Call: "paymentService.processPayment"},
+		},
+	}
+	opIndex := map[string]map[string]spec.ServiceOperation{
+		"paymentService": {"processPayment": {OperationId: "processPayment",
+			Postconditions: map[string]spec.Condition{"paid": {Expr: "response.status == 200"}}}},
+	}
+	// Parent span IDs route validation through the call graph, which matches out-of-order spans
+	outOfOrder := &trace.Trace{Spans: []trace.Span{
+		sagaSpan("paymentService", "processPayment", 100, 200),
+		sagaSpan("orderService", "createOrder", 300, 201),
+	}}
+	for i := range outOfOrder.Spans {
+		outOfOrder.Spans[i].Attributes["otlp.parent_span_id"] = "root"
+	}
+	declined := &trace.Trace{Spans: []trace.Span{
+		sagaSpan("orderService", "createOrder", 100, 201),
+		sagaSpan("paymentService", "processPayment", 200, 402),
+	}}
+
+	report := AnalyzeTraces(fs, opIndex, []NamedTrace{{Name: "a", Trace: outOfOrder}, {Name: "b", Trace: declined}}, DefaultRareVariantThreshold)
+	pay := report.Steps[1]
+	if pay.InOrder != 1 || pay.ConditionsPassed != 1 {
+		t.Errorf("expected one out-of-order and one declined payment, got %+v", pay)
+	}
+	if len(report.Variants) != 2 || report.Variants[0].Rare {
+		t.Errorf("expected two equally frequent variants, got %+v", report.Variants)
+	}
+}
@@ -0,0 +1,129 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package validate
+
+import (
+	"math"
+
+	"github.com/choreoatlas2025/cli/internal/spec"
+	"github.com/choreoatlas2025/cli/internal/trace"
+)
+
+// MaxAssignmentSearch bounds the number of search states explored when assigning
+// steps to spans. Once reached, the best assignment found so far is used.
+var MaxAssignmentSearch = 20000
+
+// Assignment costs: an unmatched step outweighs any constraint violation, which
+// in turn outweighs a failed semantic check
+const (
+	costUnmatched    = 1000
+	costViolation    = 100
+	costSemanticFail = 10
+)
+
+// assignSlot holds the spans a step may be assigned to, in preference order
+type assignSlot struct {
+	candidates []int    // span indices
+	reasons    []string // match reason per candidate
+	costs      []int    // context-free cost per candidate
+}
+
+// assignment is the chosen span per slot (-1 when unmatched)
+type assignment struct {
+	spans []int
+	cost  int
+}
+
+// reason returns the match reason of the span assigned to a slot
+func (a assignment) reason(slots []assignSlot, slot int) string {
+	for i, c := range slots[slot].candidates {
+		if c == a.spans[slot] {
+			return slots[slot].reasons[i]
+		}
+	}
+	return ""
+}
+
+// costFunc returns the context-dependent cost of assigning span cand to slot,
+// given the spans assigned to the previous slots. ok=false rules the span out.
+type costFunc func(assigned []int, slot, cand int) (cost int, ok bool)
+
+// solveAssignment searches for the cheapest assignment of slots to distinct spans
+// with depth-first branch and bound. Candidates are tried in order and leaving a
+// slot unmatched is tried last, so the first complete assignment equals the
+// greedy first-match result; the search only ever improves on it.
+func solveAssignment(slots []assignSlot, extra costFunc) assignment {
+	n := len(slots)
+	best := assignment{cost: math.MaxInt}
+	cur := make([]int, n)
+	used := make(map[int]bool)
+	states := 0
+
+	var dfs func(slot, acc int)
+	dfs = func(slot, acc int) {
+		if acc >= best.cost {
+			return
+		}
+		if slot == n {
+			best = assignment{spans: append([]int(nil), cur...), cost: acc}
+			return
+		}
+		states++
+		for i, c := range slots[slot].candidates {
+			if used[c] {
+				continue
+			}
+			inc := slots[slot].costs[i]
+			if extra != nil {
+				more, ok := extra(cur[:slot], slot, c)
+				if !ok {
+					continue
+				}
+				inc += more
+			}
+			cur[slot] = c
+			used[c] = true
+			dfs(slot+1, acc+inc)
+			used[c] = false
+			if best.spans != nil && states >= MaxAssignmentSearch {
+				return
+			}
+		}
+		cur[slot] = -1
+		dfs(slot+1, acc+costUnmatched)
+	}
+	dfs(0, 0)
+	return best
+}
+
+// candidateSlot lists the spans matching a step. With semantic validation
+// enabled, spans failing the operation's conditions cost more.
+func candidateSlot(st spec.FlowStep, spans []trace.Span, opIndex map[string]map[string]spec.ServiceOperation) assignSlot {
+	var slot assignSlot
+	opSpec, hasOp := lookupOperation(opIndex, st.Call)
+	for i, sp := range spans {
+		ok, reason := matchStep(st, sp)
+		if !ok {
+			continue
+		}
+		cost := 0
+		if EnableSemantic && hasOp {
+			if _, okSem := EvaluateConditions(st, opSpec, sp, map[string]any{}); !okSem {
+				cost = costSemanticFail
+			}
+		}
+		slot.candidates = append(slot.candidates, i)
+		slot.reasons = append(slot.reasons, reason)
+		slot.costs = append(slot.costs, cost)
+	}
+	return slot
+}
+
+func lookupOperation(opIndex map[string]map[string]spec.ServiceOperation, call string) (spec.ServiceOperation, bool) {
+	svc, op, err := splitCall(call)
+	if err != nil {
+		return spec.ServiceOperation{}, false
+	}
+	opSpec, ok := opIndex[svc][op]
+	return opSpec, ok
+}
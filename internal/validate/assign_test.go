@@ -0,0 +1,110 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package validate
+
+import (
+	"testing"
+
+	"github.com/choreoatlas2025/cli/internal/spec"
+	"github.com/choreoatlas2025/cli/internal/trace"
+)
+
+func TestAssignment_TimeSequencePrefersSemanticallyValidSpan(t *testing.T) {
+	fs := &spec.FlowSpec{
+		Flow: []spec.FlowStep{
+			{Step: "create", Call: "orderService.createOrder"},
+			{Step: "pay", Call: "paymentService.processPayment"},
+		},
+	}
+	opIndex := map[string]map[string]spec.ServiceOperation{
+		"orderService": {"createOrder": {OperationId: "createOrder",
+			Postconditions: map[string]spec.Condition{"created": {Expr: "response.status == 201"}}}},
+	}
+	// The first createOrder attempt failed and was retried
+	tr := &trace.Trace{Spans: []trace.Span{
+		sagaSpan("orderService", "createOrder", 100, 500),
+		sagaSpan("orderService", "createOrder", 200, 201),
+		sagaSpan("paymentService", "processPayment", 300, 200),
+	}}
+
+	results, _ := ValidateAgainstTrace(fs, opIndex, tr)
+	byStep := resultByStep(results)
+	if r := byStep["create"]; r.Status != "PASS" || r.SpanID != getSpanID(tr.Spans[1]) {
+		t.Fatalf("expected create to be assigned to the retried span, got %s on %s (%s)", r.Status, r.SpanID, r.Message)
+	}
+	if byStep["pay"].Status != "PASS" {
+		t.Errorf("expected pay to pass, got %s", byStep["pay"].Status)
+	}
+}
+
+func graphRepeatFixture() (*spec.FlowSpec, *trace.Trace) {
+	fs := &spec.FlowSpec{
+		Graph: &spec.GraphSpec{
+			Nodes: []spec.GraphNode{
+				{ID: "reserve", Call: "inventoryService.reserve"},
+				{ID: "confirm", Call: "inventoryService.confirm", Depends: []string{"reserve"}},
+			},
+		},
+	}
+	fs.Graph.EnsureEdges()
+	tr := &trace.Trace{Spans: []trace.Span{
+		{Service: "inventoryService", Name: "confirm", StartNanos: 50, EndNanos: 60},
+		{Service: "inventoryService", Name: "reserve", StartNanos: 100, EndNanos: 150},
+		{Service: "inventoryService", Name: "confirm", StartNanos: 200, EndNanos: 250},
+	}}
+	return fs, tr
+}
+
+func TestAssignment_GraphHonorsDependencies(t *testing.T) {
+	fs, tr := graphRepeatFixture()
+	results, ok := ValidateAgainstTrace(fs, nil, tr)
+	if !ok {
+		t.Fatalf("expected consistent assignment to pass, got %+v", results)
+	}
+	if r := resultByStep(results)["confirm"]; r.SpanID != getSpanID(tr.Spans[2]) {
+		t.Errorf("expected confirm to use the span after reserve, got %s", r.SpanID)
+	}
+}
+
+func TestAssignment_SearchCapFallsBackToGreedy(t *testing.T) {
+	saved := MaxAssignmentSearch
+	MaxAssignmentSearch = 1
+	defer func() { MaxAssignmentSearch = saved }()
+
+	fs, tr := graphRepeatFixture()
+	results, ok := ValidateAgainstTrace(fs, nil, tr)
+	if ok {
+		t.Fatalf("expected capped search to keep the greedy assignment, got %+v", results)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected a result for every node, got %+v", results)
+	}
+}
+
+func TestAssignment_CausalityUsesDistinctSpans(t *testing.T) {
+	fs := &spec.FlowSpec{
+		Flow: []spec.FlowStep{
+			{Step: "first lookup", Call: "catalogService.lookup"},
+			{Step: "price", Call: "pricingService.quote"},
+			{Step: "second lookup", Call: "catalogService.lookup"},
+		},
+	}
+	span := func(id, service, name string, start int64) trace.Span {
+		return trace.Span{Service: service, Name: name, StartNanos: start, EndNanos: start + 50,
+			Attributes: map[string]any{"otlp.span_id": id, "otlp.parent_span_id": "root"}}
+	}
+	tr := &trace.Trace{Spans: []trace.Span{
+		span("s1", "catalogService", "lookup", 100),
+		span("s2", "pricingService", "quote", 200),
+		span("s3", "catalogService", "lookup", 300),
+	}}
+
+	results, ok := ValidateAgainstTrace(fs, nil, tr)
+	if !ok {
+		t.Fatalf("expected causality validation to pass, got %+v", results)
+	}
+	byStep := resultByStep(results)
+	if byStep["first lookup"].SpanID != "s1" || byStep["second lookup"].SpanID != "s3" {
+		t.Errorf("expected lookups on s1 and s3, got %s and %s", byStep["first lookup"].SpanID, byStep["second lookup"].SpanID)
+	}
+}
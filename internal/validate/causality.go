@@ -14,6 +14,8 @@ import (
 type CallGraph struct {
 	Nodes map[string]*CallNode `json:"nodes"`
 	Edges []*CallEdge          `json:"edges"`
+
+	messageTargets map[string][]string // 消息边索引：span ID -> 被其触发的 span IDs（惰性构建）
 }
 
 // CallNode 表示调用图中的节点
@@ -25,6 +27,8 @@ type CallNode struct {
 	StartNanos int64             `json:"startNanos"`
 	EndNanos   int64             `json:"endNanos"`
 	Attributes map[string]any    `json:"attributes"`
+	Kind       string            `json:"kind,omitempty"`
+	Links      []trace.SpanLink  `json:"links,omitempty"`
 	Children   []*CallNode       `json:"children,omitempty"`
 	Parent     *CallNode         `json:"parent,omitempty"`
 }
@@ -33,7 +37,7 @@ type CallNode struct {
 type CallEdge struct {
 	From        string `json:"from"`
 	To          string `json:"to"`
-	Relationship string `json:"relationship"` // "parent", "follows", "concurrent"
+	Relationship string `json:"relationship"` // "parent", "follows", "concurrent", "message"
 }
 
 // ParallelStep 表示并发步骤
@@ -59,6 +63,8 @@ func BuildCallGraph(spans []trace.Span) (*CallGraph, error) {
 			StartNanos: span.StartNanos,
 			EndNanos:   span.EndNanos,
 			Attributes: span.Attributes,
+			Kind:       span.Kind,
+			Links:      span.Links,
 			Children:   make([]*CallNode, 0),
 		}
 		graph.Nodes[spanID] = node
@@ -85,12 +91,55 @@ func BuildCallGraph(spans []trace.Span) (*CallGraph, error) {
 		}
 	}
 
+	// 建立消息关系（异步生产者 -> 消费者）
+	buildMessageEdges(graph, spans)
+
 	// 建立时序关系（同级spans的先后顺序）
 	buildTemporalEdges(graph)
 
 	return graph, nil
 }
 
+// buildMessageEdges 建立消息边：消费者通过 span link 指向生产者，
+// 或与生产者携带相同的 messaging.message.id
+func buildMessageEdges(graph *CallGraph, spans []trace.Span) {
+	seen := make(map[string]bool)
+	addEdge := func(from, to string) {
+		key := from + "->" + to
+		if from == to || seen[key] {
+			return
+		}
+		if _, ok := graph.Nodes[from]; !ok {
+			return
+		}
+		seen[key] = true
+		graph.Edges = append(graph.Edges, &CallEdge{
+			From:         from,
+			To:           to,
+			Relationship: "message",
+		})
+	}
+
+	producers := make(map[string][]string) // message ID -> producer span IDs
+	for _, span := range spans {
+		if span.MessagingRole() == trace.MessagingPublish && span.MessageID() != "" {
+			producers[span.MessageID()] = append(producers[span.MessageID()], getSpanID(span))
+		}
+	}
+
+	for _, span := range spans {
+		spanID := getSpanID(span)
+		for _, link := range span.Links {
+			addEdge(link.SpanID, spanID)
+		}
+		if span.MessagingRole() == trace.MessagingConsume && span.MessageID() != "" {
+			for _, producerID := range producers[span.MessageID()] {
+				addEdge(producerID, spanID)
+			}
+		}
+	}
+}
+
 // buildTemporalEdges 建立时序边
 func buildTemporalEdges(graph *CallGraph) {
 	// 按开始时间排序所有节点
@@ -128,113 +177,225 @@ func buildTemporalEdges(graph *CallGraph) {
 	}
 }
 
-// CheckCausality 检查因果关系和并发约束
-func CheckCausality(flow *spec.FlowSpec, graph *CallGraph) ([]StepResult, bool) {
-	var results []StepResult
-	allPassed := true
+// CheckCausality 检查因果关系和并发约束；opIndex 用于在重复调用中优先选择满足条件的 span（可为 nil）
+func CheckCausality(flow *spec.FlowSpec, graph *CallGraph, opIndex map[string]map[string]spec.ServiceOperation) ([]StepResult, bool) {
+	return checkFlowEntries(flow.Flow, graph, opIndex)
+}
 
-	// 处理常规流程步骤
-	for _, step := range flow.Flow {
-		if len(step.Parallel) > 0 {
-			// 并发步骤组 - 优先处理并发步骤
-			parallelResults := checkParallelSteps(step.Parallel, graph)
-			results = append(results, parallelResults...)
-			for _, pr := range parallelResults {
-				if pr.Status != "PASS" {
-					allPassed = false
+// checkFlowEntries 为流程条目（常规步骤或并发步骤组）整体分配调用图节点后逐一检查。
+// 分配时偏好：后续步骤晚于前一条目开始、并发组内的步骤时间重叠或同父。
+func checkFlowEntries(entries []spec.FlowStep, graph *CallGraph, opIndex map[string]map[string]spec.ServiceOperation) ([]StepResult, bool) {
+	// 节点按开始时间排序，保证候选顺序确定
+	nodes := sortedCallNodes(graph)
+	spans := make([]trace.Span, len(nodes))
+	for i, node := range nodes {
+		spans[i] = nodeSpan(node)
+	}
+
+	// 每个可解析的步骤对应一个 slot
+	var slots []assignSlot
+	var slotEntry []int // slot -> 条目下标
+	slotOf := make(map[*spec.FlowStep]int)
+	addSlot := func(entry int, st *spec.FlowStep) {
+		if _, _, err := splitCall(st.Call); err != nil {
+			return
+		}
+		slotOf[st] = len(slots)
+		slotEntry = append(slotEntry, entry)
+		slots = append(slots, candidateSlot(*st, spans, opIndex))
+	}
+	for i := range entries {
+		if len(entries[i].Parallel) > 0 {
+			for j := range entries[i].Parallel {
+				addSlot(i, &entries[i].Parallel[j])
+			}
+		} else if entries[i].Step != "" && entries[i].Call != "" {
+			addSlot(i, &entries[i])
+		}
+	}
+
+	sol := solveAssignment(slots, func(assigned []int, slot, cand int) (int, bool) {
+		cost := 0
+		entry := slotEntry[slot]
+		for k := slot - 1; k >= 0; k-- {
+			if assigned[k] < 0 {
+				continue
+			}
+			switch {
+			case slotEntry[k] == entry:
+				// 同一并发组：应时间重叠或同父
+				prev := nodes[assigned[k]]
+				if !isOverlapping(prev, nodes[cand]) && !hasSameParent(prev, nodes[cand]) {
+					cost += costViolation
 				}
+			case slotEntry[k] < entry:
+				// 前序条目：不应晚于当前步骤开始
+				if nodes[assigned[k]].StartNanos > nodes[cand].StartNanos {
+					cost += costViolation
+				}
+			}
+		}
+		if GlobalCausalityMode == CausalityStrict {
+			// strict：应与最近一个已分配的前序条目存在因果关系
+			var prev []*CallNode
+			prevEntry := -1
+			for k := slot - 1; k >= 0; k-- {
+				if assigned[k] < 0 || slotEntry[k] >= entry {
+					continue
+				}
+				if prevEntry >= 0 && slotEntry[k] != prevEntry {
+					break
+				}
+				prevEntry = slotEntry[k]
+				prev = append(prev, nodes[assigned[k]])
+			}
+			if len(prev) > 0 && !relatedToAny(graph, prev, nodes[cand]) {
+				cost += costViolation
 			}
+		}
+		return cost, true
+	})
+
+	matched := func(st *spec.FlowStep) (*CallNode, string) {
+		slot, ok := slotOf[st]
+		if !ok || sol.spans[slot] < 0 {
+			return nil, ""
+		}
+		return nodes[sol.spans[slot]], sol.reason(slots, slot)
+	}
+
+	var results []StepResult
+	allPassed := true
+	var prevNodes []*CallNode // 最近一个有匹配的前序条目的节点
+	var prevStep string
+	toleranceNanos := GlobalCausalityToleranceMs * 1000000
+	for i := range entries {
+		step := &entries[i]
+		var entryResults []StepResult
+		if len(step.Parallel) > 0 {
+			// 并发步骤组
+			entryResults = parallelStepResults(step.Parallel, matched)
 		} else if step.Step != "" && step.Call != "" {
 			// 常规步骤
-			result := checkSingleStep(step, graph)
-			results = append(results, result)
-			if result.Status != "PASS" {
+			node, reason := matched(step)
+			entryResults = []StepResult{singleStepResult(*step, node, reason)}
+		}
+
+		var entryNodes []*CallNode
+		for j := range entryResults {
+			if entryResults[j].Status != "PASS" {
+				continue
+			}
+			node := graph.Nodes[entryResults[j].SpanID]
+			entryNodes = append(entryNodes, node)
+			// 消费步骤与其生产者的关系由 checkMessageCausality 检查
+			consume := step.Kind == spec.StepKindConsume
+			if len(step.Parallel) > 0 {
+				consume = step.Parallel[j].Kind == spec.StepKindConsume
+			}
+			if GlobalCausalityMode != CausalityStrict || len(prevNodes) == 0 {
+				continue
+			}
+			// strict：顺序步骤必须晚于前序条目开始（硬约束，而非分配时的偏好）
+			if startsBeforeAny(prevNodes, node, toleranceNanos) {
+				entryResults[j].Status = "FAIL"
+				entryResults[j].Code = CodeOrderViolation
+				entryResults[j].Message = fmt.Sprintf("out of order: starts before step %s (strict mode)", prevStep)
+			} else if !consume && !relatedToAny(graph, prevNodes, node) {
+				entryResults[j].Status = "FAIL"
+				entryResults[j].Code = CodeOrderViolation
+				entryResults[j].Message = fmt.Sprintf("not causally related to step %s (no shared parent, parent-child or span link, strict mode)", prevStep)
+			}
+		}
+		if len(entryNodes) > 0 {
+			prevNodes = entryNodes
+			prevStep = step.Step
+			if len(step.Parallel) > 0 {
+				prevStep = step.Parallel[0].Step
+			}
+		}
+
+		for _, r := range entryResults {
+			if r.Status != "PASS" {
 				allPassed = false
 			}
 		}
+		results = append(results, entryResults...)
 	}
 
 	return results, allPassed
 }
 
+// sortedCallNodes 返回按开始时间（其次 SpanID）排序的节点
+func sortedCallNodes(graph *CallGraph) []*CallNode {
+	nodes := make([]*CallNode, 0, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].StartNanos != nodes[j].StartNanos {
+			return nodes[i].StartNanos < nodes[j].StartNanos
+		}
+		return nodes[i].SpanID < nodes[j].SpanID
+	})
+	return nodes
+}
+
 // checkSingleStep 检查单个步骤
 func checkSingleStep(step spec.FlowStep, graph *CallGraph) StepResult {
-	svc, op, err := splitCall(step.Call)
-	if err != nil {
+	results, _ := checkFlowEntries([]spec.FlowStep{step}, graph, nil)
+	return results[0]
+}
+
+// checkParallelSteps 检查并发步骤组
+func checkParallelSteps(parallelSteps []spec.FlowStep, graph *CallGraph) []StepResult {
+	results, _ := checkFlowEntries([]spec.FlowStep{{Parallel: parallelSteps}}, graph, nil)
+	return results
+}
+
+// singleStepResult 根据分配到的节点生成单个步骤的结果
+func singleStepResult(step spec.FlowStep, node *CallNode, reason string) StepResult {
+	if _, _, err := splitCall(step.Call); err != nil {
 		return StepResult{
 			Step:    step.Step,
 			Call:    step.Call,
 			Status:  "FAIL",
 			Message: fmt.Sprintf("Failed to parse call: %v", err),
+			Code:    CodeTraceError,
 		}
 	}
 
-	// 在图中查找匹配的节点
-	var matchedNode *CallNode
-	for _, node := range graph.Nodes {
-		if normalize(node.Service) == normalize(svc) && normalize(node.Operation) == normalize(op) {
-			matchedNode = node
-			break
-		}
-	}
-
-	if matchedNode == nil {
+	if node == nil {
 		return StepResult{
 			Step:    step.Step,
 			Call:    step.Call,
 			Status:  "FAIL",
 			Message: "No matching span found in trace",
+			Code:    CodeStepNotObserved,
 		}
 	}
 
 	return StepResult{
-		Step:   step.Step,
-		Call:   step.Call,
-		Status: "PASS",
+		Step:        step.Step,
+		Call:        step.Call,
+		Status:      "PASS",
+		SpanID:      node.SpanID,
+		MatchReason: reason,
 	}
 }
 
-// checkParallelSteps 检查并发步骤组
-func checkParallelSteps(parallelSteps []spec.FlowStep, graph *CallGraph) []StepResult {
+// parallelStepResults 生成并发步骤组的结果并验证并发约束
+func parallelStepResults(parallelSteps []spec.FlowStep, matched func(*spec.FlowStep) (*CallNode, string)) []StepResult {
 	var results []StepResult
 	var matchedNodes []*CallNode
 
-	// 找到所有并发步骤对应的节点
-	for _, step := range parallelSteps {
-		svc, op, err := splitCall(step.Call)
-		if err != nil {
-			results = append(results, StepResult{
-				Step:    step.Step,
-				Call:    step.Call,
-				Status:  "FAIL",
-				Message: fmt.Sprintf("Failed to parse call: %v", err),
-			})
-			continue
-		}
-
-		var matchedNode *CallNode
-		for _, node := range graph.Nodes {
-			if normalize(node.Service) == normalize(svc) && normalize(node.Operation) == normalize(op) {
-				matchedNode = node
-				break
-			}
-		}
-
-		if matchedNode == nil {
-			results = append(results, StepResult{
-				Step:    step.Step,
-				Call:    step.Call,
-				Status:  "FAIL",
-				Message: "No matching span found in trace",
-			})
-		} else {
-			matchedNodes = append(matchedNodes, matchedNode)
-			results = append(results, StepResult{
-				Step:   step.Step,
-				Call:   step.Call,
-				Status: "PASS",
-			})
+	for i := range parallelSteps {
+		node, reason := matched(&parallelSteps[i])
+		result := singleStepResult(parallelSteps[i], node, reason)
+		if result.Status == "PASS" {
+			matchedNodes = append(matchedNodes, node)
 		}
+		results = append(results, result)
 	}
 
 	// 验证并发约束：所有步骤应该在时间上重叠或属于同一父span
@@ -245,6 +406,7 @@ func checkParallelSteps(parallelSteps []spec.FlowStep, graph *CallGraph) []StepR
 				if results[i].Status == "PASS" {
 					results[i].Status = "FAIL"
 					results[i].Message = "Concurrency constraint violation: steps not executed concurrently"
+					results[i].Code = CodeOrderViolation
 				}
 			}
 		}
@@ -315,6 +477,70 @@ func isOverlapping(node1, node2 *CallNode) bool {
 	return node1.StartNanos < node2.EndNanos && node2.StartNanos < node1.EndNanos
 }
 
+// CausallyRelated 判断 from 与 to 是否存在因果关系（strict 模式使用）：
+// to 是 from 的后代；from 的父 span（编排者）也是 to 的祖先；
+// 或 from 子树中的某个 span 通过消息边（span link / message id）触发了 to 或其祖先。
+func (g *CallGraph) CausallyRelated(fromID, toID string) bool {
+	from, to := g.Nodes[fromID], g.Nodes[toID]
+	if from == nil || to == nil || from == to {
+		return false
+	}
+
+	ancestors := make(map[*CallNode]bool) // to 及其祖先
+	for n := to; n != nil && !ancestors[n]; n = n.Parent {
+		ancestors[n] = true
+	}
+	if ancestors[from] || (from.Parent != nil && ancestors[from.Parent]) {
+		return true
+	}
+
+	if g.messageTargets == nil {
+		g.messageTargets = make(map[string][]string)
+		for _, e := range g.Edges {
+			if e.Relationship == "message" {
+				g.messageTargets[e.From] = append(g.messageTargets[e.From], e.To)
+			}
+		}
+	}
+	visited := make(map[*CallNode]bool)
+	stack := []*CallNode{from}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if visited[n] {
+			continue
+		}
+		visited[n] = true
+		for _, target := range g.messageTargets[n.SpanID] {
+			if ancestors[g.Nodes[target]] {
+				return true
+			}
+		}
+		stack = append(stack, n.Children...)
+	}
+	return false
+}
+
+// relatedToAny 判断 node 是否与 prev 中任一节点存在因果关系
+func relatedToAny(graph *CallGraph, prev []*CallNode, node *CallNode) bool {
+	for _, p := range prev {
+		if graph.CausallyRelated(p.SpanID, node.SpanID) {
+			return true
+		}
+	}
+	return false
+}
+
+// startsBeforeAny 判断 node 是否早于 prev 中任一节点开始（超出容差）
+func startsBeforeAny(prev []*CallNode, node *CallNode, toleranceNanos int64) bool {
+	for _, p := range prev {
+		if node.StartNanos+toleranceNanos < p.StartNanos {
+			return true
+		}
+	}
+	return false
+}
+
 // ValidateSequentialSteps 验证顺序步骤（增强版的原有逻辑）
 func ValidateSequentialSteps(flow *spec.FlowSpec, spans []trace.Span) ([]StepResult, bool) {
 	// 构建调用图
@@ -325,11 +551,12 @@ func ValidateSequentialSteps(flow *spec.FlowSpec, spans []trace.Span) ([]StepRes
 			Call:    "internal",
 			Status:  "FAIL",
 			Message: fmt.Sprintf("Failed to build call graph: %v", err),
+			Code:    CodeTraceError,
 		}}, false
 	}
 
 	// 使用新的因果检查逻辑
-	return CheckCausality(flow, graph)
+	return CheckCausality(flow, graph, nil)
 }
 
 // GetCallGraphStats 获取调用图统计信息
@@ -340,12 +567,14 @@ func GetCallGraphStats(graph *CallGraph) map[string]any {
 		"rootNodes":       0,
 		"maxDepth":        0,
 		"concurrentPairs": 0,
+		"messageEdges":    0,
 		"services":        make(map[string]int),
 	}
 
 	serviceStats := make(map[string]int)
 	rootCount := 0
 	concurrentCount := 0
+	messageCount := 0
 
 	for _, node := range graph.Nodes {
 		serviceStats[node.Service]++
@@ -355,13 +584,17 @@ func GetCallGraphStats(graph *CallGraph) map[string]any {
 	}
 
 	for _, edge := range graph.Edges {
-		if edge.Relationship == "concurrent" {
+		switch edge.Relationship {
+		case "concurrent":
 			concurrentCount++
+		case "message":
+			messageCount++
 		}
 	}
 
 	stats["rootNodes"] = rootCount
 	stats["concurrentPairs"] = concurrentCount
+	stats["messageEdges"] = messageCount
 	stats["services"] = serviceStats
 
 	return stats
@@ -371,7 +604,7 @@ func GetCallGraphStats(graph *CallGraph) map[string]any {
 type EdgeViolation struct {
 	From    string `json:"from"`
 	To      string `json:"to"`
-	Type    string `json:"type"`    // "cycle", "causality", "overlap"
+	Type    string `json:"type"`    // "cycle", "causality", "overlap", "message"
 	Message string `json:"message"`
 }
 
@@ -477,6 +710,18 @@ func (g *CallGraph) ValidateEdgeConstraints(toleranceNanos int64) []EdgeViolatio
 						fromNode.Service, fromNode.Operation),
 				})
 			}
+		case "message":
+			// 验证消息关系：消费者不应早于生产者开始
+			if toNode.StartNanos < fromNode.StartNanos-toleranceNanos {
+				violations = append(violations, EdgeViolation{
+					From: edge.From,
+					To:   edge.To,
+					Type: "message",
+					Message: fmt.Sprintf("Message constraint violation: consumer %s.%s starts before producer %s.%s",
+						toNode.Service, toNode.Operation,
+						fromNode.Service, fromNode.Operation),
+				})
+			}
 		case "concurrent":
 			// 验证并发关系：应有时间重叠
 			if !isOverlapping(fromNode, toNode) {
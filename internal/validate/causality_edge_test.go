@@ -5,6 +5,7 @@ package validate
 import (
 	"testing"
 
+	"github.com/choreoatlas2025/cli/internal/spec"
 	"github.com/choreoatlas2025/cli/internal/trace"
 )
 
@@ -354,4 +355,26 @@ func TestGetTopologicalOrder(t *testing.T) {
 	if indexB >= indexD {
 		t.Errorf("B should come before D in topological order")
 	}
-}
\ No newline at end of file
+}
+
+func TestValidateAgainstTrace_ReturnsDAGViolations(t *testing.T) {
+	fs := &spec.FlowSpec{Graph: &spec.GraphSpec{Nodes: []spec.GraphNode{
+		{ID: "parent", Call: "serviceA.parent"},
+		{ID: "child", Call: "serviceB.child", Depends: []string{"parent"}},
+	}}}
+	tr := &trace.Trace{Spans: []trace.Span{
+		{Name: "parent", Service: "serviceA", StartNanos: 1000000000, EndNanos: 2000000000,
+			Attributes: map[string]any{"otlp.span_id": "span1"}},
+		{Name: "child", Service: "serviceB", StartNanos: 900000000, EndNanos: 2100000000,
+			Attributes: map[string]any{"otlp.span_id": "span2", "otlp.parent_span_id": "span1"}},
+	}}
+
+	results, ok := ValidateAgainstTrace(fs, nil, tr)
+	if ok {
+		t.Fatal("expected DAG violation to fail validation")
+	}
+	r := resultByStep(results)["DAG Validation"]
+	if r.Code != CodeOrderViolation || len(r.Violations) != 1 || r.Violations[0].Type != "parent-child" {
+		t.Errorf("expected the parent-child violation on the DAG Validation result, got %+v", r)
+	}
+}
@@ -3,6 +3,7 @@
 package validate
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/choreoatlas2025/cli/internal/spec"
@@ -122,7 +123,7 @@ func TestCheckCausality(t *testing.T) {
 		Edges: []*CallEdge{},
 	}
 
-	results, allPassed := CheckCausality(flowSpec, graph)
+	results, allPassed := CheckCausality(flowSpec, graph, nil)
 
 	if !allPassed {
 		t.Error("Expected all steps to pass causality check")
@@ -313,4 +314,244 @@ func TestNormalizeComparison(t *testing.T) {
 				tt.input1, tt.input2, tt.shouldMatch, matches)
 		}
 	}
-}
\ No newline at end of file
+}
+func strictSpan(id, parent, service, name string, start, end int64, links ...string) trace.Span {
+	attrs := map[string]any{"otlp.span_id": id}
+	if parent != "" {
+		attrs["otlp.parent_span_id"] = parent
+	}
+	sp := trace.Span{Service: service, Name: name, StartNanos: start, EndNanos: end, Attributes: attrs}
+	for _, l := range links {
+		sp.Links = append(sp.Links, trace.SpanLink{SpanID: l})
+	}
+	return sp
+}
+
+func TestStrictCausality_LegacyFlow(t *testing.T) {
+	flowSpec := &spec.FlowSpec{
+		Flow: []spec.FlowStep{
+			{Step: "reserve", Call: "inventoryService.reserve"},
+			{Step: "charge", Call: "paymentService.charge"},
+		},
+	}
+	tests := []struct {
+		name         string
+		spans        []trace.Span
+		strictPass   bool
+		temporalPass bool
+	}{
+		{
+			name: "siblings under orchestrator",
+			spans: []trace.Span{
+				strictSpan("root", "", "orderService", "createOrder", 0, 500),
+				strictSpan("a", "root", "inventoryService", "reserve", 100, 200),
+				strictSpan("b", "root", "paymentService", "charge", 250, 350),
+			},
+			strictPass: true, temporalPass: true,
+		},
+		{
+			name: "nested call",
+			spans: []trace.Span{
+				strictSpan("a", "", "inventoryService", "reserve", 100, 400),
+				strictSpan("b", "a", "paymentService", "charge", 150, 350),
+			},
+			strictPass: true, temporalPass: true,
+		},
+		{
+			name: "span link",
+			spans: []trace.Span{
+				strictSpan("a", "", "inventoryService", "reserve", 100, 200),
+				strictSpan("b", "", "paymentService", "charge", 300, 400, "a"),
+			},
+			strictPass: true, temporalPass: true,
+		},
+		{
+			name: "unrelated call trees",
+			spans: []trace.Span{
+				strictSpan("r1", "", "orderService", "createOrder", 0, 250),
+				strictSpan("a", "r1", "inventoryService", "reserve", 100, 200),
+				strictSpan("r2", "", "billingService", "run", 260, 500),
+				strictSpan("b", "r2", "paymentService", "charge", 300, 400),
+			},
+			strictPass: false, temporalPass: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &trace.Trace{Spans: tt.spans}
+			for _, mode := range []CausalityMode{CausalityStrict, CausalityTemporal} {
+				withCausalityMode(t, mode)
+				results, ok := ValidateAgainstTrace(flowSpec, nil, tr)
+				want := tt.temporalPass
+				if mode == CausalityStrict {
+					want = tt.strictPass
+				}
+				if ok != want {
+					t.Errorf("%s: expected ok=%v, got %+v", mode, want, results)
+				}
+				if !ok && !strings.Contains(results[1].Message, "not causally related to step reserve") {
+					t.Errorf("%s: expected causality message on charge, got %+v", mode, results[1])
+				}
+			}
+		})
+	}
+}
+
+func TestStrictCausality_TimeOnlyTrace(t *testing.T) {
+	// Without parent IDs or links strict mode cannot establish causality
+	flowSpec := &spec.FlowSpec{
+		Flow: []spec.FlowStep{
+			{Step: "reserve", Call: "inventoryService.reserve"},
+			{Step: "charge", Call: "paymentService.charge"},
+		},
+	}
+	tr := &trace.Trace{Spans: []trace.Span{
+		{Service: "inventoryService", Name: "reserve", StartNanos: 100, EndNanos: 200},
+		{Service: "paymentService", Name: "charge", StartNanos: 300, EndNanos: 400},
+	}}
+
+	withCausalityMode(t, CausalityTemporal)
+	if _, ok := ValidateAgainstTrace(flowSpec, nil, tr); !ok {
+		t.Error("expected temporal mode to accept time-ordered spans")
+	}
+	withCausalityMode(t, CausalityStrict)
+	if results, ok := ValidateAgainstTrace(flowSpec, nil, tr); ok || results[0].Status != "PASS" || results[1].Status != "FAIL" {
+		t.Errorf("expected strict mode to fail the unrelated second step, got %+v", results)
+	}
+}
+
+func TestStrictCausality_GraphSiblings(t *testing.T) {
+	flowSpec := &spec.FlowSpec{
+		Graph: &spec.GraphSpec{
+			Nodes: []spec.GraphNode{
+				{ID: "reserve", Call: "inventoryService.reserve"},
+				{ID: "charge", Call: "paymentService.charge", Depends: []string{"reserve"}},
+			},
+		},
+	}
+	flowSpec.Graph.EnsureEdges()
+
+	// Before, strict mode demanded charge be a direct child of reserve
+	siblings := &trace.Trace{Spans: []trace.Span{
+		strictSpan("root", "", "orderService", "createOrder", 0, 500),
+		strictSpan("a", "root", "inventoryService", "reserve", 100, 200),
+		strictSpan("b", "root", "paymentService", "charge", 250, 350),
+	}}
+	withCausalityMode(t, CausalityStrict)
+	if results, ok := ValidateAgainstTrace(flowSpec, nil, siblings); !ok {
+		t.Errorf("expected siblings under orchestrator to pass strict mode, got %+v", results)
+	}
+
+	unrelated := &trace.Trace{Spans: []trace.Span{
+		strictSpan("a", "", "inventoryService", "reserve", 100, 200),
+		strictSpan("b", "", "paymentService", "charge", 250, 350),
+	}}
+	if _, ok := ValidateAgainstTrace(flowSpec, nil, unrelated); ok {
+		t.Error("expected unrelated root spans to fail strict mode")
+	}
+	withCausalityMode(t, CausalityTemporal)
+	if results, ok := ValidateAgainstTrace(flowSpec, nil, unrelated); !ok {
+		t.Errorf("expected temporal mode to accept ordered root spans, got %+v", results)
+	}
+}
+
+func TestCausality_EvaluatesDeclaredInput(t *testing.T) {
+	flowSpec := &spec.FlowSpec{
+		Flow: []spec.FlowStep{
+			{Step: "create", Call: "orderService.createOrder", Input: map[string]any{"body": map[string]any{"x": "1"}}},
+		},
+	}
+	opIndex := map[string]map[string]spec.ServiceOperation{
+		"orderService": {"createOrder": {OperationId: "createOrder", Preconditions: map[string]spec.Condition{
+			"declaredX": {Expr: "declared.body.x == '1'"},
+		}}},
+	}
+	tr := &trace.Trace{Spans: []trace.Span{
+		strictSpan("root", "", "gateway", "handle", 0, 500),
+		strictSpan("a", "root", "orderService", "createOrder", 100, 200),
+	}}
+
+	withCausalityMode(t, CausalityTemporal)
+	results, ok := ValidateAgainstTrace(flowSpec, opIndex, tr)
+	if !ok || len(results) != 1 || len(results[0].Conditions) != 1 || results[0].Conditions[0].Status != "PASS" {
+		t.Errorf("expected declared input to be available on the causality path, got %+v", results)
+	}
+}
+
+func TestCausality_PrefersSpanSatisfyingConditions(t *testing.T) {
+	flowSpec := &spec.FlowSpec{
+		Flow: []spec.FlowStep{{Step: "charge", Call: "paymentService.charge"}},
+	}
+	opIndex := map[string]map[string]spec.ServiceOperation{
+		"paymentService": {"charge": {OperationId: "charge", Postconditions: map[string]spec.Condition{
+			"approved": {Expr: "attrs['payment.approved'] == true"},
+		}}},
+	}
+	declined := strictSpan("a", "root", "paymentService", "charge", 100, 200)
+	declined.Attributes["payment.approved"] = false
+	approved := strictSpan("b", "root", "paymentService", "charge", 250, 350)
+	approved.Attributes["payment.approved"] = true
+	tr := &trace.Trace{Spans: []trace.Span{
+		strictSpan("root", "", "orderService", "createOrder", 0, 500),
+		declined,
+		approved,
+	}}
+
+	withCausalityMode(t, CausalityTemporal)
+	results, ok := ValidateAgainstTrace(flowSpec, opIndex, tr)
+	if !ok || results[0].SpanID != "b" {
+		t.Errorf("expected the retry that satisfies the postcondition to be matched, got %+v", results)
+	}
+}
+
+func TestStrictCausality_OutOfOrderSteps(t *testing.T) {
+	flowSpec := &spec.FlowSpec{
+		Flow: []spec.FlowStep{
+			{Step: "reserve", Call: "inventoryService.reserve"},
+			{Step: "charge", Call: "paymentService.charge"},
+		},
+	}
+	const ms = int64(1000000)
+	// Siblings under the orchestrator, but charge ran well before reserve
+	tr := &trace.Trace{Spans: []trace.Span{
+		strictSpan("root", "", "orderService", "createOrder", 0, 1000*ms),
+		strictSpan("a", "root", "inventoryService", "reserve", 500*ms, 600*ms),
+		strictSpan("b", "root", "paymentService", "charge", 100*ms, 200*ms),
+	}}
+
+	withCausalityMode(t, CausalityStrict)
+	results, ok := ValidateAgainstTrace(flowSpec, nil, tr)
+	charge := resultByStep(results)["charge"]
+	if ok || charge.Status != "FAIL" || charge.Code != CodeOrderViolation || !strings.Contains(charge.Message, "out of order") {
+		t.Fatalf("expected strict mode to fail the out-of-order step with %s, got %+v", CodeOrderViolation, results)
+	}
+
+	ExplainFailures(flowSpec, nil, tr, results)
+	charge = resultByStep(results)["charge"]
+	if len(charge.Candidates) == 0 || charge.Candidates[0].SpanID != "b" ||
+		!strings.Contains(strings.Join(charge.Candidates[0].Rejected, "; "), "out of order") {
+		t.Errorf("expected explain to report the order mismatch, got %+v", charge.Candidates)
+	}
+}
+
+func TestExplainFailures_StrictFlowCausality(t *testing.T) {
+	flowSpec := &spec.FlowSpec{
+		Flow: []spec.FlowStep{
+			{Step: "reserve", Call: "inventoryService.reserve"},
+			{Step: "charge", Call: "paymentService.charge"},
+		},
+	}
+	tr := &trace.Trace{Spans: []trace.Span{
+		strictSpan("a", "", "inventoryService", "reserve", 100, 200),
+		strictSpan("b", "", "paymentService", "charge", 300, 400),
+	}}
+
+	withCausalityMode(t, CausalityStrict)
+	results, _ := ValidateAgainstTrace(flowSpec, nil, tr)
+	ExplainFailures(flowSpec, nil, tr, results)
+	charge := resultByStep(results)["charge"]
+	if len(charge.Candidates) == 0 || charge.Candidates[0].SpanID != "b" ||
+		!strings.Contains(strings.Join(charge.Candidates[0].Rejected, "; "), "causality mismatch: not causally related to step reserve") {
+		t.Errorf("expected explain to report the causality mismatch, got %+v", charge.Candidates)
+	}
+}
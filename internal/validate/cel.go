@@ -3,9 +3,12 @@
 package validate
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/url"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/google/cel-go/cel"
 	"github.com/google/cel-go/common/types"
@@ -16,24 +19,29 @@ import (
 
 // 条件结果
 type ConditionResult struct {
-	Kind    string `json:"kind"`    // "pre" | "post"
-	Name    string `json:"name"`
-	Expr    string `json:"expr"`
-	Status  string `json:"status"`  // "PASS" | "FAIL" | "SKIP"
-	Message string `json:"message"` // 失败/跳过原因
+	Kind     string `json:"kind"` // "pre" | "post"
+	Name     string `json:"name"`
+	Expr     string `json:"expr"`
+	Status   string `json:"status"`             // "PASS" | "FAIL" | "SKIP" | "WARN"（warn/info 级条件失败）
+	Severity string `json:"severity,omitempty"` // "error" | "warn" | "info"
+	Message  string `json:"message"`            // 失败/跳过原因
+
+	Code     string         `json:"code,omitempty"`     // 失败/跳过时的诊断码
+	Position *spec.Position `json:"position,omitempty"` // 表达式在 ServiceSpec 中的位置
 }
 
-// 将 FlowSpec 的 input + span.attributes 投影为 CEL 环境可用的变量
+// 将 span.attributes 与 FlowSpec 的 input 投影为 CEL 环境可用的变量
 // 约定：
-// - request: 来自 step.input（会做 ${var} 的占位保留，不做替换以免误导，可后续扩展变量解引用）
+// - request: 从 span 属性还原实际请求 { method, path, query, params, headers, body }（见 requestVar）
+// - declared: FlowSpec 中声明的 step.input 原样（${var} 占位保留，不做替换）
 // - response: 从 span.attributes 映射（response.status 优先取：response.status|http.status_code|statusCode）
-// - span: { name, service, attributes }
+// - span: { name, service, kind, attributes, startNanos, endNanos, durationMs }
+// - http / rpc / messaging / attrs: span.attributes 的命名空间视图（见 attributeNamespaces）
 // - vars: 从前序步骤输出收集（可选，当前为占位）
 func buildEvalEnvForStep(step spec.FlowStep, sp trace.Span, vars map[string]any) (map[string]any, error) {
-	// request 直接采用 FlowSpec 中的 input 原样
-	request := map[string]any{}
-	if step.Input != nil {
-		request["body"] = step.Input // 约定 input 即 body，满足大多数 REST 场景
+	declared := map[string]any{}
+	for k, v := range step.Input {
+		declared[k] = v
 	}
 
 	// 响应投影：尽量从 attributes 推断出 response.status / response.body
@@ -59,61 +67,274 @@ func buildEvalEnvForStep(step spec.FlowStep, sp trace.Span, vars map[string]any)
 		response["body"] = sp.Attributes
 	}
 
-	span := map[string]any{
+	env := map[string]any{
+		"request":  requestVar(sp.Attributes),
+		"declared": declared,
+		"response": response,
+		"span":     spanVar(sp),
+		"vars":     vars,
+	}
+	for name, ns := range attributeNamespaces(sp.Attributes) {
+		env[name] = ns
+	}
+	return env, nil
+}
+
+// attributeNamespaces 将 span 属性投影为 discover 生成条件所用的变量模型：
+// - http / rpc / messaging: 对应前缀的属性，去掉前缀后按 "." 展开为嵌套 map
+//   （http.method -> http.method，messaging.destination.name -> messaging.destination.name）
+// - attrs: 原始属性（attrs['http.status_code']），适用于任意键
+func attributeNamespaces(attributes map[string]any) map[string]any {
+	namespaces := map[string]any{}
+	for _, prefix := range []string{"http", "rpc", "messaging"} {
+		namespaces[prefix] = map[string]any{}
+	}
+	attrs := map[string]any{}
+	for key, value := range attributes {
+		attrs[key] = value
+		prefix, rest, ok := strings.Cut(key, ".")
+		ns, known := namespaces[prefix].(map[string]any)
+		if !ok || !known || rest == "" {
+			continue
+		}
+		setNested(ns, strings.Split(rest, "."), value)
+	}
+	namespaces["attrs"] = attrs
+	return namespaces
+}
+
+// setNested 按路径写入嵌套 map；与已有键冲突时（如 http.request 与 http.request.method）保留先出现的
+func setNested(m map[string]any, parts []string, value any) {
+	for _, part := range parts[:len(parts)-1] {
+		child, isMap := m[part].(map[string]any)
+		if _, exists := m[part]; exists && !isMap {
+			return
+		}
+		if child == nil {
+			child = map[string]any{}
+			m[part] = child
+		}
+		m = child
+	}
+	if _, exists := m[parts[len(parts)-1]]; !exists {
+		m[parts[len(parts)-1]] = value
+	}
+}
+
+// requestVar 从 span 属性还原实际发送的请求：
+// - method: http.request.method | http.method
+// - path / query: url.path、url.query，或从 http.target / url.full / http.url 解析；query 多值时取第一个
+// - params: 按 http.route 模板（/orders/{id} 或 /orders/:id）从 path 提取的路径参数
+// - headers: http.request.header.*（名称小写，多值以 ", " 连接）
+// - body: request.body（map 或 JSON 字符串）与 request.body.* 属性（按 "." 展开）
+func requestVar(attributes map[string]any) map[string]any {
+	headers := map[string]any{}
+	body := map[string]any{}
+	for key, value := range attributes {
+		lower := strings.ToLower(key)
+		switch {
+		case strings.HasPrefix(lower, "http.request.header."):
+			headers[strings.TrimPrefix(lower, "http.request.header.")] = headerValue(value)
+		case strings.HasPrefix(key, "request.body.") && len(key) > len("request.body."):
+			setNested(body, strings.Split(strings.TrimPrefix(key, "request.body."), "."), value)
+		}
+	}
+	if raw, ok := attributes["request.body"]; ok {
+		if s, isStr := raw.(string); isStr {
+			var decoded map[string]any
+			if json.Unmarshal([]byte(s), &decoded) == nil {
+				raw = decoded
+			}
+		}
+		if m, isMap := raw.(map[string]any); isMap {
+			for k, v := range m {
+				body[k] = v
+			}
+		}
+	}
+
+	method, _ := firstString(attributes, "http.request.method", "http.method")
+	path, _ := firstString(attributes, "url.path")
+	rawQuery, hasQuery := firstString(attributes, "url.query")
+	if target, ok := firstString(attributes, "http.target", "url.full", "http.url"); ok && (path == "" || !hasQuery) {
+		if u, err := url.Parse(target); err == nil {
+			if path == "" {
+				path = u.Path
+			}
+			if !hasQuery {
+				rawQuery = u.RawQuery
+			}
+		}
+	}
+	query := map[string]any{}
+	if values, err := url.ParseQuery(rawQuery); err == nil {
+		for k, v := range values {
+			query[k] = v[0]
+		}
+	}
+	params := map[string]any{}
+	if route, ok := firstString(attributes, "http.route"); ok {
+		params = routeParams(route, path)
+	}
+
+	return map[string]any{
+		"method":  method,
+		"path":    path,
+		"query":   query,
+		"params":  params,
+		"headers": headers,
+		"body":    body,
+	}
+}
+
+func firstString(attributes map[string]any, keys ...string) (string, bool) {
+	for _, k := range keys {
+		if s, ok := attributes[k].(string); ok {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// headerValue 将 OTel 的数组型 header 值合并为字符串
+func headerValue(v any) any {
+	list, ok := v.([]any)
+	if !ok {
+		return v
+	}
+	parts := make([]string, len(list))
+	for i, item := range list {
+		parts[i] = fmt.Sprint(item)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// routeParams 按路由模板提取路径参数；段数或字面段不一致时返回空
+func routeParams(route, path string) map[string]any {
+	params := map[string]any{}
+	rs := strings.Split(strings.Trim(route, "/"), "/")
+	ps := strings.Split(strings.Trim(path, "/"), "/")
+	if len(rs) != len(ps) {
+		return params
+	}
+	for i, seg := range rs {
+		switch {
+		case strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}"):
+			params[seg[1:len(seg)-1]] = ps[i]
+		case strings.HasPrefix(seg, ":"):
+			params[seg[1:]] = ps[i]
+		case seg != ps[i]:
+			return map[string]any{}
+		}
+	}
+	return params
+}
+
+// celEnvOptions 声明条件、匹配与禁止规则共用的 CEL 变量
+func celEnvOptions() []cel.EnvOption {
+	var opts []cel.EnvOption
+	for _, name := range []string{"request", "declared", "response", "span", "parent", "vars", "http", "rpc", "messaging", "attrs"} {
+		opts = append(opts, cel.Variable(name, cel.DynType))
+	}
+	return append(opts, celLibrary()...)
+}
+
+// spanVar 将 span 投影为 CEL 变量：{ name, service, kind, attributes, startNanos, endNanos, durationMs }
+func spanVar(sp trace.Span) map[string]any {
+	attrs := sp.Attributes
+	if attrs == nil {
+		attrs = map[string]any{}
+	}
+	durationMs := 0.0
+	if sp.EndNanos > sp.StartNanos {
+		durationMs = float64(sp.EndNanos-sp.StartNanos) / 1e6
+	}
+	return map[string]any{
 		"name":       sp.Name,
 		"service":    sp.Service,
-		"attributes": sp.Attributes,
+		"kind":       sp.Kind,
+		"attributes": attrs,
+		"startNanos": sp.StartNanos,
+		"endNanos":   sp.EndNanos,
+		"durationMs": durationMs,
 	}
+}
 
-	return map[string]any{
-		"request":  request,
-		"response": response,
-		"span":     span,
-		"vars":     vars,
-	}, nil
+// spanEnv 构造仅含 span 的求值环境（匹配器与禁止规则使用）
+func spanEnv(sp trace.Span) map[string]any {
+	env := attributeNamespaces(sp.Attributes)
+	env["span"] = spanVar(sp)
+	return env
+}
+
+// compiledCEL 是缓存的编译结果（含编译/类型错误，避免重复编译失败的表达式）
+type compiledCEL struct {
+	prg   cel.Program
+	phase string // 出错阶段：compile | program | type
+	err   error
+}
+
+var (
+	celEnvOnce  sync.Once
+	sharedEnv   *cel.Env
+	sharedErr   error
+	celPrograms = newLRUCache[*compiledCEL](celCacheSize) // expr -> 编译结果
+)
+
+// celCacheSize 是编译结果缓存的容量，足以容纳一个工作区的全部条件
+const celCacheSize = 1024
+
+// compileCached 编译、类型检查并规划表达式，结果按表达式缓存（LRU，容量有限）。
+// 结果类型必须为 bool（或动态类型，求值时再检查）。
+func compileCached(expr string) *compiledCEL {
+	if c, ok := celPrograms.Get(expr); ok {
+		return c
+	}
+	celEnvOnce.Do(func() {
+		sharedEnv, sharedErr = cel.NewEnv(celEnvOptions()...)
+	})
+	c := &compiledCEL{}
+	if sharedErr != nil {
+		c.phase, c.err = "env", fmt.Errorf("create cel env: %w", sharedErr)
+	} else if ast, issues := sharedEnv.Compile(normalizeExpr(expr)); issues != nil && issues.Err() != nil {
+		c.phase, c.err = "compile", issues.Err()
+	} else if out := ast.OutputType(); !out.IsExactType(cel.BoolType) && !out.IsExactType(cel.DynType) {
+		c.phase, c.err = "type", fmt.Errorf("result type %s is not bool", out)
+	} else if c.prg, c.err = sharedEnv.Program(ast); c.err != nil {
+		c.phase = "program"
+	}
+	return celPrograms.Add(expr, c)
+}
+
+// compileCEL 仅编译并检查表达式（lint 阶段使用），不求值
+func compileCEL(expr string) error {
+	return compileCached(expr).err
 }
 
-// 简单规范化表达式：支持 foo =~ /re/ 语法，转为 foo.matches("re")
-var reLike = regexp.MustCompile(`\s*=~\s*/([^/]+)/`)
+// 简单规范化表达式：支持 foo =~ /re/ 语法，转为 foo.matches("re")；正则中的 / 写作 \/
+var reLike = regexp.MustCompile(`\s*=~\s*/((?:\\.|[^/\\])+)/`)
 
 func normalizeExpr(e string) string {
 	// 将 x =~ /abc/ 替换为 x.matches("abc")
-	// 注意：此实现是简化版，不支持包含斜杠转义的复杂正则
 	return reLike.ReplaceAllStringFunc(e, func(m string) string {
 		sub := reLike.FindStringSubmatch(m)
 		if len(sub) != 2 {
 			return m
 		}
-		re := sub[1]
-		return fmt.Sprintf(`.matches("%s")`, strings.ReplaceAll(re, `"`, `\"`))
+		re := strings.ReplaceAll(sub[1], `\/`, "/")
+		// 转为 CEL 字符串字面量：保留正则中的反斜杠转义（\d、\.）
+		re = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(re)
+		return fmt.Sprintf(`.matches("%s")`, re)
 	})
 }
 
 func evalCELBool(expr string, envVars map[string]any) (bool, string, error) {
-	e := normalizeExpr(expr)
-
-	// 使用新版 CEL API 创建环境
-	celEnv, err := cel.NewEnv(
-		cel.Variable("request", cel.DynType),
-		cel.Variable("response", cel.DynType),
-		cel.Variable("span", cel.DynType),
-		cel.Variable("vars", cel.DynType),
-	)
-	if err != nil {
-		return false, "", fmt.Errorf("create cel env: %w", err)
-	}
-
-	ast, issues := celEnv.Compile(e)
-	if issues != nil && issues.Err() != nil {
-		return false, "compile", issues.Err()
+	c := compileCached(expr)
+	if c.err != nil {
+		return false, c.phase, c.err
 	}
 
-	prg, err := celEnv.Program(ast)
-	if err != nil {
-		return false, "program", err
-	}
-
-	out, _, err := prg.Eval(envVars)
+	out, _, err := c.prg.Eval(envVars)
 	if err != nil {
 		return false, "runtime", err
 	}
@@ -128,7 +349,8 @@ func evalCELBool(expr string, envVars map[string]any) (bool, string, error) {
 }
 
 // EvaluateConditions 对某一步骤的 pre/postconditions 进行求值
-// 说明：编译错误/不支持表达式 -> SKIP，不计为失败
+// 说明：编译错误/不支持表达式 -> SKIP，不计为失败；引用数据缺失时按 onMissing 处理。
+// severity 为 warn/info 的条件失败时记为 WARN，不影响步骤结果。
 func EvaluateConditions(
 	step spec.FlowStep,
 	op spec.ServiceOperation,
@@ -141,39 +363,45 @@ func EvaluateConditions(
 
 	envVars, _ := buildEvalEnvForStep(step, sp, vars)
 
-	// 预条件
-	for name, expr := range op.Preconditions {
-		ok, phase, err := evalCELBool(expr, envVars)
-		cr := ConditionResult{Kind: "pre", Name: name, Expr: expr}
-		if err != nil {
-			cr.Status = "SKIP"
-			cr.Message = fmt.Sprintf("unsupported or compilation failed (%s): %v", phase, err)
-		} else if ok {
-			cr.Status = "PASS"
-		} else {
-			cr.Status = "FAIL"
-			cr.Message = "result is false"
-			passAll = false
-		}
-		results = append(results, cr)
-	}
-
-	// 后置条件
-	for name, expr := range op.Postconditions {
-		ok, phase, err := evalCELBool(expr, envVars)
-		cr := ConditionResult{Kind: "post", Name: name, Expr: expr}
-		if err != nil {
-			cr.Status = "SKIP"
-			cr.Message = fmt.Sprintf("unsupported or compilation failed (%s): %v", phase, err)
-		} else if ok {
-			cr.Status = "PASS"
-		} else {
-			cr.Status = "FAIL"
-			cr.Message = "result is false"
-			passAll = false
+	for _, section := range []struct {
+		kind  string
+		conds map[string]spec.Condition
+	}{{"pre", op.Preconditions}, {"post", op.Postconditions}} {
+		for name, cond := range section.conds {
+			cr := evaluateCondition(section.kind, name, cond, envVars)
+			if cr.Status == "FAIL" {
+				passAll = false
+			}
+			results = append(results, cr)
 		}
-		results = append(results, cr)
 	}
 
 	return results, passAll
-}
\ No newline at end of file
+}
+
+// evaluateCondition 求值单个条件并按 severity/onMissing 确定状态
+func evaluateCondition(kind, name string, cond spec.Condition, envVars map[string]any) ConditionResult {
+	cr := ConditionResult{Kind: kind, Name: name, Expr: cond.Expr, Severity: cond.SeverityLevel(), Position: positionRef(cond.Pos)}
+	ok, phase, err := evalCELBool(cond.Expr, envVars)
+	switch {
+	case err != nil && phase == "runtime" && cond.FailOnMissing():
+		cr.Status = "FAIL"
+		cr.Message = fmt.Sprintf("referenced data is missing (onMissing: fail): %v", err)
+	case err != nil:
+		cr.Status = "SKIP"
+		cr.Code = CodeConditionSkipped
+		cr.Message = fmt.Sprintf("unsupported or compilation failed (%s): %v", phase, err)
+		return cr
+	case ok:
+		cr.Status = "PASS"
+		return cr
+	default:
+		cr.Status = "FAIL"
+		cr.Message = "result is false"
+	}
+	cr.Code = CodeConditionFailed
+	if cr.Severity != spec.SeverityError {
+		cr.Status = "WARN"
+	}
+	return cr
+}
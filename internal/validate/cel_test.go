@@ -0,0 +1,308 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package validate
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/choreoatlas2025/cli/internal/spec"
+	"github.com/choreoatlas2025/cli/internal/trace"
+)
+
+// Conditions generated by discover must pass against the trace they came from
+func TestDiscoveredConditions_EvaluateAgainstSourceTrace(t *testing.T) {
+	spans := []trace.Span{
+		{
+			Name: "POST /orders", Service: "orders",
+			Attributes: map[string]any{
+				"http.method":                       "POST",
+				"http.route":                        "/orders",
+				"http.status_code":                  float64(201),
+				"http.request.header.authorization": "Bearer abc",
+				"request.body.customer-id":          "c'1",
+			},
+		},
+		{
+			Name: "GET /stock", Service: "inventory",
+			Attributes: map[string]any{
+				"http.method":      "GET",
+				"http.target":      "/stock?sku=1",
+				"http.status_code": int64(200),
+			},
+		},
+		{
+			Name: "Charge", Service: "payments",
+			Attributes: map[string]any{
+				"rpc.system":           "grpc",
+				"rpc.service":          "payments.v1.Payments",
+				"rpc.method":           "Charge",
+				"rpc.grpc.status_code": float64(0),
+			},
+		},
+		{
+			Name: "orders publish", Service: "notifier",
+			Attributes: map[string]any{
+				"messaging.system":           "kafka",
+				"messaging.operation":        "publish",
+				"messaging.destination.name": "orders",
+			},
+		},
+	}
+
+	ops := spec.ServiceOperationsFromSpans(spans)
+	evaluated := 0
+	for _, sp := range spans {
+		for _, op := range ops[sp.Service] {
+			results, ok := EvaluateConditions(spec.FlowStep{}, op, sp, map[string]any{})
+			for _, r := range results {
+				if r.Status != "PASS" {
+					t.Errorf("%s %s condition %s (%s): %s %s", sp.Service, op.OperationId, r.Name, r.Expr, r.Status, r.Message)
+				}
+			}
+			if !ok {
+				t.Errorf("%s %s: conditions did not pass", sp.Service, op.OperationId)
+			}
+			evaluated += len(results)
+		}
+	}
+	if evaluated < 14 {
+		t.Errorf("expected conditions for every identity/status attribute, evaluated %d", evaluated)
+	}
+}
+
+func TestAttributeNamespaces(t *testing.T) {
+	ns := attributeNamespaces(map[string]any{
+		"http.method":                "GET",
+		"messaging.destination.name": "orders",
+		"db.system":                  "postgresql",
+	})
+	if got := ns["http"].(map[string]any)["method"]; got != "GET" {
+		t.Errorf("http.method = %v", got)
+	}
+	dest := ns["messaging"].(map[string]any)["destination"].(map[string]any)
+	if dest["name"] != "orders" {
+		t.Errorf("messaging.destination.name = %v", dest["name"])
+	}
+	if ns["attrs"].(map[string]any)["db.system"] != "postgresql" {
+		t.Errorf("attrs should expose raw attribute keys: %v", ns["attrs"])
+	}
+	if len(ns["rpc"].(map[string]any)) != 0 {
+		t.Errorf("rpc namespace should be empty: %v", ns["rpc"])
+	}
+}
+
+func TestLintConditions_ReportsInvalidConditions(t *testing.T) {
+	opIndex := map[string]map[string]spec.ServiceOperation{
+		"orders": {"create": {
+			OperationId: "create",
+			Preconditions: map[string]spec.Condition{
+				"ok":      {Expr: "http.method == 'POST'"},
+				"syntax":  {Expr: "response.status =="},
+				"unknown": {Expr: "reqest.body.id != ''"},
+			},
+			Postconditions: map[string]spec.Condition{
+				"not_bool": {Expr: "1 + 2"},
+				"regex":    {Expr: "attrs['x'] =~ /Bearer .+/", Severity: spec.SeverityWarn},
+			},
+		}},
+	}
+	issues := lintConditions(opIndex)
+	if len(issues) != 3 {
+		t.Fatalf("expected 3 issues, got %+v", issues)
+	}
+	for i, want := range []string{
+		`service=orders operation=create precondition "syntax" is invalid`,
+		`service=orders operation=create precondition "unknown" is invalid: ERROR: <input>:1:1: undeclared reference to 'reqest'`,
+		`service=orders operation=create postcondition "not_bool" is invalid: result type int is not bool`,
+	} {
+		if issues[i].Level != "ERROR" || !strings.HasPrefix(issues[i].Msg, want) {
+			t.Errorf("issue %d = %+v, want prefix %q", i, issues[i], want)
+		}
+	}
+}
+
+func TestCompileCached_ReusesPrograms(t *testing.T) {
+	expr := "span.name == 'cached'"
+	first := compileCached(expr)
+	if first.err != nil {
+		t.Fatalf("compile failed: %v", first.err)
+	}
+	if compileCached(expr) != first {
+		t.Error("expected the compiled program to be cached")
+	}
+	ok, _, err := evalCELBool(expr, map[string]any{"span": map[string]any{"name": "cached"}})
+	if err != nil || !ok {
+		t.Errorf("evaluation with cached program = %v, %v", ok, err)
+	}
+}
+
+func TestCompileCached_Bounded(t *testing.T) {
+	for i := 0; i < celCacheSize+10; i++ {
+		compileCached(fmt.Sprintf("span.name == 'edit-%d'", i))
+	}
+	if n := celPrograms.Len(); n > celCacheSize {
+		t.Errorf("cache holds %d programs, want at most %d", n, celCacheSize)
+	}
+
+	c := newLRUCache[int](2)
+	c.Add("a", 1)
+	c.Add("b", 2)
+	c.Get("a")
+	c.Add("c", 3)
+	if _, ok := c.Get("b"); ok {
+		t.Error("expected the least recently used entry to be evicted")
+	}
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("recently used entry = %v, %v", v, ok)
+	}
+}
+
+func TestCELLibrary(t *testing.T) {
+	sp := trace.Span{
+		Name: "POST /orders", Service: "orders", StartNanos: 1_000_000, EndNanos: 251_000_000,
+		Attributes: map[string]any{
+			"http.route":                       "/orders",
+			"http.request.header.content-type": []any{"application/json"},
+			"response.body":                    `{"items":[{"sku":"A-1"},{"sku":"B-2"}],"id":"6f1c2a54-9a37-4d3e-8f33-2e4c9fcb7d10"}`,
+			"request.body.email":               "ada@example.com",
+			"request.body.created":             "2025-03-01T10:00:00Z",
+			"service.version":                  "v1.4.2-rc.1",
+		},
+	}
+	step := spec.FlowStep{Input: map[string]any{"headers": map[string]any{"X-Tenant": "acme"}}}
+	env, _ := buildEvalEnvForStep(step, sp, map[string]any{})
+
+	for expr, want := range map[string]bool{
+		`jsonpath(response.body, '$.items[0].sku') == 'A-1'`:     true,
+		`jsonpath(response.body, "$['items'][-1].sku") == 'B-2'`: true,
+		`jsonpath(response.body, '$.items[5].sku') == null`:      true,
+		`isUUID(jsonpath(response.body, '$.id'))`:                true,
+		`span.durationMs == 250.0`:                               true,
+		`span.durationMs < 500`:                                  true,
+		`duration(span) < duration('300ms')`:                     true,
+		`duration(span) > duration('1s')`:                        false,
+		`header(span, 'Content-Type') == 'application/json'`:     true,
+		`header(declared.headers, 'x-tenant') == 'acme'`:         true,
+		`header(span, 'Authorization') == null`:                  true,
+		`isEmail(attrs['request.body.email'])`:                   true,
+		`isEmail('not-an-email')`:                                false,
+		`isISO8601(attrs['request.body.created'])`:               true,
+		`isISO8601('2025-03-01')`:                                true,
+		`isISO8601('03/01/2025')`:                                false,
+		`semver(attrs['service.version'], '>=1.4.0')`:            true,
+		`semver(attrs['service.version'], '>=1.4.2')`:            false,
+		`semver('1.10.0', '> 1.9')`:                              true,
+		`semver('1.0.0-alpha.2', '<1.0.0-alpha.10')`:             true,
+		`hasAttr('http.route') && !hasAttr('http.target')`:       true,
+		`duration('1s') == duration('1000ms')`:                   true,
+		`attrs['request.body.created'] =~ /^\d{4}-\d{2}-\d{2}T/`: true,
+		`http.route =~ /^\/orders$/`:                             true,
+		`http.route =~ /^\/orders\/\d+$/`:                        false,
+	} {
+		got, phase, err := evalCELBool(expr, env)
+		if err != nil {
+			t.Errorf("%s: %s error: %v", expr, phase, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s = %v, want %v", expr, got, want)
+		}
+	}
+
+	if _, _, err := evalCELBool(`jsonpath(response.body, 'items') == null`, env); err == nil {
+		t.Error("expected an error for a path without $")
+	}
+	if _, _, err := evalCELBool(`semver(attrs['request.body.email'], '>=1.0.0')`, env); err == nil {
+		t.Error("expected an error for an invalid version")
+	}
+}
+
+func TestNormalizeExpr_EscapedSlashes(t *testing.T) {
+	got := normalizeExpr(`http.target =~ /^\/api\/v\d+\// && x == 1`)
+	want := `http.target.matches("^/api/v\\d+/") && x == 1`
+	if got != want {
+		t.Errorf("normalizeExpr = %s, want %s", got, want)
+	}
+}
+
+func TestEvaluateConditions_SeverityAndOnMissing(t *testing.T) {
+	op := spec.ServiceOperation{
+		OperationId: "create",
+		Postconditions: map[string]spec.Condition{
+			"created":      {Expr: "response.status == 201"},
+			"fast":         {Expr: "span.durationMs < 100", Severity: spec.SeverityWarn},
+			"hint":         {Expr: "attrs['missing'] == 1", Severity: spec.SeverityInfo, OnMissing: spec.OnMissingFail},
+			"skipMissing":  {Expr: "attrs['missing'] == 1"},
+			"requireTrace": {Expr: "attrs['missing'] == 1", OnMissing: spec.OnMissingFail},
+		},
+	}
+	sp := trace.Span{Name: "create", Service: "orders", StartNanos: 0, EndNanos: 250_000_000,
+		Attributes: map[string]any{"http.status_code": 201}}
+
+	results, ok := EvaluateConditions(spec.FlowStep{}, op, sp, map[string]any{})
+	got := map[string]ConditionResult{}
+	for _, r := range results {
+		got[r.Name] = r
+	}
+	for name, want := range map[string][2]string{
+		"created":      {"PASS", "error"},
+		"fast":         {"WARN", "warn"},
+		"hint":         {"WARN", "info"},
+		"skipMissing":  {"SKIP", "error"},
+		"requireTrace": {"FAIL", "error"},
+	} {
+		if r := got[name]; r.Status != want[0] || r.Severity != want[1] {
+			t.Errorf("%s = %s/%s (%s), want %s/%s", name, r.Status, r.Severity, r.Message, want[0], want[1])
+		}
+	}
+	if ok {
+		t.Error("onMissing: fail on an error condition should fail the step")
+	}
+
+	delete(op.Postconditions, "requireTrace")
+	if _, ok := EvaluateConditions(spec.FlowStep{}, op, sp, map[string]any{}); !ok {
+		t.Error("warn/info conditions must not fail the step")
+	}
+}
+
+func TestRequestVar_FromSpanAttributes(t *testing.T) {
+	sp := trace.Span{Name: "GET /orders/{id}", Service: "orders", Attributes: map[string]any{
+		"http.method":                      "GET",
+		"http.route":                       "/orders/{id}",
+		"http.target":                      "/orders/42?expand=items&expand=customer&dryRun=true",
+		"http.request.header.x-tenant":     []any{"acme"},
+		"http.request.header.accept":       []any{"text/html", "application/json"},
+		"request.body.customer.tier":       "gold",
+		"request.body":                     `{"quantity": 2}`,
+		"http.response.header.retry-after": "5",
+	}}
+	step := spec.FlowStep{Input: map[string]any{"orderId": "${orderId}"}}
+	env, _ := buildEvalEnvForStep(step, sp, map[string]any{})
+
+	for _, expr := range []string{
+		`request.method == 'GET'`,
+		`request.path == '/orders/42'`,
+		`request.params.id == '42'`,
+		`request.query.expand == 'items' && request.query.dryRun == 'true'`,
+		`request.headers['x-tenant'] == 'acme'`,
+		`request.headers.accept == 'text/html, application/json'`,
+		`!('retry-after' in request.headers)`,
+		`request.body.customer.tier == 'gold' && request.body.quantity == 2.0`,
+		`declared.orderId == '${orderId}'`,
+	} {
+		if ok, phase, err := evalCELBool(expr, env); err != nil || !ok {
+			t.Errorf("%s = %v (%s %v)", expr, ok, phase, err)
+		}
+	}
+
+	// OTel 新语义约定：url.path / url.query 优先
+	req := requestVar(map[string]any{"url.path": "/a", "url.query": "q=1", "url.full": "https://x/b?q=2"})
+	if req["path"] != "/a" || req["query"].(map[string]any)["q"] != "1" {
+		t.Errorf("url.path/url.query should take precedence: %v", req)
+	}
+	if params := routeParams("/orders/:id/items", "/orders/7/lines"); len(params) != 0 {
+		t.Errorf("mismatched literal segment should yield no params: %v", params)
+	}
+}
@@ -0,0 +1,382 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+)
+
+// celLibrary 注册 ChoreoAtlas 条件函数库：
+// - jsonpath(obj, '$.items[0].sku'): 按 JSONPath 取值，路径不存在时为 null；obj 可为 JSON 字符串
+// - duration(span): span 的耗时（google.protobuf.Duration）；另见 span.durationMs
+// - header(obj, 'Content-Type'): 大小写不敏感的 header 查找；obj 为 header map 或 span
+// - isUUID(s) / isISO8601(s) / isEmail(s): 常见取值形状
+// - semver(version, '>=1.4.0'): 语义化版本比较（>=, >, <=, <, ==, !=）
+// - hasAttr('http.route'): 属性是否存在，等价于 'http.route' in attrs
+func celLibrary() []cel.EnvOption {
+	return []cel.EnvOption{
+		cel.Function("jsonpath",
+			cel.Overload("jsonpath_dyn_string", []*cel.Type{cel.DynType, cel.StringType}, cel.DynType,
+				cel.BinaryBinding(jsonpathLookup))),
+		cel.Function("duration",
+			cel.Overload("duration_span", []*cel.Type{cel.MapType(cel.StringType, cel.DynType)}, cel.DurationType,
+				cel.UnaryBinding(spanDuration))),
+		cel.Function("header",
+			cel.Overload("header_dyn_string", []*cel.Type{cel.DynType, cel.StringType}, cel.DynType,
+				cel.BinaryBinding(headerLookup))),
+		cel.Function("isUUID",
+			cel.Overload("isUUID_string", []*cel.Type{cel.StringType}, cel.BoolType,
+				cel.UnaryBinding(stringPredicate(isUUID)))),
+		cel.Function("isISO8601",
+			cel.Overload("isISO8601_string", []*cel.Type{cel.StringType}, cel.BoolType,
+				cel.UnaryBinding(stringPredicate(isISO8601)))),
+		cel.Function("isEmail",
+			cel.Overload("isEmail_string", []*cel.Type{cel.StringType}, cel.BoolType,
+				cel.UnaryBinding(stringPredicate(isEmail)))),
+		cel.Function("semver",
+			cel.Overload("semver_string_string", []*cel.Type{cel.StringType, cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(semverMatch))),
+		cel.Macros(cel.GlobalMacro("hasAttr", 1, expandHasAttr)),
+	}
+}
+
+// expandHasAttr 将 hasAttr(k) 展开为 k in attrs
+func expandHasAttr(eh cel.MacroExprFactory, _ ast.Expr, args []ast.Expr) (ast.Expr, *cel.Error) {
+	return eh.NewCall(operators.In, args[0], eh.NewIdent("attrs")), nil
+}
+
+// jsonpathLookup 支持 $、.name、['name'] 与 [n]（负数从末尾计）
+func jsonpathLookup(obj, path ref.Val) ref.Val {
+	p, ok := path.(types.String)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(path)
+	}
+	segs, err := parseJSONPath(string(p))
+	if err != nil {
+		return types.NewErr("jsonpath %q: %v", string(p), err)
+	}
+	cur := obj
+	for _, seg := range segs {
+		cur = decodeJSONString(cur)
+		if seg.field {
+			m, ok := cur.(traits.Mapper)
+			if !ok {
+				return types.NullValue
+			}
+			v, found := m.Find(types.String(seg.name))
+			if !found {
+				return types.NullValue
+			}
+			cur = v
+			continue
+		}
+		l, ok := cur.(traits.Lister)
+		if !ok {
+			return types.NullValue
+		}
+		n, _ := l.Size().(types.Int)
+		i := seg.index
+		if i < 0 {
+			i += int64(n)
+		}
+		if i < 0 || i >= int64(n) {
+			return types.NullValue
+		}
+		cur = l.Get(types.Int(i))
+	}
+	return cur
+}
+
+type jsonPathSegment struct {
+	field bool
+	name  string
+	index int64
+}
+
+func parseJSONPath(path string) ([]jsonPathSegment, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("must start with $")
+	}
+	var segs []jsonPathSegment
+	rest := path[1:]
+	for rest != "" {
+		switch {
+		case rest[0] == '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			name := rest[1 : end+1]
+			if name == "" {
+				return nil, fmt.Errorf("empty field name")
+			}
+			segs = append(segs, jsonPathSegment{field: true, name: name})
+			rest = rest[end+1:]
+		case rest[0] == '[':
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated [")
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				segs = append(segs, jsonPathSegment{field: true, name: inner[1 : len(inner)-1]})
+			} else if i, err := strconv.ParseInt(inner, 10, 64); err == nil {
+				segs = append(segs, jsonPathSegment{index: i})
+			} else {
+				return nil, fmt.Errorf("unsupported selector [%s]", inner)
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("unexpected %q", rest)
+		}
+	}
+	return segs, nil
+}
+
+// decodeJSONString 将 JSON 对象/数组字符串（如 response.body 属性）解析为 CEL 值
+func decodeJSONString(v ref.Val) ref.Val {
+	s, ok := v.(types.String)
+	if !ok {
+		return v
+	}
+	trimmed := strings.TrimSpace(string(s))
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return v
+	}
+	var decoded any
+	if err := json.Unmarshal([]byte(trimmed), &decoded); err != nil {
+		return v
+	}
+	return types.DefaultTypeAdapter.NativeToValue(decoded)
+}
+
+// spanDuration 由 span 变量的 startNanos/endNanos 计算耗时
+func spanDuration(sp ref.Val) ref.Val {
+	m, ok := sp.(traits.Mapper)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(sp)
+	}
+	start, okStart := m.Find(types.String("startNanos"))
+	end, okEnd := m.Find(types.String("endNanos"))
+	if !okStart || !okEnd {
+		return types.NewErr("duration: span has no startNanos/endNanos")
+	}
+	s, okS := start.(types.Int)
+	e, okE := end.(types.Int)
+	if !okS || !okE {
+		return types.NewErr("duration: startNanos/endNanos must be int")
+	}
+	return types.Duration{Duration: time.Duration(e - s)}
+}
+
+// headerLookup 大小写不敏感地查找 header：obj 为 span 时查找
+// http.request.header.<name>，其次 http.response.header.<name>；多值 header 取第一个值
+func headerLookup(obj, name ref.Val) ref.Val {
+	n, ok := name.(types.String)
+	if !ok {
+		return types.MaybeNoSuchOverloadErr(name)
+	}
+	m, ok := obj.(traits.Mapper)
+	if !ok {
+		return types.NullValue
+	}
+	want := strings.ToLower(string(n))
+	candidates := []string{want}
+	if attrs, found := m.Find(types.String("attributes")); found {
+		if am, isMap := attrs.(traits.Mapper); isMap {
+			m = am
+			candidates = []string{"http.request.header." + want, "http.response.header." + want}
+		}
+	}
+	for _, key := range candidates {
+		for it := m.Iterator(); it.HasNext() == types.True; {
+			k := it.Next()
+			if ks, isStr := k.(types.String); isStr && strings.ToLower(string(ks)) == key {
+				return firstValue(m.Get(k))
+			}
+		}
+	}
+	return types.NullValue
+}
+
+func firstValue(v ref.Val) ref.Val {
+	l, ok := v.(traits.Lister)
+	if !ok {
+		return v
+	}
+	if l.Size() == types.IntZero {
+		return types.NullValue
+	}
+	return l.Get(types.IntZero)
+}
+
+func stringPredicate(pred func(string) bool) func(ref.Val) ref.Val {
+	return func(v ref.Val) ref.Val {
+		s, ok := v.(types.String)
+		if !ok {
+			return types.MaybeNoSuchOverloadErr(v)
+		}
+		return types.Bool(pred(string(s)))
+	}
+}
+
+var (
+	uuidPattern  = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	emailPattern = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s.]+$`)
+)
+
+func isUUID(s string) bool {
+	return uuidPattern.MatchString(s)
+}
+
+func isEmail(s string) bool {
+	return emailPattern.MatchString(s)
+}
+
+// ISO 8601 常见形式：日期、带/不带时区的日期时间
+var iso8601Layouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999Z0700",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+func isISO8601(s string) bool {
+	for _, layout := range iso8601Layouts {
+		if _, err := time.Parse(layout, s); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// semverMatch 判断 version 是否满足约束，如 ">=1.4.0"；无运算符时为相等比较
+func semverMatch(version, constraint ref.Val) ref.Val {
+	v, ok1 := version.(types.String)
+	c, ok2 := constraint.(types.String)
+	if !ok1 || !ok2 {
+		return types.MaybeNoSuchOverloadErr(version)
+	}
+	op, target := "==", strings.TrimSpace(string(c))
+	for _, candidate := range []string{">=", "<=", "==", "!=", ">", "<", "="} {
+		if strings.HasPrefix(target, candidate) {
+			op, target = candidate, strings.TrimSpace(target[len(candidate):])
+			break
+		}
+	}
+	a, err := parseSemver(string(v))
+	if err != nil {
+		return types.NewErr("semver: %v", err)
+	}
+	b, err := parseSemver(target)
+	if err != nil {
+		return types.NewErr("semver: %v", err)
+	}
+	cmp := compareSemver(a, b)
+	switch op {
+	case ">=":
+		return types.Bool(cmp >= 0)
+	case "<=":
+		return types.Bool(cmp <= 0)
+	case ">":
+		return types.Bool(cmp > 0)
+	case "<":
+		return types.Bool(cmp < 0)
+	case "!=":
+		return types.Bool(cmp != 0)
+	}
+	return types.Bool(cmp == 0)
+}
+
+type semverVersion struct {
+	core [3]int64
+	pre  []string
+}
+
+// parseSemver 解析 [v]MAJOR[.MINOR[.PATCH]][-PRERELEASE][+BUILD]，缺省部分按 0 处理
+func parseSemver(s string) (semverVersion, error) {
+	var sv semverVersion
+	raw := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	raw, _, _ = strings.Cut(raw, "+")
+	raw, pre, hasPre := strings.Cut(raw, "-")
+	if hasPre {
+		if pre == "" {
+			return sv, fmt.Errorf("invalid version %q", s)
+		}
+		sv.pre = strings.Split(pre, ".")
+	}
+	parts := strings.Split(raw, ".")
+	if len(parts) > 3 {
+		return sv, fmt.Errorf("invalid version %q", s)
+	}
+	for i, p := range parts {
+		n, err := strconv.ParseInt(p, 10, 64)
+		if err != nil || n < 0 {
+			return sv, fmt.Errorf("invalid version %q", s)
+		}
+		sv.core[i] = n
+	}
+	return sv, nil
+}
+
+// compareSemver 按 semver 2.0 优先级比较：预发布版本低于正式版本
+func compareSemver(a, b semverVersion) int {
+	for i := range a.core {
+		if a.core[i] != b.core[i] {
+			if a.core[i] < b.core[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(a.pre) == 0 && len(b.pre) == 0:
+		return 0
+	case len(a.pre) == 0:
+		return 1
+	case len(b.pre) == 0:
+		return -1
+	}
+	for i := 0; i < len(a.pre) && i < len(b.pre); i++ {
+		x, y := a.pre[i], b.pre[i]
+		if x == y {
+			continue
+		}
+		xn, errX := strconv.ParseInt(x, 10, 64)
+		yn, errY := strconv.ParseInt(y, 10, 64)
+		switch {
+		case errX == nil && errY == nil:
+			if xn < yn {
+				return -1
+			}
+			return 1
+		case errX == nil:
+			return -1
+		case errY == nil:
+			return 1
+		case x < y:
+			return -1
+		default:
+			return 1
+		}
+	}
+	switch {
+	case len(a.pre) < len(b.pre):
+		return -1
+	case len(a.pre) > len(b.pre):
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,142 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package validate
+
+import (
+	"fmt"
+
+	"github.com/choreoatlas2025/cli/internal/spec"
+)
+
+// 稳定的诊断码：CA-LINT-* 为静态检查，CA-VAL-* 为基于 trace 的校验。
+// 诊断码一经发布不再变更含义，新规则追加新码。
+const (
+	CodeEmptyTitle         = "CA-LINT-001"
+	CodeFlowFormat         = "CA-LINT-002"
+	CodeUnknownVariable    = "CA-LINT-003"
+	CodeLegacyFormat       = "CA-LINT-004"
+	CodeStepName           = "CA-LINT-005"
+	CodeInvalidCall        = "CA-LINT-006"
+	CodeUndeclaredService  = "CA-LINT-007"
+	CodeUnknownOperation   = "CA-LINT-008"
+	CodeTelemetryInput     = "CA-LINT-009"
+	CodeStepKind           = "CA-LINT-010"
+	CodeInvalidMatcher     = "CA-LINT-011"
+	CodeInvalidCompensate  = "CA-LINT-012"
+	CodeInvalidAllow       = "CA-LINT-013"
+	CodeInvalidForbid      = "CA-LINT-014"
+	CodeInvalidCondition   = "CA-LINT-015"
+	CodeGraphStructure     = "CA-LINT-016"
+	CodeDeadNode           = "CA-LINT-017"
+	CodeUnusedOutput       = "CA-LINT-018"
+	CodeUnusedBinding      = "CA-LINT-019"
+	CodeUnusedOperation    = "CA-LINT-020"
+	CodeServiceAlias       = "CA-LINT-021"
+	CodeParallelDependency = "CA-LINT-022"
+	CodeMissingVersion     = "CA-LINT-023"
+	CodeStepDestination    = "CA-LINT-024"
+	CodeEmptyMatcher       = "CA-LINT-025"
+	CodeAllowServicePart   = "CA-LINT-026"
+	CodeNestedCompensate   = "CA-LINT-027"
+	CodeStepNotObserved    = "CA-VAL-001"
+	CodeOrderViolation     = "CA-VAL-002"
+	CodeConditionFailed    = "CA-VAL-003"
+	CodeConditionSkipped   = "CA-VAL-004"
+	CodeForbiddenCall      = "CA-VAL-005"
+	CodeMessagingViolation = "CA-VAL-006"
+	CodeRuntimeFailure     = "CA-VAL-007"
+	CodeTraceError         = "CA-VAL-008"
+)
+
+// diagnosticTitles 诊断码的简短说明
+var diagnosticTitles = map[string]string{
+	CodeEmptyTitle:         "empty title",
+	CodeFlowFormat:         "invalid flow format",
+	CodeUnknownVariable:    "unknown variable",
+	CodeLegacyFormat:       "legacy flow format",
+	CodeStepName:           "missing or duplicate step name",
+	CodeInvalidCall:        "invalid call",
+	CodeUndeclaredService:  "undeclared service",
+	CodeUnknownOperation:   "unknown operation",
+	CodeTelemetryInput:     "telemetry keys in input",
+	CodeStepKind:           "invalid step kind",
+	CodeInvalidMatcher:     "invalid span matcher",
+	CodeInvalidCompensate:  "invalid compensation",
+	CodeInvalidAllow:       "invalid allowUnmatched pattern",
+	CodeInvalidForbid:      "invalid forbid rule",
+	CodeInvalidCondition:   "invalid condition",
+	CodeGraphStructure:     "invalid graph structure",
+	CodeDeadNode:           "unreachable node",
+	CodeUnusedOutput:       "unused output",
+	CodeUnusedBinding:      "unused service binding",
+	CodeUnusedOperation:    "unused operation",
+	CodeServiceAlias:       "service alias mismatch",
+	CodeParallelDependency: "dependency between parallel steps",
+	CodeMissingVersion:     "missing version",
+	CodeStepDestination:    "destination inconsistent with kind",
+	CodeEmptyMatcher:       "empty span matcher",
+	CodeAllowServicePart:   "allowUnmatched pattern without service",
+	CodeNestedCompensate:   "compensation with its own compensation",
+	CodeStepNotObserved:    "step not observed",
+	CodeOrderViolation:     "ordering or causality violation",
+	CodeConditionFailed:    "condition failed",
+	CodeConditionSkipped:   "condition skipped",
+	CodeForbiddenCall:      "forbidden call",
+	CodeMessagingViolation: "messaging link violation",
+	CodeRuntimeFailure:     "runtime failure",
+	CodeTraceError:         "trace error",
+}
+
+// DiagnosticTitle 返回诊断码的简短说明，未知码返回空串
+func DiagnosticTitle(code string) string {
+	return diagnosticTitles[code]
+}
+
+// positionRef 将有效位置转为指针，便于 JSON 中省略未知位置
+func positionRef(pos spec.Position) *spec.Position {
+	if !pos.IsValid() {
+		return nil
+	}
+	return &pos
+}
+
+// DiagnosticCodes 返回全部诊断码（排序）
+func DiagnosticCodes() []string {
+	return sortedKeys(diagnosticTitles)
+}
+
+// newIssue 构造带诊断码的静态检查问题
+func newIssue(code, format string, args ...any) LintIssue {
+	rule, _ := lookupLintRule(code)
+	return LintIssue{Level: rule.Level, Msg: fmt.Sprintf(format, args...), Code: code}
+}
+
+// at 设置源码位置
+func (i LintIssue) at(pos spec.Position) LintIssue {
+	i.Pos = pos
+	return i
+}
+
+// about 设置关联的 step / node ID
+func (i LintIssue) about(ref string) LintIssue {
+	i.Ref = ref
+	return i
+}
+
+// withHint 设置修复建议
+func (i LintIssue) withHint(format string, args ...any) LintIssue {
+	i.Hint = fmt.Sprintf(format, args...)
+	return i
+}
+
+// String 格式为 file:line:col: msg (CODE title)
+func (i LintIssue) String() string {
+	s := i.Msg
+	if i.Pos.IsValid() {
+		s = i.Pos.String() + ": " + s
+	}
+	if i.Code != "" {
+		s += fmt.Sprintf(" (%s %s)", i.Code, DiagnosticTitle(i.Code))
+	}
+	return s
+}
@@ -0,0 +1,120 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package validate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/choreoatlas2025/cli/internal/spec"
+	"github.com/choreoatlas2025/cli/internal/trace"
+)
+
+func loadDiagnosticsFlow(t *testing.T, flowYAML string) (string, *spec.FlowSpec) {
+	t.Helper()
+	dir := t.TempDir()
+	svcPath := filepath.Join(dir, "order.servicespec.yaml")
+	if err := os.WriteFile(svcPath, []byte("service: orderService\noperations:\n  - operationId: createOrder\n  - operationId: getOrder\n"), 0o644); err != nil {
+		t.Fatalf("write service spec: %v", err)
+	}
+	flowPath := filepath.Join(dir, "order.flowspec.yaml")
+	if err := os.WriteFile(flowPath, []byte(flowYAML), 0o644); err != nil {
+		t.Fatalf("write flow: %v", err)
+	}
+	fs, err := spec.LoadFlowSpec(flowPath)
+	if err != nil {
+		t.Fatalf("load flow: %v", err)
+	}
+	return flowPath, fs
+}
+
+const diagnosticsFlow = `info:
+  title: order
+services:
+  orderService:
+    spec: ./order.servicespec.yaml
+flow:
+  - step: create
+    call: orderService.createOrder
+    output:
+      orderId: response.body.id
+  - step: fetch
+    call: orderService.getOrder
+    input:
+      id: ${orderRef}
+`
+
+func TestLintIssues_CarryCodeAndPosition(t *testing.T) {
+	flowPath, fs := loadDiagnosticsFlow(t, diagnosticsFlow)
+	_, opIndex, err := fs.BuildOperationIndex(flowPath)
+	if err != nil {
+		t.Fatalf("build op index: %v", err)
+	}
+	issues, err := LintFlow(flowPath, fs, opIndex, nil)
+	if err != nil {
+		t.Fatalf("lint: %v", err)
+	}
+	var found *LintIssue
+	for i := range issues {
+		if issues[i].Code == CodeUnknownVariable {
+			found = &issues[i]
+		}
+	}
+	if found == nil {
+		t.Fatalf("expected %s issue, got %+v", CodeUnknownVariable, issues)
+	}
+	if found.Ref != "fetch" || found.Pos.File != flowPath || found.Pos.Line != 13 {
+		t.Errorf("unexpected ref/position: %+v", found)
+	}
+	if found.Hint == "" {
+		t.Errorf("expected a fix hint, got %+v", found)
+	}
+	want := flowPath + ":13:"
+	if s := found.String(); !strings.HasPrefix(s, want) || !strings.Contains(s, "(CA-LINT-003 unknown variable)") {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+func TestValidationResults_CarryCodeAndPosition(t *testing.T) {
+	_, fs := loadDiagnosticsFlow(t, diagnosticsFlow)
+	tr := &trace.Trace{Spans: []trace.Span{
+		{Service: "orderService", Name: "createOrder", StartNanos: 100, EndNanos: 200,
+			Attributes: map[string]any{"otlp.span_id": "a"}},
+	}}
+
+	results, ok := ValidateAgainstTrace(fs, nil, tr)
+	if ok {
+		t.Fatal("expected missing step to fail validation")
+	}
+	byStep := map[string]StepResult{}
+	for _, r := range results {
+		byStep[r.Step] = r
+	}
+	if r := byStep["create"]; r.Status != "PASS" || r.Code != "" || r.Position == nil || r.Position.Line != 7 {
+		t.Errorf("unexpected result for create: %+v", r)
+	}
+	r := byStep["fetch"]
+	if r.Status != "FAIL" || r.Code != CodeStepNotObserved {
+		t.Fatalf("expected %s for fetch, got %+v", CodeStepNotObserved, r)
+	}
+	if r.Position == nil || r.Position.Line != 11 || r.Position.Column != 5 {
+		t.Errorf("expected fetch position 11:5, got %+v", r.Position)
+	}
+}
+
+func TestEvaluateCondition_CodeAndPosition(t *testing.T) {
+	cond := spec.Condition{Expr: "response.status == 200", Pos: spec.Position{File: "svc.yaml", Line: 9, Column: 17}}
+	cr := evaluateCondition("post", "ok", cond, map[string]any{"response": map[string]any{"status": 500}})
+	if cr.Status != "FAIL" || cr.Code != CodeConditionFailed {
+		t.Fatalf("expected failed condition with code, got %+v", cr)
+	}
+	if cr.Position == nil || cr.Position.String() != "svc.yaml:9:17" {
+		t.Errorf("unexpected position %+v", cr.Position)
+	}
+	cr = evaluateCondition("post", "bad", spec.Condition{Expr: "response.("}, map[string]any{})
+	if cr.Status != "SKIP" || cr.Code != CodeConditionSkipped || cr.Position != nil {
+		t.Errorf("expected skipped condition without position, got %+v", cr)
+	}
+}
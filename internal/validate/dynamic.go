@@ -16,11 +16,17 @@ var EnableSemantic = true
 
 // StepResult 表示单个步骤的验证结果
 type StepResult struct {
-	Step       string            `json:"step"`
-	Call       string            `json:"call"`
-	Status     string            `json:"status"` // PASS / FAIL
-	Message    string            `json:"message,omitempty"`
-	Conditions []ConditionResult `json:"conditions,omitempty"`
+	Step        string            `json:"step"`
+	Call        string            `json:"call"`
+	Status      string            `json:"status"` // PASS / FAIL
+	Message     string            `json:"message,omitempty"`
+	SpanID      string            `json:"spanId,omitempty"`      // Span matched to this step, if any
+	MatchReason string            `json:"matchReason,omitempty"` // Why the span was matched (span name, attributes, ...)
+	Conditions  []ConditionResult `json:"conditions,omitempty"`
+	Candidates  []Candidate       `json:"candidates,omitempty"` // Nearest spans for failed steps (explain mode)
+	Code        string            `json:"code,omitempty"`       // 失败时的诊断码（CA-VAL-*）
+	Position    *spec.Position    `json:"position,omitempty"`   // 步骤在 FlowSpec 中的位置
+	Violations  []EdgeViolation   `json:"violations,omitempty"` // DAG 约束违规明细（DAG Validation 结果）
 }
 
 // CausalityMode represents the causality checking mode
@@ -40,6 +46,78 @@ var GlobalCausalityToleranceMs int64 = 50
 
 // ValidateAgainstTrace 根据追踪数据验证流程执行（支持因果和并发校验）
 func ValidateAgainstTrace(fs *spec.FlowSpec, opIndex map[string]map[string]spec.ServiceOperation, tr *trace.Trace) ([]StepResult, bool) {
+	var results []StepResult
+	var ok bool
+	// Saga flows validate the forward path first, then the compensation path
+	if len(fs.Compensations()) > 0 {
+		results, ok = validateSaga(fs, opIndex, tr)
+	} else {
+		results, ok = validateForward(fs, opIndex, tr)
+	}
+
+	// Optional steps that were not observed are not applicable rather than missing
+	if markOptionalNotObserved(fs, results) && !ok {
+		ok = !hasFailedResults(results)
+	}
+
+	// Async consumers must be causally linked to their producers
+	if GlobalCausalityMode != CausalityOff && hasMessagingSteps(fs) {
+		if !checkMessageCausality(fs, results, tr) {
+			ok = false
+		}
+	}
+
+	annotateStepPositions(fs, results)
+
+	// Negative assertions over the call graph
+	if len(fs.Forbid) > 0 {
+		forbidResults, okForbid := checkForbidRules(fs, tr)
+		results = append(results, forbidResults...)
+		if !okForbid {
+			ok = false
+		}
+	}
+	return results, ok
+}
+
+// markOptionalNotObserved turns failures of optional steps without a matched
+// span into N/A results. Reports whether any result was changed.
+func markOptionalNotObserved(fs *spec.FlowSpec, results []StepResult) bool {
+	changed := false
+	for i := range results {
+		r := &results[i]
+		if r.Status != "FAIL" || r.SpanID != "" {
+			continue
+		}
+		if st, found := fs.StepByName(r.Step); found && st.Optional {
+			r.Status, r.Code = StatusNotApplicable, ""
+			r.Message = "optional step not observed in trace"
+			changed = true
+		}
+	}
+	return changed
+}
+
+// annotateStepPositions 将结果关联到步骤 / 节点在 FlowSpec 中的位置
+func annotateStepPositions(fs *spec.FlowSpec, results []StepResult) {
+	for i := range results {
+		if results[i].Position == nil {
+			results[i].Position = positionRef(fs.Source.Step(results[i].Step))
+		}
+	}
+}
+
+func hasFailedResults(results []StepResult) bool {
+	for _, r := range results {
+		if r.Status == "FAIL" {
+			return true
+		}
+	}
+	return false
+}
+
+// validateForward routes to the format specific validator
+func validateForward(fs *spec.FlowSpec, opIndex map[string]map[string]spec.ServiceOperation, tr *trace.Trace) ([]StepResult, bool) {
 	// Route to appropriate validation based on format
 	if fs.IsGraphMode() {
 		return validateGraphAgainstTrace(fs, opIndex, tr)
@@ -55,8 +133,9 @@ func ValidateAgainstTrace(fs *spec.FlowSpec, opIndex map[string]map[string]spec.
 		}
 	}
 
-	// 如果有并发步骤或OTLP数据（包含父子关系），使用因果校验
-	if hasParallelSteps || hasOTLPMetadata(tr) {
+	// 如果有并发步骤或OTLP数据（包含父子关系），使用因果校验；
+	// strict 模式需要调用树，始终走因果校验
+	if hasParallelSteps || hasOTLPMetadata(tr) || GlobalCausalityMode == CausalityStrict {
 		return validateWithCausality(fs, opIndex, tr)
 	}
 
@@ -84,6 +163,7 @@ func validateWithCausality(fs *spec.FlowSpec, opIndex map[string]map[string]spec
 			Call:    "internal",
 			Status:  "FAIL",
 			Message: fmt.Sprintf("Failed to build call graph: %v", err),
+			Code:    CodeTraceError,
 		}}, false
 	}
 
@@ -92,24 +172,13 @@ func validateWithCausality(fs *spec.FlowSpec, opIndex map[string]map[string]spec
 	violations := graph.ValidateEdgeConstraints(toleranceNanos)
 
 	// 执行因果校验
-	results, allPassed := CheckCausality(fs, graph)
+	results, allPassed := CheckCausality(fs, graph, opIndex)
 
 	// 如果有违规，添加到结果中
 	if len(violations) > 0 {
 		allPassed = false
 		// 在结果前插入DAG验证结果
-		dagResult := StepResult{
-			Step:    "DAG Validation",
-			Call:    "internal",
-			Status:  "FAIL",
-			Message: fmt.Sprintf("Detected %d DAG constraint violations", len(violations)),
-		}
-		results = append([]StepResult{dagResult}, results...)
-
-		// 输出详细的违规信息
-		for _, v := range violations {
-			fmt.Printf("[DAG Violation] %s: %s\n", v.Type, v.Message)
-		}
+		results = append([]StepResult{dagViolationResult(violations)}, results...)
 	}
 
 	// 应用语义校验
@@ -120,15 +189,20 @@ func validateWithCausality(fs *spec.FlowSpec, opIndex map[string]map[string]spec
 				if err == nil {
 					// 找到对应的span
 					for _, span := range tr.Spans {
-						if normalize(span.Service) == normalize(svc) && normalize(span.Name) == normalize(op) {
+						if getSpanID(span) == results[i].SpanID {
 							if ops, ok := opIndex[svc]; ok {
 								if opSpec, ok := ops[op]; ok {
-									conds, okSem := EvaluateConditions(
-										spec.FlowStep{Step: results[i].Step, Call: results[i].Call},
-										opSpec, span, map[string]any{})
+									st, found := fs.StepByName(results[i].Step)
+									if !found {
+										st = spec.FlowStep{Step: results[i].Step, Call: results[i].Call}
+									}
+									conds, okSem := EvaluateConditions(st, opSpec, span, map[string]any{})
 									results[i].Conditions = conds
 									if !okSem {
 										results[i].Status = "FAIL"
+										if results[i].Code == "" {
+											results[i].Code = CodeConditionFailed
+										}
 										if results[i].Message != "" {
 											results[i].Message += " | "
 										}
@@ -148,7 +222,21 @@ func validateWithCausality(fs *spec.FlowSpec, opIndex map[string]map[string]spec
 	return results, allPassed
 }
 
+// dagViolationResult 汇总 DAG 约束违规；明细随结果返回，由调用方输出
+func dagViolationResult(violations []EdgeViolation) StepResult {
+	return StepResult{
+		Step:       "DAG Validation",
+		Call:       "internal",
+		Status:     "FAIL",
+		Message:    fmt.Sprintf("Detected %d DAG constraint violations", len(violations)),
+		Code:       CodeOrderViolation,
+		Violations: violations,
+	}
+}
+
 // validateWithTimeSequence 使用原来的时序校验（向后兼容）
+// 步骤与 span 的对应关系由 solveAssignment 求解：保持时间顺序，重复调用同一操作时
+// 选择整体代价最小的组合，而不是贪心地取第一个匹配的 span
 func validateWithTimeSequence(fs *spec.FlowSpec, opIndex map[string]map[string]spec.ServiceOperation, tr *trace.Trace) ([]StepResult, bool) {
 	var results []StepResult
 	okAll := true
@@ -160,77 +248,81 @@ func validateWithTimeSequence(fs *spec.FlowSpec, opIndex map[string]map[string]s
 		return sortedSpans[i].StartNanos < sortedSpans[j].StartNanos
 	})
 
-	spanIndex := 0 // 当前匹配的 span 索引
-
-	for _, st := range fs.Flow {
+	// 为每个顺序步骤收集候选 span
+	var slots []assignSlot
+	slotOf := make(map[int]int) // fs.Flow 下标 -> slot
+	for i, st := range fs.Flow {
 		// 跳过并发步骤组（由因果校验处理）
 		if len(st.Parallel) > 0 {
 			continue
 		}
+		if _, _, err := splitCall(st.Call); err != nil {
+			continue
+		}
+		slotOf[i] = len(slots)
+		slots = append(slots, candidateSlot(st, sortedSpans, opIndex))
+	}
+
+	// 时序约束：每个步骤的 span 必须晚于上一个已匹配步骤的 span
+	sol := solveAssignment(slots, func(assigned []int, slot, cand int) (int, bool) {
+		for k := slot - 1; k >= 0; k-- {
+			if assigned[k] >= 0 {
+				return 0, cand > assigned[k]
+			}
+		}
+		return 0, true
+	})
+
+	spanIndex := 0 // 上一个匹配 span 之后的位置
+
+	for i, st := range fs.Flow {
+		if len(st.Parallel) > 0 {
+			continue
+		}
 
 		svc, op, err := splitCall(st.Call)
 		if err != nil {
-			results = append(results, StepResult{Step: st.Step, Call: st.Call, Status: "FAIL", Message: err.Error()})
+			results = append(results, StepResult{Step: st.Step, Call: st.Call, Status: "FAIL", Message: err.Error(), Code: CodeTraceError})
 			okAll = false
 			continue
 		}
 
-		// 在剩余的 spans 中查找匹配项（保持顺序）
-		found := false
-		matchedIndex := -1
+		slot := slotOf[i]
+		matchedIndex := sol.spans[slot]
+		if matchedIndex < 0 {
+			results = append(results, StepResult{Step: st.Step, Call: st.Call, Status: "FAIL", Message: "no matching span found in trace", Code: CodeStepNotObserved})
+			okAll = false
+			continue
+		}
 
-		for j := spanIndex; j < len(sortedSpans); j++ {
-			sp := sortedSpans[j]
-			if normalize(sp.Service) == normalize(svc) && normalize(sp.Name) == normalize(op) {
-				found = true
-				matchedIndex = j
-				break
-			}
+		note := ""
+		if matchedIndex > spanIndex {
+			note = fmt.Sprintf("matched span #%d by time sequence (intermediate spans exist)", matchedIndex+1)
 		}
 
-		if found {
-			if matchedIndex >= spanIndex {
-				note := ""
-				if matchedIndex > spanIndex {
-					note = fmt.Sprintf("matched span #%d by time sequence (intermediate spans exist)", matchedIndex+1)
-				}
-				
-				// 默认 PASS（顺序已通过）
-				sr := StepResult{Step: st.Step, Call: st.Call, Status: "PASS", Message: note}
-
-				// 语义校验（如果有对应的 operation 规约）
-				if EnableSemantic {
-					if ops, ok := opIndex[svc]; ok {
-						if opSpec, ok := ops[op]; ok {
-							conds, okSem := EvaluateConditions(st, opSpec, sortedSpans[matchedIndex], /*vars*/ map[string]any{})
-							sr.Conditions = conds
-							if !okSem {
-								sr.Status = "FAIL"
-								if sr.Message != "" {
-									sr.Message += " | "
-								}
-								sr.Message += "semantic validation failed"
-							}
+		// 默认 PASS（顺序已通过）
+		sr := StepResult{Step: st.Step, Call: st.Call, Status: "PASS", Message: note, SpanID: getSpanID(sortedSpans[matchedIndex]), MatchReason: sol.reason(slots, slot)}
+
+		// 语义校验（如果有对应的 operation 规约）
+		if EnableSemantic {
+			if ops, ok := opIndex[svc]; ok {
+				if opSpec, ok := ops[op]; ok {
+					conds, okSem := EvaluateConditions(st, opSpec, sortedSpans[matchedIndex], /*vars*/ map[string]any{})
+					sr.Conditions = conds
+					if !okSem {
+						sr.Status = "FAIL"
+						sr.Code = CodeConditionFailed
+						if sr.Message != "" {
+							sr.Message += " | "
 						}
+						sr.Message += "semantic validation failed"
 					}
 				}
-				
-				results = append(results, sr)
-				spanIndex = matchedIndex + 1
-			} else {
-				// span 出现在上一步之前（时序倒退）
-				results = append(results, StepResult{
-					Step:    st.Step,
-					Call:    st.Call,
-					Status:  "FAIL",
-					Message: "temporal regression: matched span occurred before previous step",
-				})
-				okAll = false
 			}
-		} else {
-			results = append(results, StepResult{Step: st.Step, Call: st.Call, Status: "FAIL", Message: "no matching span found in trace"})
-			okAll = false
 		}
+
+		results = append(results, sr)
+		spanIndex = matchedIndex + 1
 	}
 	return results, okAll
 }
@@ -248,6 +340,7 @@ func validateGraphAgainstTrace(fs *spec.FlowSpec, opIndex map[string]map[string]
 			Call:    "internal",
 			Status:  "FAIL",
 			Message: fmt.Sprintf("Failed to build call graph: %v", err),
+			Code:    CodeTraceError,
 		}}, false
 	}
 
@@ -257,27 +350,9 @@ func validateGraphAgainstTrace(fs *spec.FlowSpec, opIndex map[string]map[string]
 	if len(violations) > 0 {
 		okAll = false
 		// Add violations as a result
-		dagResult := StepResult{
-			Step:    "DAG Validation",
-			Call:    "internal",
-			Status:  "FAIL",
-			Message: fmt.Sprintf("Detected %d DAG constraint violations", len(violations)),
-		}
-		results = append(results, dagResult)
-
-		// Output detailed violations
-		for _, v := range violations {
-			fmt.Printf("[DAG Violation] %s: %s\n", v.Type, v.Message)
-		}
+		results = append(results, dagViolationResult(violations))
 	}
 
-	// Build span matching index by service.operation
-	spanIndex := make(map[string][]trace.Span)
-	for _, span := range tr.Spans {
-		key := fmt.Sprintf("%s.%s", span.Service, span.Name)
-		spanIndex[key] = append(spanIndex[key], span)
-	}
-	
 	// Validate each node in topological order
 	topOrder, err := topologicalSort(fs.Graph)
 	if err != nil {
@@ -288,15 +363,55 @@ func validateGraphAgainstTrace(fs *spec.FlowSpec, opIndex map[string]map[string]
 				Call: node.Call,
 				Status: "FAIL",
 				Message: fmt.Sprintf("DAG topological sort failed: %v", err),
+				Code: CodeTraceError,
 			})
 		}
 		return results, false
 	}
 	
-	// Track matched spans to avoid double-matching
-	usedSpans := make(map[string]bool) // span service:name:startNanos
+	// Create a FlowStep view of each node for matching and condition evaluation
+	nodeSteps := make([]spec.FlowStep, len(topOrder))
+	var slots []assignSlot
+	for i, nodeID := range topOrder {
+		if node := findNodeByID(fs.Graph, nodeID); node != nil {
+			nodeSteps[i] = spec.FlowStep{
+				Step: node.ID,
+				Call: node.Call,
+				Input: node.Input,
+				Output: node.Output,
+				Meta: node.Meta,
+				Kind: node.Kind,
+				Destination: node.Destination,
+				Match: node.Match,
+			}
+			slots = append(slots, candidateSlot(nodeSteps[i], tr.Spans, opIndex))
+		} else {
+			slots = append(slots, assignSlot{})
+		}
+	}
+
+	// Choose spans for all nodes at once; a span violating the causality of an
+	// already assigned predecessor is only used when nothing better exists
+	sol := solveAssignment(slots, func(assigned []int, slot, cand int) (int, bool) {
+		if GlobalCausalityMode == CausalityOff {
+			return 0, true
+		}
+		node := findNodeByID(fs.Graph, topOrder[slot])
+		matched := make(map[string]*trace.Span)
+		for k, idx := range assigned {
+			if idx >= 0 {
+				matched[topOrder[k]] = &tr.Spans[idx]
+			}
+		}
+		if validateCausality(node, &tr.Spans[cand], fs.Graph, matched, graph) != nil {
+			return costViolation, true
+		}
+		return 0, true
+	})
+
+	matchedSpans := make(map[string]*trace.Span) // node ID -> matched span
 	
-	for _, nodeID := range topOrder {
+	for i, nodeID := range topOrder {
 		node := findNodeByID(fs.Graph, nodeID)
 		if node == nil {
 			results = append(results, StepResult{
@@ -304,22 +419,19 @@ func validateGraphAgainstTrace(fs *spec.FlowSpec, opIndex map[string]map[string]
 				Call: "", 
 				Status: "FAIL",
 				Message: "Node not found",
+				Code: CodeStepNotObserved,
 			})
 			okAll = false
 			continue
 		}
-		
-		// Find matching spans for this node
-		candidateSpans := spanIndex[node.Call]
+		tempStep := nodeSteps[i]
+
 		var matchedSpan *trace.Span
-		
-		for _, span := range candidateSpans {
-			spanKey := fmt.Sprintf("%s:%s:%d", span.Service, span.Name, span.StartNanos)
-			if !usedSpans[spanKey] {
-				matchedSpan = &span
-				usedSpans[spanKey] = true
-				break
-			}
+		reason := ""
+		if idx := sol.spans[i]; idx >= 0 {
+			matchedSpan = &tr.Spans[idx]
+			reason = sol.reason(slots, i)
+			matchedSpans[node.ID] = matchedSpan
 		}
 		
 		if matchedSpan == nil {
@@ -328,6 +440,7 @@ func validateGraphAgainstTrace(fs *spec.FlowSpec, opIndex map[string]map[string]
 				Call: node.Call, 
 				Status: "FAIL", 
 				Message: "No matching span found in trace",
+				Code: CodeStepNotObserved,
 			})
 			okAll = false
 			continue
@@ -335,12 +448,14 @@ func validateGraphAgainstTrace(fs *spec.FlowSpec, opIndex map[string]map[string]
 		
 		// Perform causality checking if enabled
 		if GlobalCausalityMode != CausalityOff {
-			if err := validateCausality(node, matchedSpan, fs.Graph, tr, usedSpans); err != nil {
+			if err := validateCausality(node, matchedSpan, fs.Graph, matchedSpans, graph); err != nil {
 				results = append(results, StepResult{
 					Step: node.ID,
 					Call: node.Call,
 					Status: "FAIL",
 					Message: fmt.Sprintf("Causality validation failed: %v", err),
+					Code: CodeOrderViolation,
+					SpanID: getSpanID(*matchedSpan),
 				})
 				okAll = false
 				continue
@@ -353,14 +468,6 @@ func validateGraphAgainstTrace(fs *spec.FlowSpec, opIndex map[string]map[string]
 			// Similar to flow validation - check service operation conditions
 			if ops, ok := opIndex[getServiceFromCall(node.Call)]; ok {
 				if op, exists := ops[getOperationFromCall(node.Call)]; exists {
-					// Create a temporary FlowStep for condition evaluation
-					tempStep := spec.FlowStep{
-						Step: node.ID,
-						Call: node.Call,
-						Input: node.Input,
-						Output: node.Output,
-						Meta: node.Meta,
-					}
 					conditions, _ = EvaluateConditions(tempStep, op, *matchedSpan, nil)
 				}
 			}
@@ -368,12 +475,13 @@ func validateGraphAgainstTrace(fs *spec.FlowSpec, opIndex map[string]map[string]
 		
 		// Determine overall status based on conditions
 		status := "PASS"
-		var message string
+		var message, code string
 		if EnableSemantic && len(conditions) > 0 {
 			for _, cond := range conditions {
 				if cond.Status == "FAIL" {
 					status = "FAIL"
 					message = "semantic validation failed"
+					code = CodeConditionFailed
 					okAll = false
 					break
 				}
@@ -385,6 +493,9 @@ func validateGraphAgainstTrace(fs *spec.FlowSpec, opIndex map[string]map[string]
 			Call: node.Call,
 			Status: status,
 			Message: message,
+			Code: code,
+			SpanID: getSpanID(*matchedSpan),
+			MatchReason: reason,
 			Conditions: conditions,
 		})
 	}
@@ -393,27 +504,13 @@ func validateGraphAgainstTrace(fs *spec.FlowSpec, opIndex map[string]map[string]
 }
 
 // validateCausality checks causality constraints for DAG nodes
-func validateCausality(node *spec.GraphNode, nodeSpan *trace.Span, graph *spec.GraphSpec, tr *trace.Trace, usedSpans map[string]bool) error {
+func validateCausality(node *spec.GraphNode, nodeSpan *trace.Span, graph *spec.GraphSpec, matchedSpans map[string]*trace.Span, calls *CallGraph) error {
 	// Get predecessor nodes
 	predecessors := getPredecessors(node.ID, graph)
 	
 	for _, predID := range predecessors {
 		// Find the span that was matched to this predecessor
-		predNode := findNodeByID(graph, predID)
-		if predNode == nil {
-			continue
-		}
-		
-		// Find the matched span for predecessor
-		var predSpan *trace.Span
-		for _, span := range tr.Spans {
-			spanKey := fmt.Sprintf("%s:%s:%d", span.Service, span.Name, span.StartNanos)
-			if usedSpans[spanKey] && span.Service == getServiceFromCall(predNode.Call) && span.Name == getOperationFromCall(predNode.Call) {
-				predSpan = &span
-				break
-			}
-		}
-		
+		predSpan := matchedSpans[predID]
 		if predSpan == nil {
 			continue // Predecessor not found, will be caught in its own validation
 		}
@@ -421,9 +518,9 @@ func validateCausality(node *spec.GraphNode, nodeSpan *trace.Span, graph *spec.G
 		// Apply causality mode
 		switch GlobalCausalityMode {
 		case CausalityStrict:
-			// Check parent-child relationship
-			if !isParentChild(predSpan, nodeSpan) {
-				return fmt.Errorf("node %s should be child of %s (strict mode)", node.ID, predID)
+			// Check call-tree relationship: parent-child, shared orchestrator or span link
+			if !calls.CausallyRelated(getSpanID(*predSpan), getSpanID(*nodeSpan)) {
+				return fmt.Errorf("node %s is not causally related to %s (strict mode)", node.ID, predID)
 			}
 		case CausalityTemporal:
 			// Check temporal ordering: predecessor should start before or at the same time as current
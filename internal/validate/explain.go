@@ -0,0 +1,291 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package validate
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/choreoatlas2025/cli/internal/spec"
+	"github.com/choreoatlas2025/cli/internal/trace"
+)
+
+// MaxExplainCandidates limits the candidate spans listed per failed step
+var MaxExplainCandidates = 3
+
+// Candidate is a span that was considered for a failed step, with the reasons
+// it was not (or could not be) used
+type Candidate struct {
+	SpanID      string   `json:"spanId"`
+	Service     string   `json:"service"`
+	Name        string   `json:"name"`
+	OperationID string   `json:"operationId,omitempty"`
+	StartNanos  int64    `json:"startNanos"`
+	Score       float64  `json:"score"` // 0..1, higher is closer
+	Rejected    []string `json:"rejected,omitempty"`
+}
+
+// ExplainFailures attaches the nearest candidate spans to every failed step.
+// Candidates are ranked by service and operation similarity (edit distance on
+// the span name and on the computed operation ID) and by whether they fall in
+// the expected time window between the neighbouring matched steps.
+func ExplainFailures(fs *spec.FlowSpec, opIndex map[string]map[string]spec.ServiceOperation, tr *trace.Trace, results []StepResult) {
+	usedBy := make(map[string]string)     // span ID -> step
+	spanOf := make(map[string]trace.Span) // step -> its matched span
+	spansByID := make(map[string]trace.Span)
+	calls, _ := BuildCallGraph(tr.Spans)
+	for _, sp := range tr.Spans {
+		spansByID[getSpanID(sp)] = sp
+	}
+	for _, r := range results {
+		if sp, ok := spansByID[r.SpanID]; ok && r.SpanID != "" {
+			usedBy[r.SpanID] = r.Step
+			spanOf[r.Step] = sp
+		}
+	}
+
+	for i := range results {
+		r := &results[i]
+		if r.Status != "FAIL" || r.Call == "internal" {
+			continue
+		}
+		st, ok := fs.StepByName(r.Step)
+		if !ok {
+			continue
+		}
+		svc, op, err := splitCall(st.Call)
+		if err != nil {
+			continue
+		}
+		lo, hi := explainWindow(fs, results, i, spanOf)
+
+		var cands []Candidate
+		for _, sp := range tr.Spans {
+			c := Candidate{
+				SpanID:      getSpanID(sp),
+				Service:     sp.Service,
+				Name:        sp.Name,
+				OperationID: spec.ComputeOperationID(sp),
+				StartNanos:  sp.StartNanos,
+			}
+			inWindow := sp.StartNanos >= lo && sp.StartNanos <= hi
+			c.Score = candidateScore(st, svc, op, sp, inWindow)
+			c.Rejected = rejectionReasons(fs, opIndex, calls, st, svc, op, sp, r, usedBy, spanOf, lo, hi)
+			cands = append(cands, c)
+		}
+		sort.SliceStable(cands, func(a, b int) bool {
+			if cands[a].Score != cands[b].Score {
+				return cands[a].Score > cands[b].Score
+			}
+			return windowDistance(cands[a].StartNanos, lo, hi) < windowDistance(cands[b].StartNanos, lo, hi)
+		})
+		if len(cands) > MaxExplainCandidates {
+			cands = cands[:MaxExplainCandidates]
+		}
+		r.Candidates = cands
+	}
+}
+
+// explainWindow returns the start time range in which the step's span was expected:
+// after the spans of preceding steps and before those of following steps
+func explainWindow(fs *spec.FlowSpec, results []StepResult, idx int, spanOf map[string]trace.Span) (int64, int64) {
+	lo, hi := int64(math.MinInt64), int64(math.MaxInt64)
+	step := results[idx].Step
+
+	if fs.IsGraphMode() {
+		for _, pred := range getPredecessors(step, fs.Graph) {
+			if sp, ok := spanOf[pred]; ok && sp.StartNanos > lo {
+				lo = sp.StartNanos
+			}
+		}
+		for _, e := range fs.Graph.Edges {
+			if sp, ok := spanOf[e.To]; ok && e.From == step && sp.StartNanos < hi {
+				hi = sp.StartNanos
+			}
+		}
+		return lo, hi
+	}
+
+	for j := idx - 1; j >= 0; j-- {
+		if sp, ok := spanOf[results[j].Step]; ok && results[j].Call != "internal" {
+			lo = sp.StartNanos
+			break
+		}
+	}
+	for j := idx + 1; j < len(results); j++ {
+		if sp, ok := spanOf[results[j].Step]; ok && results[j].Call != "internal" {
+			hi = sp.StartNanos
+			break
+		}
+	}
+	return lo, hi
+}
+
+// precedingFlowStep returns the step of the nearest preceding flow entry that
+// has a matched span; for parallel entries any matched child is returned
+func precedingFlowStep(fs *spec.FlowSpec, step string, spanOf map[string]trace.Span) string {
+	entry := -1
+	for i, e := range fs.Flow {
+		if e.Step == step {
+			entry = i
+		}
+		for _, p := range e.Parallel {
+			if p.Step == step {
+				entry = i
+			}
+		}
+	}
+	for i := entry - 1; i >= 0; i-- {
+		if _, ok := spanOf[fs.Flow[i].Step]; ok && fs.Flow[i].Step != "" {
+			return fs.Flow[i].Step
+		}
+		for _, p := range fs.Flow[i].Parallel {
+			if _, ok := spanOf[p.Step]; ok {
+				return p.Step
+			}
+		}
+	}
+	return ""
+}
+
+// candidateScore weighs service similarity, operation similarity and timing
+func candidateScore(st spec.FlowStep, svc, op string, sp trace.Span, inWindow bool) float64 {
+	svcSim := similarity(normalize(sp.Service), normalize(svc))
+	opSim := math.Max(similarity(normalize(sp.Name), normalize(op)),
+		similarity(normalize(spec.ComputeOperationID(sp)), normalize(op)))
+	if nameMatches(st, op, sp) {
+		opSim = 1
+	}
+	score := 0.5*svcSim + 0.4*opSim
+	if inWindow {
+		score += 0.1
+	}
+	return math.Round(score*100) / 100
+}
+
+// nameMatches applies the step matcher while ignoring the service
+func nameMatches(st spec.FlowStep, op string, sp trace.Span) bool {
+	st.Call = sp.Service + "." + op
+	ok, _ := matchStep(st, sp)
+	return ok
+}
+
+// rejectionReasons explains why a span does not satisfy the step
+func rejectionReasons(
+	fs *spec.FlowSpec,
+	opIndex map[string]map[string]spec.ServiceOperation,
+	calls *CallGraph,
+	st spec.FlowStep, svc, op string, sp trace.Span,
+	r *StepResult, usedBy map[string]string, spanOf map[string]trace.Span, lo, hi int64,
+) []string {
+	var reasons []string
+	spanID := getSpanID(sp)
+
+	if normalize(sp.Service) != normalize(svc) {
+		reasons = append(reasons, fmt.Sprintf("wrong service: %s (expected %s)", sp.Service, svc))
+	}
+	if !nameMatches(st, op, sp) {
+		switch {
+		case st.Match != nil:
+			reasons = append(reasons, "attributes do not satisfy match")
+		case st.IsMessaging():
+			reasons = append(reasons, fmt.Sprintf("not a %s on %s (role %q, destination %q)", st.Kind, st.Destination, sp.MessagingRole(), sp.MessagingDestination()))
+		default:
+			reasons = append(reasons, fmt.Sprintf("wrong name: %s / %s (expected %s)", sp.Name, spec.ComputeOperationID(sp), op))
+		}
+	}
+	if other := usedBy[spanID]; other != "" && other != r.Step {
+		reasons = append(reasons, fmt.Sprintf("already used by step %s", other))
+	}
+	if sp.StartNanos < lo {
+		reasons = append(reasons, "out of order: starts before the preceding step")
+	} else if sp.StartNanos > hi {
+		reasons = append(reasons, "out of order: starts after the following step")
+	}
+	if len(reasons) > 0 {
+		return reasons
+	}
+
+	// The span fits structurally; look at causality and conditions
+	if fs.IsGraphMode() && GlobalCausalityMode != CausalityOff {
+		if node := findNodeByID(fs.Graph, r.Step); node != nil {
+			matched := make(map[string]*trace.Span)
+			for _, pred := range getPredecessors(r.Step, fs.Graph) {
+				if predSpan, ok := spanOf[pred]; ok {
+					matched[pred] = &predSpan
+				}
+			}
+			if err := validateCausality(node, &sp, fs.Graph, matched, calls); err != nil {
+				reasons = append(reasons, fmt.Sprintf("causality mismatch: %v", err))
+			}
+		}
+	} else if !fs.IsGraphMode() && GlobalCausalityMode == CausalityStrict && st.Kind != spec.StepKindConsume {
+		if prev := precedingFlowStep(fs, r.Step, spanOf); prev != "" && calls != nil &&
+			!calls.CausallyRelated(getSpanID(spanOf[prev]), spanID) {
+			reasons = append(reasons, fmt.Sprintf("causality mismatch: not causally related to step %s", prev))
+		}
+	}
+	if EnableSemantic {
+		if opSpec, ok := lookupOperation(opIndex, st.Call); ok {
+			conds, okSem := EvaluateConditions(st, opSpec, sp, map[string]any{})
+			if !okSem {
+				var failed []string
+				for _, c := range conds {
+					if c.Status == "FAIL" {
+						failed = append(failed, c.Kind+":"+c.Name)
+					}
+				}
+				reasons = append(reasons, fmt.Sprintf("condition failure: %s", strings.Join(failed, ", ")))
+			}
+		}
+	}
+	if len(reasons) == 0 && spanID == r.SpanID {
+		reasons = append(reasons, "assigned to this step: "+r.Message)
+	}
+	return reasons
+}
+
+func windowDistance(start, lo, hi int64) int64 {
+	switch {
+	case start < lo:
+		return lo - start
+	case start > hi:
+		return start - hi
+	}
+	return 0
+}
+
+// similarity is 1 - normalized Levenshtein distance
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	maxLen := len(ra)
+	if len(rb) > maxLen {
+		maxLen = len(rb)
+	}
+	if maxLen == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(maxLen)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,111 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package validate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/choreoatlas2025/cli/internal/spec"
+	"github.com/choreoatlas2025/cli/internal/trace"
+)
+
+func hasRejection(c Candidate, substr string) bool {
+	for _, r := range c.Rejected {
+		if strings.Contains(r, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestExplainFailures_RanksNearestCandidates(t *testing.T) {
+	fs := &spec.FlowSpec{
+		Flow: []spec.FlowStep{
+			{Step: "create", Call: "orderService.createOrder"},
+			{Step: "pay", Call: "paymentService.processPayment"},
+		},
+	}
+	tr := &trace.Trace{Spans: []trace.Span{
+		{Service: "orderService", Name: "createOrders", StartNanos: 100, EndNanos: 150},
+		{Service: "inventoryService", Name: "createOrder", StartNanos: 120, EndNanos: 160},
+		{Service: "paymentService", Name: "processPayment", StartNanos: 200, EndNanos: 250},
+		{Service: "shippingService", Name: "ship", StartNanos: 300, EndNanos: 350},
+	}}
+
+	results, _ := ValidateAgainstTrace(fs, nil, tr)
+	ExplainFailures(fs, nil, tr, results)
+
+	create := resultByStep(results)["create"]
+	if create.Status != "FAIL" || len(create.Candidates) != MaxExplainCandidates {
+		t.Fatalf("expected failed step with %d candidates, got %s %+v", MaxExplainCandidates, create.Status, create.Candidates)
+	}
+	top := create.Candidates[0]
+	if top.Name != "createOrders" || !hasRejection(top, "wrong name") {
+		t.Errorf("expected misnamed span first with wrong name reason, got %+v", top)
+	}
+	second := create.Candidates[1]
+	if second.Service != "inventoryService" || !hasRejection(second, "wrong service") {
+		t.Errorf("expected span of other service second, got %+v", second)
+	}
+	if resultByStep(results)["pay"].Candidates != nil {
+		t.Error("expected no candidates on passing steps")
+	}
+}
+
+func TestExplainFailures_AlreadyUsedAndConditions(t *testing.T) {
+	fs := &spec.FlowSpec{
+		Flow: []spec.FlowStep{
+			{Step: "first", Call: "orderService.createOrder"},
+			{Step: "second", Call: "orderService.createOrder"},
+			{Step: "pay", Call: "paymentService.processPayment"},
+		},
+	}
+	opIndex := map[string]map[string]spec.ServiceOperation{
+		"paymentService": {"processPayment": {OperationId: "processPayment",
+			Postconditions: map[string]spec.Condition{"paid": {Expr: "response.status == 200"}}}},
+	}
+	tr := &trace.Trace{Spans: []trace.Span{
+		sagaSpan("orderService", "createOrder", 100, 201),
+		sagaSpan("paymentService", "processPayment", 200, 402),
+	}}
+
+	results, _ := ValidateAgainstTrace(fs, opIndex, tr)
+	ExplainFailures(fs, opIndex, tr, results)
+	byStep := resultByStep(results)
+
+	second := byStep["second"]
+	if len(second.Candidates) == 0 || !hasRejection(second.Candidates[0], "already used by step first") {
+		t.Errorf("expected already used candidate, got %+v", second.Candidates)
+	}
+	pay := byStep["pay"]
+	if pay.Status != "FAIL" || len(pay.Candidates) == 0 || !hasRejection(pay.Candidates[0], "condition failure: post:paid") {
+		t.Errorf("expected condition failure candidate, got %s %+v", pay.Status, pay.Candidates)
+	}
+}
+
+func TestExplainFailures_GraphOutOfOrder(t *testing.T) {
+	fs := &spec.FlowSpec{
+		Graph: &spec.GraphSpec{
+			Nodes: []spec.GraphNode{
+				{ID: "reserve", Call: "inventoryService.reserve"},
+				{ID: "confirm", Call: "inventoryService.confirm", Depends: []string{"reserve"}},
+			},
+		},
+	}
+	fs.Graph.EnsureEdges()
+	tr := &trace.Trace{Spans: []trace.Span{
+		{Service: "inventoryService", Name: "confirm", StartNanos: 50, EndNanos: 60},
+		{Service: "inventoryService", Name: "reserve", StartNanos: 100, EndNanos: 150},
+	}}
+
+	results, ok := ValidateAgainstTrace(fs, nil, tr)
+	if ok {
+		t.Fatal("expected confirm before reserve to fail")
+	}
+	ExplainFailures(fs, nil, tr, results)
+	confirm := resultByStep(results)["confirm"]
+	if len(confirm.Candidates) == 0 || !hasRejection(confirm.Candidates[0], "out of order") {
+		t.Errorf("expected out of order candidate, got %+v", confirm.Candidates)
+	}
+}
@@ -0,0 +1,176 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package validate
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/choreoatlas2025/cli/internal/spec"
+)
+
+// DefaultFlowVersion 是 --fix 为缺失的 info.version 写入的版本
+const DefaultFlowVersion = "0.1.0"
+
+// FileFix 是 lint --fix 对单个文件的修改
+type FileFix struct {
+	Path   string
+	Before []byte
+	After  []byte
+	Fixes  []string // 每项修改的说明
+}
+
+// FixFlow 为可机械修复的 lint 问题生成修改（不写文件）：
+//   - unknown-operation：ServiceSpec 中存在仅大小写或命名风格不同的操作时改写 call，否则为其生成操作桩
+//   - telemetry-input：将 input 中的 http.* / otel.* / span.* 键移至被调用操作的 preconditions
+//   - info.version 缺失时写入 DefaultFlowVersion（与 missing-version 规则是否开启无关）
+//
+// 除 info.version 外只处理传入的问题，因此被配置关闭或 inline 忽略的问题不会被修复。
+func FixFlow(flowPath string, fs *spec.FlowSpec, opIndex map[string]map[string]spec.ServiceOperation, issues []LintIssue) ([]FileFix, error) {
+	flowEdits := spec.FlowEdits{Calls: map[string]string{}, DropInput: map[string][]string{}}
+	svcEdits := map[string]*spec.ServiceEdits{} // ServiceSpec 路径 -> 修改
+	fixes := map[string][]string{}              // 文件路径 -> 说明
+	note := func(path, format string, args ...any) {
+		fixes[path] = append(fixes[path], fmt.Sprintf(format, args...))
+	}
+	serviceEdits := func(svc string) (string, *spec.ServiceEdits) {
+		path := spec.ResolvePath(flowPath, fs.Services[svc].Spec)
+		if svcEdits[path] == nil {
+			svcEdits[path] = &spec.ServiceEdits{Preconditions: map[string]map[string]spec.Condition{}}
+		}
+		return path, svcEdits[path]
+	}
+	stubbed := map[string]bool{}
+
+	// 先处理调用，遥测条件需要挂到改写后的操作上
+	calls := map[string]string{}
+	for _, is := range issues {
+		if is.Code != CodeUnknownOperation {
+			continue
+		}
+		st, ok := fs.StepByName(is.Ref)
+		if !ok {
+			continue
+		}
+		svc, op, err := splitCall(st.Call)
+		if err != nil {
+			continue
+		}
+		similar := similarOperations(op, opIndex[svc])
+		if len(similar) == 1 {
+			calls[is.Ref] = svc + "." + similar[0]
+			flowEdits.Calls[is.Ref] = calls[is.Ref]
+			note(flowPath, "%s: call %s -> %s", is.Ref, st.Call, calls[is.Ref])
+			continue
+		}
+		if len(similar) > 1 {
+			continue // 有歧义时不修复
+		}
+		path, e := serviceEdits(svc)
+		if !stubbed[path+"\x00"+op] {
+			stubbed[path+"\x00"+op] = true
+			e.Stubs = append(e.Stubs, spec.ServiceOperation{
+				OperationId: op,
+				Description: "TODO: stub added by lint --fix for " + st.Call,
+			})
+			note(path, "add operation %s (called by %s)", op, is.Ref)
+		}
+	}
+
+	for _, is := range issues {
+		if is.Code == CodeTelemetryInput {
+			st, ok := fs.StepByName(is.Ref)
+			if !ok {
+				continue
+			}
+			call := st.Call
+			if c, ok := calls[is.Ref]; ok {
+				call = c
+			}
+			svc, op, err := splitCall(call)
+			if err != nil {
+				continue
+			}
+			path, e := serviceEdits(svc)
+			if _, ok := opIndex[svc][op]; !ok && !stubbed[path+"\x00"+op] {
+				continue // 操作无法确定时保留 input，避免丢失信息
+			}
+			keys := findTelemetryKeys(st.Input)
+			sort.Strings(keys)
+			if e.Preconditions[op] == nil {
+				e.Preconditions[op] = map[string]spec.Condition{}
+			}
+			for _, k := range keys {
+				attr, value := k, st.Input[k]
+				if _, top := st.Input[k]; !top {
+					body, _ := st.Input["body"].(map[string]any)
+					attr = strings.TrimPrefix(k, "body.")
+					value = body[attr]
+				}
+				e.Preconditions[op][attr] = spec.Condition{Expr: telemetryCondition(attr, value)}
+				note(path, "%s: precondition %s (moved from %s input)", op, attr, is.Ref)
+			}
+			flowEdits.DropInput[is.Ref] = keys
+			note(flowPath, "%s: remove telemetry input %s", is.Ref, strings.Join(keys, ", "))
+		}
+	}
+	if fs.Info.Version == "" {
+		flowEdits.Version = DefaultFlowVersion
+		note(flowPath, "set info.version to %q", DefaultFlowVersion)
+	}
+
+	var out []FileFix
+	if len(fixes[flowPath]) > 0 {
+		before := fs.Source.Bytes()
+		after, err := spec.EditFlowSpec(before, flowEdits)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fix %s: %w", flowPath, err)
+		}
+		out = append(out, FileFix{Path: flowPath, Before: before, After: after, Fixes: fixes[flowPath]})
+	}
+	for _, path := range sortedKeys(svcEdits) {
+		if len(fixes[path]) == 0 {
+			continue
+		}
+		before, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read servicespec: %w", err)
+		}
+		after, err := spec.EditServiceSpec(before, *svcEdits[path])
+		if err != nil {
+			return nil, fmt.Errorf("failed to fix %s: %w", path, err)
+		}
+		out = append(out, FileFix{Path: path, Before: before, After: after, Fixes: fixes[path]})
+	}
+	return out, nil
+}
+
+// similarOperations 返回与 op 仅大小写或命名风格（snake_case、kebab-case、HTTP 路由等）不同的操作
+func similarOperations(op string, ops map[string]spec.ServiceOperation) []string {
+	want := strings.ToLower(spec.NormalizeOperationID(op))
+	var out []string
+	for _, id := range sortedKeys(ops) {
+		if strings.ToLower(spec.NormalizeOperationID(id)) == want {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// telemetryCondition 将 input 中的遥测键转为等价的前置条件：
+// 字面量比较属性值，变量引用等无法静态确定的值只要求属性存在
+func telemetryCondition(attr string, value any) string {
+	key := strconv.Quote(attr)
+	switch v := value.(type) {
+	case string:
+		if !strings.Contains(v, "${") {
+			return fmt.Sprintf("attrs[%s] == %s", key, strconv.Quote(v))
+		}
+	case bool, int, int64, float64:
+		return fmt.Sprintf("attrs[%s] == %v", key, v)
+	}
+	return fmt.Sprintf("hasAttr(%s)", key)
+}
@@ -0,0 +1,141 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package validate
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/choreoatlas2025/cli/internal/spec"
+)
+
+const fixFlow = `info:
+  title: order  # reviewed
+
+services:
+  orderService:
+    spec: ./order.servicespec.yaml
+
+flow:
+  - step: create
+    call: orderService.CreateOrder
+    input:
+      customerId: ${customerId}
+      http.method: POST
+    output:
+      orderId: response.body.id
+  - step: cancel
+    call: orderService.cancel_order
+    input:
+      orderId: ${orderId}
+      body:
+        http.route: ${route}
+`
+
+func TestFixFlow(t *testing.T) {
+	flowPath, fs := loadDiagnosticsFlow(t, fixFlow)
+	_, opIndex, err := fs.BuildOperationIndex(flowPath)
+	if err != nil {
+		t.Fatalf("build op index: %v", err)
+	}
+	issues, err := LintFlow(flowPath, fs, opIndex, nil)
+	if err != nil {
+		t.Fatalf("lint: %v", err)
+	}
+	fixes, err := FixFlow(flowPath, fs, opIndex, issues)
+	if err != nil {
+		t.Fatalf("FixFlow: %v", err)
+	}
+	if len(fixes) != 2 || fixes[0].Path != flowPath {
+		t.Fatalf("expected fixes for the flow and its servicespec, got %+v", fixes)
+	}
+
+	flow := string(fixes[0].After)
+	for _, want := range []string{
+		"  title: order  # reviewed\n  version: \"0.1.0\"\n\nservices:",
+		"call: orderService.createOrder\n    input:\n      customerId: ${customerId}\n    output:",
+		"      orderId: ${orderId}\n",
+	} {
+		if !strings.Contains(flow, want) {
+			t.Errorf("fixed flow missing %q:\n%s", want, flow)
+		}
+	}
+	if strings.Contains(flow, "http.") || strings.Contains(flow, "body:") {
+		t.Errorf("telemetry input should be removed:\n%s", flow)
+	}
+	svc := string(fixes[1].After)
+	for _, want := range []string{
+		`http.method: attrs["http.method"] == "POST"`,
+		"- operationId: cancel_order",
+		`http.route: hasAttr("http.route")`,
+	} {
+		if !strings.Contains(svc, want) {
+			t.Errorf("fixed servicespec missing %q:\n%s", want, svc)
+		}
+	}
+
+	for _, f := range fixes {
+		if err := os.WriteFile(f.Path, f.After, 0o644); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	fs, err = spec.LoadFlowSpec(flowPath)
+	if err != nil {
+		t.Fatalf("load fixed flow: %v", err)
+	}
+	if _, opIndex, err = fs.BuildOperationIndex(flowPath); err != nil {
+		t.Fatalf("build op index: %v", err)
+	}
+	left, _ := LintFlow(flowPath, fs, opIndex, nil)
+	if hasIssue(left, CodeUnknownOperation, CodeTelemetryInput, CodeMissingVersion, CodeInvalidCondition) {
+		t.Errorf("issues left after fix: %v", left)
+	}
+}
+
+func TestFixFlow_SkipsAmbiguousAndIgnored(t *testing.T) {
+	flowPath, fs := loadDiagnosticsFlow(t, `info:
+  title: order
+  version: "1.0.0"
+services:
+  orderService:
+    spec: ./order.servicespec.yaml
+flow:
+  - step: create
+    call: orderService.createOrder
+    input: # choreoatlas:ignore telemetry-input
+      http.method: POST
+`)
+	_, opIndex, err := fs.BuildOperationIndex(flowPath)
+	if err != nil {
+		t.Fatalf("build op index: %v", err)
+	}
+	issues, _ := LintFlow(flowPath, fs, opIndex, nil)
+	fixes, err := FixFlow(flowPath, fs, opIndex, issues)
+	if err != nil {
+		t.Fatalf("FixFlow: %v", err)
+	}
+	if len(fixes) != 0 {
+		t.Errorf("ignored issues and disabled rules should not be fixed, got %+v", fixes)
+	}
+
+	ops := opIndex["orderService"]
+	ops["create_order"] = ops["createOrder"]
+	if got := similarOperations("CreateOrder", ops); len(got) != 2 {
+		t.Errorf("similarOperations = %v, want both spellings", got)
+	}
+	if got := similarOperations("get-order", ops); len(got) != 1 || got[0] != "getOrder" {
+		t.Errorf("similarOperations = %v, want [getOrder]", got)
+	}
+}
+
+func hasIssue(issues []LintIssue, codes ...string) bool {
+	for _, is := range issues {
+		for _, c := range codes {
+			if is.Code == c {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,119 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package validate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/choreoatlas2025/cli/internal/spec"
+	"github.com/choreoatlas2025/cli/internal/trace"
+)
+
+// forbidStepPrefix 标记 forbid 规则产生的结果，它们不是流程步骤
+const forbidStepPrefix = "forbid: "
+
+// IsForbidResult reports whether r was produced by a `forbid:` rule rather
+// than a flow step.
+func IsForbidResult(r StepResult) bool {
+	return strings.HasPrefix(r.Step, forbidStepPrefix)
+}
+
+// checkForbidRules evaluates the `forbid:` rules over the trace's call graph.
+// A rule without violations yields one PASS result; otherwise every offending
+// span yields a FAIL result carrying its span ID.
+func checkForbidRules(fs *spec.FlowSpec, tr *trace.Trace) ([]StepResult, bool) {
+	graph, err := BuildCallGraph(tr.Spans)
+	if err != nil {
+		return nil, true // graph-build errors are reported by the main validators
+	}
+	nodes := sortedCallNodes(graph)
+	toleranceNanos := GlobalCausalityToleranceMs * 1000000
+
+	var results []StepResult
+	ok := true
+	for i, rule := range fs.Forbid {
+		name := forbidStepPrefix + rule.RuleName()
+		pos := positionRef(fs.Source.Lookup("forbid", i))
+		violated := false
+		for _, node := range nodes {
+			if !forbidMatches(rule.Call, rule.When, node) {
+				continue
+			}
+			reason, bad := forbidOrdering(rule, node, nodes, toleranceNanos)
+			if !bad {
+				continue
+			}
+			violated = true
+			msg := fmt.Sprintf("forbidden call %s.%s%s", node.Service, node.Operation, reason)
+			if rule.Message != "" {
+				msg = rule.Message + " (" + msg + ")"
+			}
+			results = append(results, StepResult{
+				Step:        name,
+				Call:        node.Service + "." + node.Operation,
+				Status:      "FAIL",
+				Message:     msg,
+				SpanID:      node.SpanID,
+				MatchReason: "forbid pattern " + rule.Call,
+				Code:        CodeForbiddenCall,
+				Position:    pos,
+			})
+		}
+		if violated {
+			ok = false
+			continue
+		}
+		results = append(results, StepResult{Step: name, Call: rule.Call, Status: "PASS", Message: "no forbidden calls", Position: pos})
+	}
+	return results, ok
+}
+
+// forbidMatches applies a call pattern and optional CEL scope to a node.
+// The scope sees the node as `span` and its caller as `parent`.
+func forbidMatches(pattern, when string, node *CallNode) bool {
+	sp := nodeSpan(node)
+	if !matchCallPattern(pattern, sp) {
+		return false
+	}
+	if when == "" {
+		return true
+	}
+	parent := map[string]any{"name": "", "service": "", "kind": "", "attributes": map[string]any{}}
+	if node.Parent != nil {
+		parent = spanVar(nodeSpan(node.Parent))
+	}
+	env := spanEnv(sp)
+	env["parent"] = parent
+	ok, _, err := evalCELBool(when, env)
+	return err == nil && ok
+}
+
+// forbidOrdering decides whether a matching node violates the rule's ordering.
+// `before`: forbidden unless an anchor span completed before the call started.
+// `after`: forbidden once an anchor span has started.
+func forbidOrdering(rule spec.ForbidRule, node *CallNode, nodes []*CallNode, toleranceNanos int64) (string, bool) {
+	if rule.Before == nil && rule.After == nil {
+		return "", true
+	}
+	if a := rule.Before; a != nil {
+		completed := false
+		for _, other := range nodes {
+			if other != node && forbidMatches(a.Call, a.When, other) && other.EndNanos <= node.StartNanos+toleranceNanos {
+				completed = true
+				break
+			}
+		}
+		if !completed {
+			return fmt.Sprintf(" before %s completed", a.Call), true
+		}
+	}
+	if a := rule.After; a != nil {
+		for _, other := range nodes {
+			if other != node && forbidMatches(a.Call, a.When, other) && other.StartNanos < node.StartNanos {
+				return fmt.Sprintf(" after %s (span %s)", a.Call, other.SpanID), true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,157 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package validate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/choreoatlas2025/cli/internal/spec"
+	"github.com/choreoatlas2025/cli/internal/trace"
+)
+
+func forbidSpan(id, parent, service, name string, start, end int64, attrs map[string]any) trace.Span {
+	if attrs == nil {
+		attrs = map[string]any{}
+	}
+	attrs["otlp.span_id"] = id
+	if parent != "" {
+		attrs["otlp.parent_span_id"] = parent
+	}
+	return trace.Span{Service: service, Name: name, StartNanos: start, EndNanos: end, Attributes: attrs}
+}
+
+func forbidResults(results []StepResult) []StepResult {
+	var out []StepResult
+	for _, r := range results {
+		if strings.HasPrefix(r.Step, "forbid: ") {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func TestForbid_CallPattern(t *testing.T) {
+	fs := &spec.FlowSpec{
+		Flow:   []spec.FlowStep{{Step: "create", Call: "orderService.createOrder"}},
+		Forbid: []spec.ForbidRule{{Name: "no-legacy", Call: "inventoryV1.*"}, {Call: "auditService.*"}},
+	}
+	tr := &trace.Trace{Spans: []trace.Span{
+		forbidSpan("a", "", "orderService", "createOrder", 100, 200, nil),
+		forbidSpan("b", "a", "inventoryV1", "reserve", 110, 150, nil),
+	}}
+
+	results, ok := ValidateAgainstTrace(fs, nil, tr)
+	if ok {
+		t.Fatal("expected forbidden call to fail validation")
+	}
+	fr := forbidResults(results)
+	if len(fr) != 2 {
+		t.Fatalf("expected one result per rule, got %+v", fr)
+	}
+	if fr[0].Status != "FAIL" || fr[0].SpanID != "b" || fr[0].Step != "forbid: no-legacy" {
+		t.Errorf("expected violation on span b, got %+v", fr[0])
+	}
+	if fr[1].Status != "PASS" || fr[1].Step != "forbid: auditService.*" {
+		t.Errorf("expected unnamed rule to pass, got %+v", fr[1])
+	}
+}
+
+func TestForbid_BeforeOrdering(t *testing.T) {
+	rule := spec.ForbidRule{
+		Call:   "paymentService.processPayment",
+		Before: &spec.ForbidAnchor{Call: "riskService.assess", When: "span.attributes['risk.decision'] == 'approve'"},
+	}
+	fs := &spec.FlowSpec{Forbid: []spec.ForbidRule{rule}}
+
+	approved := map[string]any{"risk.decision": "approve"}
+	good := &trace.Trace{Spans: []trace.Span{
+		forbidSpan("r", "", "riskService", "assess", 100, 150, approved),
+		forbidSpan("p", "", "paymentService", "processPayment", 200, 250, nil),
+	}}
+	if fr, ok := checkForbidRules(fs, good); !ok || fr[0].Status != "PASS" {
+		t.Errorf("expected payment after approved assessment to pass, got %+v", fr)
+	}
+
+	const ms = int64(1000000)
+	early := &trace.Trace{Spans: []trace.Span{
+		forbidSpan("p", "", "paymentService", "processPayment", 100*ms, 150*ms, nil),
+		forbidSpan("r", "", "riskService", "assess", 300*ms, 350*ms, approved),
+	}}
+	if fr, ok := checkForbidRules(fs, early); ok || fr[0].SpanID != "p" {
+		t.Errorf("expected payment before assessment to fail, got %+v", fr)
+	}
+
+	declined := &trace.Trace{Spans: []trace.Span{
+		forbidSpan("r", "", "riskService", "assess", 100, 150, map[string]any{"risk.decision": "decline"}),
+		forbidSpan("p", "", "paymentService", "processPayment", 200, 250, nil),
+	}}
+	if _, ok := checkForbidRules(fs, declined); ok {
+		t.Error("expected anchor scope to reject declined assessment")
+	}
+}
+
+func TestForbid_AfterOrdering(t *testing.T) {
+	fs := &spec.FlowSpec{Forbid: []spec.ForbidRule{{
+		Call:    "paymentService.refund",
+		After:   &spec.ForbidAnchor{Call: "shippingService.createShipment"},
+		Message: "no refunds once shipped",
+	}}}
+	tr := &trace.Trace{Spans: []trace.Span{
+		forbidSpan("r1", "", "paymentService", "refund", 100, 150, nil),
+		forbidSpan("s", "", "shippingService", "createShipment", 200, 250, nil),
+		forbidSpan("r2", "", "paymentService", "refund", 300, 350, nil),
+	}}
+	fr, ok := checkForbidRules(fs, tr)
+	if ok || len(fr) != 1 || fr[0].SpanID != "r2" {
+		t.Fatalf("expected only the refund after shipping to fail, got %+v", fr)
+	}
+	if !strings.HasPrefix(fr[0].Message, "no refunds once shipped") {
+		t.Errorf("expected rule message, got %q", fr[0].Message)
+	}
+}
+
+func TestForbid_WhenScopeWithParent(t *testing.T) {
+	fs := &spec.FlowSpec{Forbid: []spec.ForbidRule{{
+		Name: "no-cross-service-db",
+		Call: "*",
+		When: "'db.system' in span.attributes && parent.service != '' && parent.service != span.service",
+	}}}
+	db := map[string]any{"db.system": "postgresql"}
+	tr := &trace.Trace{Spans: []trace.Span{
+		forbidSpan("a", "", "orderService", "createOrder", 100, 200, nil),
+		forbidSpan("b", "a", "orderService", "SELECT", 110, 120, db),
+		forbidSpan("c", "a", "inventoryService", "SELECT", 130, 140, map[string]any{"db.system": "postgresql"}),
+	}}
+	fr, ok := checkForbidRules(fs, tr)
+	if ok || len(fr) != 1 || fr[0].SpanID != "c" {
+		t.Fatalf("expected only the cross-service query to fail, got %+v", fr)
+	}
+}
+
+func TestLint_ForbidRules(t *testing.T) {
+	fs := &spec.FlowSpec{
+		Info: spec.FlowInfo{Title: "forbid"},
+		Flow: []spec.FlowStep{{Step: "create", Call: "orderService.createOrder"}},
+		Forbid: []spec.ForbidRule{
+			{Name: "bad-glob", Call: "inventoryV1.[x"},
+			{Name: "bad-cel", Call: "*", When: "span.service =="},
+			{Name: "no-anchor", Call: "paymentService.*", Before: &spec.ForbidAnchor{}},
+		},
+	}
+	opIndex := map[string]map[string]spec.ServiceOperation{
+		"orderService": {"createOrder": {OperationId: "createOrder"}},
+	}
+	issues, _ := LintFlow("", fs, opIndex, nil)
+	for _, want := range []string{`"bad-glob": call pattern`, `"bad-cel": when does not compile`, `"no-anchor": before is empty`} {
+		found := false
+		for _, is := range issues {
+			if is.Level == "ERROR" && strings.Contains(is.Msg, want) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected lint error containing %s, got %+v", want, issues)
+		}
+	}
+}
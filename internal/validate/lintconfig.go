@@ -0,0 +1,219 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package validate
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/choreoatlas2025/cli/internal/spec"
+)
+
+// ProjectConfigFile 是项目配置文件名，从 FlowSpec 所在目录向上查找
+const ProjectConfigFile = ".choreoatlas.yaml"
+
+// defaultInitialVars 是未配置 initialVariables 时视为已知的初始变量（通常从请求或外部输入获得）
+var defaultInitialVars = []string{"customerId", "orderItems", "totalAmount", "userId", "requestId"}
+
+// LintConfig 是项目配置中的 lint 部分
+//
+//	lint:
+//	  rules:
+//	    legacy-format: warn      # 开启默认关闭的规则
+//	    CA-LINT-001: off         # 关闭规则
+//	    telemetry-input: warn    # 调整级别
+//	  initialVariables: [tenantId, orderId]
+type LintConfig struct {
+	Rules            map[string]string `yaml:"rules"`            // 诊断码或规则名 -> error | warn | off
+	InitialVariables []string          `yaml:"initialVariables"` // 流程开始时已知的变量；未设置时使用内置默认值
+
+	root string // 配置文件所在目录，作为工作区根目录
+}
+
+type projectConfig struct {
+	Lint LintConfig `yaml:"lint"`
+}
+
+// LoadLintConfig 读取项目配置文件中的 lint 部分并校验规则名与级别
+func LoadLintConfig(path string) (*LintConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var pc projectConfig
+	if err := yaml.Unmarshal(b, &pc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for key, level := range pc.Lint.Rules {
+		if _, ok := lookupLintRule(key); !ok {
+			return nil, fmt.Errorf("%s: unknown lint rule %q", path, key)
+		}
+		switch strings.ToLower(level) {
+		case "error", "warn", "off":
+		default:
+			return nil, fmt.Errorf("%s: lint rule %s: invalid level %q (expected error, warn or off)", path, key, level)
+		}
+	}
+	pc.Lint.root = filepath.Dir(path)
+	return &pc.Lint, nil
+}
+
+// FindProjectConfig 从 flowPath 所在目录向上查找项目配置文件，
+// 到达含 .git 的目录或文件系统根目录为止；未找到时返回空串
+func FindProjectConfig(flowPath string) string {
+	dir, err := filepath.Abs(filepath.Dir(flowPath))
+	if err != nil {
+		return ""
+	}
+	for {
+		candidate := filepath.Join(dir, ProjectConfigFile)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// InitialVars 返回流程开始时已知的变量
+func (c *LintConfig) InitialVars() []string {
+	if c == nil || c.InitialVariables == nil {
+		return defaultInitialVars
+	}
+	return c.InitialVariables
+}
+
+// ruleLevel 返回规则在配置下生效的级别："" 表示沿用问题自身级别，"OFF" 表示关闭
+func (c *LintConfig) ruleLevel(code string) string {
+	rule, _ := lookupLintRule(code)
+	if c != nil {
+		for _, key := range []string{rule.Code, rule.Name} {
+			if level, ok := c.Rules[key]; key != "" && ok {
+				return strings.ToUpper(level)
+			}
+		}
+	}
+	if rule.Disabled {
+		return "OFF"
+	}
+	return ""
+}
+
+// applyLintConfig 按项目配置与 inline ignore 过滤、调整问题级别。
+// sources 中的文件从已解析的内容读取 inline ignore（编辑器中可能尚未保存），其余文件从磁盘读取。
+func applyLintConfig(flowPath string, issues []LintIssue, cfg *LintConfig, sources ...*spec.SourceMap) []LintIssue {
+	ignores := map[string]*inlineIgnores{}
+	for _, src := range sources {
+		if src != nil {
+			ignores[src.File] = parseInlineIgnores(src.Bytes())
+		}
+	}
+	ignoresFor := func(file string) *inlineIgnores {
+		if _, ok := ignores[file]; !ok {
+			b, _ := os.ReadFile(file)
+			ignores[file] = parseInlineIgnores(b)
+		}
+		return ignores[file]
+	}
+	out := issues[:0]
+	for _, is := range issues {
+		switch level := cfg.ruleLevel(is.Code); level {
+		case "OFF":
+			continue
+		case "":
+		default:
+			is.Level = level
+		}
+		file := is.Pos.File
+		if file == "" {
+			file = flowPath
+		}
+		if ignoresFor(file).covers(is.Code, is.Pos.Line) {
+			continue
+		}
+		out = append(out, is)
+	}
+	return out
+}
+
+// inline ignore 注释：
+//
+//	# choreoatlas:ignore CA-LINT-003 unknown-variable   作用于本行；独占一行时作用于下一条非注释行
+//	# choreoatlas:ignore                                不带规则时忽略该行的全部问题
+//	# choreoatlas:ignore-file legacy-format             作用于整个文件
+var ignoreDirectiveRe = regexp.MustCompile(`#\s*choreoatlas:(ignore-file|ignore)\b([^#]*)`)
+
+type inlineIgnores struct {
+	lines map[int][]string // 行号 -> 规则（"*" 表示全部）
+	file  []string
+}
+
+func parseInlineIgnores(b []byte) *inlineIgnores {
+	ig := &inlineIgnores{lines: map[int][]string{}}
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(make([]byte, 0, 64*1024), len(b)+1)
+	pending := []int(nil) // 独占一行的 ignore 注释所在行，等待下一条非注释行
+	for line := 1; sc.Scan(); line++ {
+		text := sc.Text()
+		trimmed := strings.TrimSpace(text)
+		m := ignoreDirectiveRe.FindStringSubmatch(text)
+		if m == nil {
+			if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+				for _, l := range pending {
+					ig.lines[line] = append(ig.lines[line], ig.lines[l]...)
+				}
+				pending = nil
+			}
+			continue
+		}
+		rules := strings.FieldsFunc(m[2], func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+		if len(rules) == 0 {
+			rules = []string{"*"}
+		}
+		if m[1] == "ignore-file" {
+			ig.file = append(ig.file, rules...)
+			continue
+		}
+		if strings.HasPrefix(trimmed, "#") {
+			ig.lines[line] = rules
+			pending = append(pending, line)
+			continue
+		}
+		for _, l := range pending {
+			rules = append(rules, ig.lines[l]...)
+		}
+		ig.lines[line], pending = rules, nil
+	}
+	return ig
+}
+
+// covers 报告 code 在 line 上是否被忽略
+func (ig *inlineIgnores) covers(code string, line int) bool {
+	if matchesRule(ig.file, code) {
+		return true
+	}
+	return line > 0 && matchesRule(ig.lines[line], code)
+}
+
+func matchesRule(keys []string, code string) bool {
+	rule, _ := lookupLintRule(code)
+	for _, k := range keys {
+		if k == "*" || k == code || (rule.Name != "" && k == rule.Name) {
+			return true
+		}
+	}
+	return false
+}
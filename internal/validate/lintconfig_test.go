@@ -0,0 +1,249 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package validate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/choreoatlas2025/cli/internal/spec"
+)
+
+const lintConfigFlow = `info:
+  title: ""
+services:
+  orderService:
+    spec: ./order.servicespec.yaml
+flow:
+  - step: create
+    call: orderService.createOrder
+    input:
+      tenant: ${tenantId}
+`
+
+func lintWithConfig(t *testing.T, flowYAML string, cfg *LintConfig) []LintIssue {
+	t.Helper()
+	flowPath, fs := loadDiagnosticsFlow(t, flowYAML)
+	_, opIndex, err := fs.BuildOperationIndex(flowPath)
+	if err != nil {
+		t.Fatalf("build op index: %v", err)
+	}
+	issues, err := LintFlow(flowPath, fs, opIndex, cfg)
+	if err != nil {
+		t.Fatalf("lint: %v", err)
+	}
+	return issues
+}
+
+func issueLevels(issues []LintIssue) map[string]string {
+	levels := map[string]string{}
+	for _, is := range issues {
+		levels[is.Code] = is.Level
+	}
+	return levels
+}
+
+func TestLintConfig_RuleLevels(t *testing.T) {
+	levels := issueLevels(lintWithConfig(t, lintConfigFlow, nil))
+	if levels[CodeEmptyTitle] != "WARN" || levels[CodeUnknownVariable] != "ERROR" {
+		t.Errorf("unexpected default levels: %v", levels)
+	}
+	for _, code := range []string{CodeLegacyFormat, CodeMissingVersion} {
+		if _, ok := levels[code]; ok {
+			t.Errorf("%s should be disabled by default: %v", code, levels)
+		}
+	}
+
+	cfg := &LintConfig{Rules: map[string]string{
+		"legacy-format":    "warn",
+		"CA-LINT-001":      "off",
+		"unknown-variable": "warn",
+	}}
+	levels = issueLevels(lintWithConfig(t, lintConfigFlow, cfg))
+	if levels[CodeLegacyFormat] != "WARN" || levels[CodeUnknownVariable] != "WARN" {
+		t.Errorf("expected configured levels, got %v", levels)
+	}
+	if _, ok := levels[CodeEmptyTitle]; ok {
+		t.Errorf("CA-LINT-001 should be off: %v", levels)
+	}
+}
+
+func TestLintRules_LevelsFromRegistry(t *testing.T) {
+	issues := lintWithConfig(t, `info:
+  title: order
+services:
+  orderService:
+    spec: ./order.servicespec.yaml
+allowUnmatched: ["healthCheck"]
+flow:
+  - step: create
+    call: orderService.createOrder
+    destination: orders
+    match: {}
+    compensate: cancel
+  - step: notify
+    call: orderService.createOrder
+    kind: broadcast
+  - step: cancel
+    call: orderService.cancelOrder
+    compensate: create
+`, nil)
+	got := issueLevels(issues)
+	for _, code := range []string{CodeStepDestination, CodeEmptyMatcher, CodeAllowServicePart, CodeNestedCompensate, CodeStepKind} {
+		rule, _ := lookupLintRule(code)
+		if got[code] != rule.Level {
+			t.Errorf("%s: level %q, want registry level %q (issues: %v)", code, got[code], rule.Level, issues)
+		}
+	}
+	for _, is := range issues {
+		if rule, _ := lookupLintRule(is.Code); is.Level != rule.Level {
+			t.Errorf("%s reported at %s, registry says %s", is.Code, is.Level, rule.Level)
+		}
+	}
+}
+
+func TestLintConfig_InitialVariables(t *testing.T) {
+	cfg := &LintConfig{InitialVariables: []string{"tenantId"}}
+	if _, ok := issueLevels(lintWithConfig(t, lintConfigFlow, cfg))[CodeUnknownVariable]; ok {
+		t.Error("declared initial variable should be known")
+	}
+	flow := strings.Replace(lintConfigFlow, "${tenantId}", "${customerId}", 1)
+	if _, ok := issueLevels(lintWithConfig(t, flow, nil))[CodeUnknownVariable]; ok {
+		t.Error("built-in initial variable should be known without config")
+	}
+	if _, ok := issueLevels(lintWithConfig(t, flow, cfg))[CodeUnknownVariable]; !ok {
+		t.Error("configured initial variables should replace the built-in ones")
+	}
+}
+
+func TestLintConfig_InlineIgnores(t *testing.T) {
+	tests := []struct {
+		name    string
+		flow    string
+		ignored []string
+		kept    []string
+	}{
+		{
+			name:    "comment above line",
+			flow:    strings.Replace(lintConfigFlow, "    input:\n", "    # choreoatlas:ignore unknown-variable\n    input:\n", 1),
+			ignored: []string{CodeUnknownVariable},
+			kept:    []string{CodeEmptyTitle},
+		},
+		{
+			name:    "trailing comment",
+			flow:    strings.Replace(lintConfigFlow, `title: ""`, `title: "" # choreoatlas:ignore CA-LINT-001`, 1),
+			ignored: []string{CodeEmptyTitle},
+			kept:    []string{CodeUnknownVariable},
+		},
+		{
+			name: "other rule on line",
+			flow: strings.Replace(lintConfigFlow, "    input:\n", "    input: # choreoatlas:ignore CA-LINT-001\n", 1),
+			kept: []string{CodeUnknownVariable, CodeEmptyTitle},
+		},
+		{
+			name:    "whole file",
+			flow:    "# choreoatlas:ignore-file CA-LINT-001, unknown-variable\n" + lintConfigFlow,
+			ignored: []string{CodeEmptyTitle, CodeUnknownVariable},
+		},
+		{
+			name:    "all rules on line",
+			flow:    strings.Replace(lintConfigFlow, "    input:\n", "    # choreoatlas:ignore\n\n    input:\n", 1),
+			ignored: []string{CodeUnknownVariable},
+			kept:    []string{CodeEmptyTitle},
+		},
+	}
+	for _, tt := range tests {
+		levels := issueLevels(lintWithConfig(t, tt.flow, nil))
+		for _, code := range tt.ignored {
+			if _, ok := levels[code]; ok {
+				t.Errorf("%s: %s should be ignored, got %v", tt.name, code, levels)
+			}
+		}
+		for _, code := range tt.kept {
+			if _, ok := levels[code]; !ok {
+				t.Errorf("%s: %s should be reported, got %v", tt.name, code, levels)
+			}
+		}
+	}
+}
+
+func TestLintConfig_InlineIgnoresFromUnsavedSource(t *testing.T) {
+	flowPath, _ := loadDiagnosticsFlow(t, lintConfigFlow)
+	// 编辑器中的内容尚未保存：ignore 注释只存在于内存中
+	edited := strings.Replace(lintConfigFlow, `title: ""`, `title: "" # choreoatlas:ignore`, 1)
+	fs, err := spec.ParseFlowSpec(flowPath, []byte(edited))
+	if err != nil {
+		t.Fatalf("parse flow: %v", err)
+	}
+	_, opIndex, err := fs.BuildOperationIndex(flowPath)
+	if err != nil {
+		t.Fatalf("build op index: %v", err)
+	}
+	issues, err := LintFlow(flowPath, fs, opIndex, nil)
+	if err != nil {
+		t.Fatalf("lint: %v", err)
+	}
+	if _, ok := issueLevels(issues)[CodeEmptyTitle]; ok {
+		t.Errorf("ignore comment in the unsaved source should apply, got %v", issues)
+	}
+}
+
+func TestLintServiceSpec(t *testing.T) {
+	data := []byte(`service: orderService
+operations:
+  - operationId: createOrder
+    postconditions:
+      ok: "response.status == 201"
+      broken: "response.status =="
+      ignored: "response.status ==" # choreoatlas:ignore invalid-condition
+`)
+	issues, err := LintServiceSpec("order.servicespec.yaml", data, nil)
+	if err != nil {
+		t.Fatalf("LintServiceSpec: %v", err)
+	}
+	if len(issues) != 1 || issues[0].Code != CodeInvalidCondition || issues[0].Pos.Line != 6 {
+		t.Fatalf("expected one invalid-condition issue on line 6, got %v", issues)
+	}
+	if issues[0].Pos.File != "order.servicespec.yaml" {
+		t.Errorf("file = %q", issues[0].Pos.File)
+	}
+	if _, err := LintServiceSpec("bad.servicespec.yaml", []byte("operations: ["), nil); err == nil {
+		t.Error("expected parse error")
+	}
+}
+
+func TestLoadLintConfig(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "flows", "orders")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cfgPath := filepath.Join(root, ProjectConfigFile)
+	if err := os.WriteFile(cfgPath, []byte("lint:\n  rules:\n    legacy-format: warn\n  initialVariables: [tenantId]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := FindProjectConfig(filepath.Join(nested, "order.flowspec.yaml")); got != cfgPath {
+		t.Fatalf("FindProjectConfig = %q, want %q", got, cfgPath)
+	}
+	cfg, err := LoadLintConfig(cfgPath)
+	if err != nil {
+		t.Fatalf("LoadLintConfig: %v", err)
+	}
+	if cfg.Rules["legacy-format"] != "warn" || len(cfg.InitialVariables) != 1 {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+
+	for _, bad := range []string{
+		"lint:\n  rules:\n    no-such-rule: warn\n",
+		"lint:\n  rules:\n    CA-LINT-003: fatal\n",
+	} {
+		if err := os.WriteFile(cfgPath, []byte(bad), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadLintConfig(cfgPath); err == nil {
+			t.Errorf("expected error for config %q", bad)
+		}
+	}
+}
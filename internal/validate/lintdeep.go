@@ -0,0 +1,342 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package validate
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/choreoatlas2025/cli/internal/spec"
+)
+
+// stepCall 是流程中一个带 call 的步骤或节点
+type stepCall struct {
+	name, call string
+}
+
+// flowCalls 按声明顺序列出全部步骤（含 parallel 子步骤）或节点的调用
+func flowCalls(fs *spec.FlowSpec) []stepCall {
+	var out []stepCall
+	if fs.IsGraphMode() {
+		for _, n := range fs.Graph.Nodes {
+			out = append(out, stepCall{n.ID, n.Call})
+		}
+		return out
+	}
+	for _, st := range fs.Flow {
+		if st.Call != "" {
+			out = append(out, stepCall{st.Step, st.Call})
+		}
+		for _, pst := range st.Parallel {
+			out = append(out, stepCall{pst.Step, pst.Call})
+		}
+	}
+	return out
+}
+
+// lintDeadNodes 报告与入口节点不连通的 graph 节点。
+// 连通关系包括 depends/edges、compensate 链接以及同一 destination 的 publish→consume。
+func lintDeadNodes(fs *spec.FlowSpec) []LintIssue {
+	g := fs.Graph
+	if g == nil || len(g.Nodes) < 2 {
+		return nil
+	}
+	g.EnsureEdges()
+	adj := map[string][]string{}
+	link := func(a, b string) {
+		adj[a] = append(adj[a], b)
+		adj[b] = append(adj[b], a)
+	}
+	inDegree := map[string]int{}
+	for _, e := range g.Edges {
+		link(e.From, e.To)
+		inDegree[e.To]++
+	}
+	publishers := map[string][]string{}
+	for _, n := range g.Nodes {
+		if n.Compensate != "" {
+			link(n.ID, n.Compensate)
+		}
+		if n.Kind == spec.StepKindPublish && n.Destination != "" {
+			publishers[n.Destination] = append(publishers[n.Destination], n.ID)
+		}
+	}
+	for _, n := range g.Nodes {
+		if n.Kind == spec.StepKindConsume {
+			for _, p := range publishers[n.Destination] {
+				link(p, n.ID)
+			}
+		}
+	}
+
+	// 第一个入口节点视为流程起点
+	root := ""
+	for _, n := range g.Nodes {
+		if inDegree[n.ID] == 0 {
+			root = n.ID
+			break
+		}
+	}
+	if root == "" {
+		return nil // 有环，由 graph-structure 报告
+	}
+	seen := map[string]bool{root: true}
+	queue := []string{root}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, next := range adj[cur] {
+			if !seen[next] {
+				seen[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	var issues []LintIssue
+	for _, n := range g.Nodes {
+		if !seen[n.ID] {
+			issues = append(issues, newIssue(CodeDeadNode, "node=%s is unreachable from root node %s", n.ID, root).
+				at(fs.Source.Step(n.ID)).about(n.ID).
+				withHint("add depends to connect %s to the flow, or remove it", n.ID))
+		}
+	}
+	return issues
+}
+
+// lintUnusedOutputs 报告没有被后续步骤（graph 中为任意节点）引用的 output 变量。
+// 最后一步（graph 中无后继的节点）的输出视为流程结果，不做检查。
+func lintUnusedOutputs(fs *spec.FlowSpec) []LintIssue {
+	type producer struct {
+		name    string
+		outputs map[string]string
+		used    map[string]bool // 后续步骤引用的根变量
+	}
+	kind := "step"
+	var producers []producer
+	if fs.IsGraphMode() {
+		kind = "node"
+		fs.Graph.EnsureEdges()
+		hasSuccessor := map[string]bool{}
+		for _, e := range fs.Graph.Edges {
+			hasSuccessor[e.From] = true
+		}
+		used := map[string]bool{}
+		for _, n := range fs.Graph.Nodes {
+			for _, v := range collectVarRefs(n.Input) {
+				used[strings.SplitN(v, ".", 2)[0]] = true
+			}
+		}
+		for _, n := range fs.Graph.Nodes {
+			if hasSuccessor[n.ID] {
+				producers = append(producers, producer{n.ID, n.Output, used})
+			}
+		}
+	} else {
+		// 补偿步骤只在失败时执行，不参与"最后一步"的判断，也不检查其输出
+		compensation := map[string]bool{}
+		for _, target := range fs.Compensations() {
+			compensation[target] = true
+		}
+		last := len(fs.Flow) - 1
+		for last > 0 && compensation[fs.Flow[last].Step] {
+			last--
+		}
+		// 从后往前累积引用，使每个步骤只看到其后的步骤
+		later := map[string]bool{}
+		for i := len(fs.Flow) - 1; i >= 0; i-- {
+			st := fs.Flow[i]
+			group := st.Parallel
+			if len(group) == 0 {
+				group = []spec.FlowStep{st}
+			}
+			snapshot := make(map[string]bool, len(later))
+			for k := range later {
+				snapshot[k] = true
+			}
+			for j := len(group) - 1; j >= 0 && i < last; j-- {
+				if !compensation[group[j].Step] {
+					producers = append(producers, producer{group[j].Step, group[j].Output, snapshot})
+				}
+			}
+			for _, s := range group {
+				for _, v := range collectVarRefs(s.Input) {
+					later[strings.SplitN(v, ".", 2)[0]] = true
+				}
+			}
+		}
+		// 恢复声明顺序
+		for i, j := 0, len(producers)-1; i < j; i, j = i+1, j-1 {
+			producers[i], producers[j] = producers[j], producers[i]
+		}
+	}
+
+	var issues []LintIssue
+	for _, p := range producers {
+		for _, out := range sortedKeys(p.outputs) {
+			if !p.used[out] {
+				issues = append(issues, newIssue(CodeUnusedOutput, "%s=%s output %q is not used by any later %s", kind, p.name, out, kind).
+					at(fs.Source.Step(p.name, "output", out)).about(p.name).
+					withHint("reference it as ${%s} in a later input, or remove the output", out))
+			}
+		}
+	}
+	return issues
+}
+
+// lintUnusedBindings 报告没有步骤调用的 services 绑定
+func lintUnusedBindings(fs *spec.FlowSpec) []LintIssue {
+	used := map[string]bool{}
+	for _, c := range flowCalls(fs) {
+		if svc, _, err := splitCall(c.call); err == nil {
+			used[svc] = true
+		}
+	}
+	var issues []LintIssue
+	for _, alias := range sortedKeys(fs.Services) {
+		if !used[alias] {
+			issues = append(issues, newIssue(CodeUnusedBinding, "service %s is declared but no step calls it", alias).
+				at(fs.Source.Lookup("services", alias)).withHint("remove the %s binding or add a step that calls it", alias))
+		}
+	}
+	return issues
+}
+
+// lintServiceAliases 报告调用的服务别名与所绑定 ServiceSpec 的 service 字段不一致的步骤；
+// span 按别名匹配服务名，两者不一致时步骤通常无法匹配
+func lintServiceAliases(fs *spec.FlowSpec, flowPath string) []LintIssue {
+	names := map[string]string{}
+	for alias, bind := range fs.Services {
+		if ss, err := spec.LoadServiceSpec(spec.ResolvePath(flowPath, bind.Spec)); err == nil && ss.Service != "" {
+			names[alias] = ss.Service
+		}
+	}
+	var issues []LintIssue
+	for _, c := range flowCalls(fs) {
+		svc, _, err := splitCall(c.call)
+		if err != nil {
+			continue
+		}
+		if name, ok := names[svc]; ok && looseName(name) != looseName(svc) {
+			issues = append(issues, newIssue(CodeServiceAlias, "step=%s calls %s but the bound ServiceSpec declares service %s", c.name, svc, name).
+				at(fs.Source.Step(c.name, "call")).about(c.name).
+				withHint("spans are matched by the alias; rename the alias to %s or fix service in the ServiceSpec", name))
+		}
+	}
+	return issues
+}
+
+// looseName 只保留字母数字并转小写（"Payment Service" 与 paymentService 视为一致）
+func looseName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
+
+// lintUnusedOperations 报告本流程绑定的 ServiceSpec 中，工作区内没有任何流程引用的操作
+func lintUnusedOperations(fs *spec.FlowSpec, flowPath string, cfg *LintConfig) []LintIssue {
+	if flowPath == "" {
+		return nil
+	}
+	self, err := filepath.Abs(flowPath)
+	if err != nil {
+		return nil
+	}
+	used := map[string]bool{} // ServiceSpec 绝对路径 + "#" + operationId
+	addRefs := func(f *spec.FlowSpec, path string) {
+		for _, c := range flowCalls(f) {
+			svc, op, err := splitCall(c.call)
+			if err != nil {
+				continue
+			}
+			if bind, ok := f.Services[svc]; ok {
+				if p, err := filepath.Abs(spec.ResolvePath(path, bind.Spec)); err == nil {
+					used[p+"#"+op] = true
+				}
+			}
+		}
+	}
+	addRefs(fs, self)
+	for _, path := range workspaceFlows(workspaceRoot(self, cfg)) {
+		if path == self {
+			continue
+		}
+		if other, err := spec.LoadFlowSpec(path); err == nil {
+			addRefs(other, path)
+		}
+	}
+
+	var issues []LintIssue
+	checked := map[string]bool{}
+	for _, alias := range sortedKeys(fs.Services) {
+		specPath, err := filepath.Abs(spec.ResolvePath(self, fs.Services[alias].Spec))
+		if err != nil || checked[specPath] {
+			continue
+		}
+		checked[specPath] = true
+		data, err := os.ReadFile(specPath)
+		if err != nil {
+			continue
+		}
+		ss, err := spec.LoadServiceSpec(specPath)
+		if err != nil {
+			continue
+		}
+		src, _ := spec.NewSourceMap(spec.ResolvePath(flowPath, fs.Services[alias].Spec), data)
+		for i, op := range ss.Operations {
+			if !used[specPath+"#"+op.OperationId] {
+				issues = append(issues, newIssue(CodeUnusedOperation, "service=%s operation %s is not referenced by any flow in the workspace", alias, op.OperationId).
+					at(src.Lookup("operations", i, "operationId")).
+					withHint("remove the operation from the ServiceSpec or add a step that calls %s.%s", alias, op.OperationId))
+			}
+		}
+	}
+	return issues
+}
+
+// workspaceRoot 是查找其他流程的根目录：项目配置所在目录，否则为仓库根目录，再否则为流程所在目录
+func workspaceRoot(flowPath string, cfg *LintConfig) string {
+	if cfg != nil && cfg.root != "" {
+		return cfg.root
+	}
+	dir := filepath.Dir(flowPath)
+	for d := dir; ; {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			return d
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return dir
+		}
+		d = parent
+	}
+}
+
+// workspaceFlows 列出 root 下的 *.flowspec.yaml / *.flowspec.yml（跳过隐藏目录与依赖目录）
+func workspaceFlows(root string) []string {
+	var out []string
+	_ = filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != root && (strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(d.Name(), ".flowspec.yaml") || strings.HasSuffix(d.Name(), ".flowspec.yml") {
+			out = append(out, path)
+		}
+		return nil
+	})
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,164 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package validate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/choreoatlas2025/cli/internal/spec"
+)
+
+func lintFile(t *testing.T, flowPath string) []LintIssue {
+	t.Helper()
+	fs, err := spec.LoadFlowSpec(flowPath)
+	if err != nil {
+		t.Fatalf("load flow: %v", err)
+	}
+	_, opIndex, err := fs.BuildOperationIndex(flowPath)
+	if err != nil {
+		t.Fatalf("build op index: %v", err)
+	}
+	issues, err := LintFlow(flowPath, fs, opIndex, nil)
+	if err != nil {
+		t.Fatalf("lint: %v", err)
+	}
+	return issues
+}
+
+func issuesWithCode(issues []LintIssue, code string) []LintIssue {
+	var out []LintIssue
+	for _, is := range issues {
+		if is.Code == code {
+			out = append(out, is)
+		}
+	}
+	return out
+}
+
+func TestLintDeep_FlowRules(t *testing.T) {
+	issues := lintWithConfig(t, `info:
+  title: deep
+services:
+  orderService:
+    spec: ./order.servicespec.yaml
+  audit:
+    spec: ./order.servicespec.yaml
+flow:
+  - step: create
+    call: orderService.createOrder
+    output:
+      orderId: response.body.id
+      draft: response.body.draft
+  - parallel:
+      - step: fetchA
+        call: orderService.getOrder
+        input:
+          id: ${orderId}
+        output:
+          snapshot: response.body
+      - step: fetchB
+        call: orderService.getOrder
+        input:
+          ref: ${snapshot.id}
+  - step: done
+    call: orderService.getOrder
+    input:
+      id: ${orderId}
+    output:
+      final: response.body
+`, nil)
+
+	unused := issuesWithCode(issues, CodeUnusedOutput)
+	var drafts []string
+	for _, is := range unused {
+		if strings.Contains(is.Msg, `"final"`) {
+			t.Errorf("outputs of the last step should not be reported: %s", is)
+		}
+		if strings.Contains(is.Msg, `"draft"`) {
+			drafts = append(drafts, is.Ref)
+		}
+	}
+	if len(drafts) != 1 || drafts[0] != "create" {
+		t.Errorf("expected unused output draft on create, got %+v", unused)
+	}
+
+	par := issuesWithCode(issues, CodeParallelDependency)
+	if len(par) != 1 || par[0].Ref != "fetchB" || par[0].Level != "ERROR" || !strings.Contains(par[0].Msg, "fetchA") {
+		t.Errorf("expected parallel dependency of fetchB on fetchA, got %+v", par)
+	}
+	if len(issuesWithCode(issues, CodeUnknownVariable)) != 0 {
+		t.Errorf("sibling reference should not also be an unknown variable: %+v", issues)
+	}
+
+	bindings := issuesWithCode(issues, CodeUnusedBinding)
+	if len(bindings) != 1 || !strings.Contains(bindings[0].Msg, "audit") || bindings[0].Pos.Line != 6 {
+		t.Errorf("expected unused binding audit at line 6, got %+v", bindings)
+	}
+	if n := len(issuesWithCode(issues, CodeUnusedOperation)); n != 0 {
+		t.Errorf("all operations are called, got %d unused-operation issues", n)
+	}
+}
+
+const deepGraphFlow = `info:
+  title: deep
+services:
+  orders:
+    spec: ./order.servicespec.yaml
+graph:
+  nodes:
+    - id: create
+      call: orders.createOrder
+      output:
+        orderId: response.body.id
+    - id: fetch
+      call: orders.createOrder
+      depends: [create]
+      input:
+        id: ${orderId}
+        ref: ${other}
+    - id: side
+      call: orders.createOrder
+      depends: [create]
+      output:
+        other: response.body
+    - id: orphan
+      call: orders.createOrder
+`
+
+func TestLintDeep_GraphRules(t *testing.T) {
+	flowPath, _ := loadDiagnosticsFlow(t, deepGraphFlow)
+	issues := lintFile(t, flowPath)
+
+	dead := issuesWithCode(issues, CodeDeadNode)
+	if len(dead) != 1 || dead[0].Ref != "orphan" || dead[0].Pos.Line != 23 {
+		t.Errorf("expected orphan to be unreachable at line 23, got %+v", dead)
+	}
+	par := issuesWithCode(issues, CodeParallelDependency)
+	if len(par) != 1 || par[0].Ref != "fetch" || !strings.Contains(par[0].Msg, "side") {
+		t.Errorf("expected parallel dependency of fetch on side, got %+v", par)
+	}
+	if n := len(issuesWithCode(issues, CodeServiceAlias)); n != 4 {
+		t.Errorf("expected alias mismatch on each of 4 nodes, got %d", n)
+	}
+	if n := len(issuesWithCode(issues, CodeUnusedOutput)); n != 0 {
+		t.Errorf("used and sink outputs should not be reported, got %+v", issuesWithCode(issues, CodeUnusedOutput))
+	}
+
+	ops := issuesWithCode(issues, CodeUnusedOperation)
+	if len(ops) != 1 || !strings.Contains(ops[0].Msg, "getOrder") ||
+		!strings.HasSuffix(ops[0].Pos.File, "order.servicespec.yaml") || ops[0].Pos.Line != 4 {
+		t.Fatalf("expected getOrder to be unused at order.servicespec.yaml:4, got %+v", ops)
+	}
+
+	// 工作区中另一个流程引用了 getOrder
+	other := filepath.Join(filepath.Dir(flowPath), "lookup.flowspec.yaml")
+	if err := os.WriteFile(other, []byte("info:\n  title: lookup\nservices:\n  orderService:\n    spec: ./order.servicespec.yaml\nflow:\n  - step: get\n    call: orderService.getOrder\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if ops := issuesWithCode(lintFile(t, flowPath), CodeUnusedOperation); len(ops) != 0 {
+		t.Errorf("getOrder is referenced by another flow, got %+v", ops)
+	}
+}
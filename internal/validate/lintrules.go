@@ -0,0 +1,59 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package validate
+
+// LintRule 描述一条静态检查规则。规则按诊断码登记，登记表决定问题的默认级别
+// 与是否默认开启；项目配置可以调整二者。
+type LintRule struct {
+	Code        string
+	Name        string // 配置与 inline ignore 中可替代 Code 使用的名字
+	Level       string // 默认级别："ERROR" | "WARN"
+	Disabled    bool   // 默认关闭，在项目配置中设为 error / warn 时开启
+	Description string
+}
+
+// lintRules 是全部静态检查规则的登记表，按诊断码排序
+var lintRules = []LintRule{
+	{Code: CodeEmptyTitle, Name: "empty-title", Level: "WARN", Description: "info.title is empty"},
+	{Code: CodeFlowFormat, Name: "flow-format", Level: "ERROR", Description: "flow/graph is missing, empty, or both are set"},
+	{Code: CodeUnknownVariable, Name: "unknown-variable", Level: "ERROR", Description: "input references a variable no preceding step outputs"},
+	{Code: CodeLegacyFormat, Name: "legacy-format", Level: "WARN", Disabled: true, Description: "flow uses the sequential format instead of graph (DAG)"},
+	{Code: CodeStepName, Name: "step-name", Level: "ERROR", Description: "step name is missing or duplicated"},
+	{Code: CodeInvalidCall, Name: "invalid-call", Level: "ERROR", Description: "call is not in the form serviceAlias.operationId"},
+	{Code: CodeUndeclaredService, Name: "undeclared-service", Level: "ERROR", Description: "call references a service not declared under services"},
+	{Code: CodeUnknownOperation, Name: "unknown-operation", Level: "ERROR", Description: "call references an operation missing from the ServiceSpec"},
+	{Code: CodeTelemetryInput, Name: "telemetry-input", Level: "ERROR", Description: "input contains http.* / otel.* / span.* telemetry keys"},
+	{Code: CodeStepKind, Name: "step-kind", Level: "ERROR", Description: "step kind is not call, publish or consume"},
+	{Code: CodeInvalidMatcher, Name: "invalid-matcher", Level: "ERROR", Description: "match regex or cel does not compile"},
+	{Code: CodeInvalidCompensate, Name: "invalid-compensate", Level: "ERROR", Description: "compensate references itself, a missing step or a step without call"},
+	{Code: CodeInvalidAllow, Name: "invalid-allow", Level: "ERROR", Description: "allowUnmatched pattern is invalid"},
+	{Code: CodeInvalidForbid, Name: "invalid-forbid", Level: "ERROR", Description: "forbid rule pattern or CEL scope is invalid"},
+	{Code: CodeInvalidCondition, Name: "invalid-condition", Level: "ERROR", Description: "ServiceSpec pre/postcondition does not compile or type-check"},
+	{Code: CodeGraphStructure, Name: "graph-structure", Level: "ERROR", Description: "graph has cycles, dangling edges or duplicate node IDs"},
+	{Code: CodeDeadNode, Name: "dead-node", Level: "WARN", Description: "graph node is not connected to the root node"},
+	{Code: CodeUnusedOutput, Name: "unused-output", Level: "WARN", Description: "output variable is not used by any later step"},
+	{Code: CodeUnusedBinding, Name: "unused-binding", Level: "WARN", Description: "services binding is not called by any step"},
+	{Code: CodeUnusedOperation, Name: "unused-operation", Level: "WARN", Description: "ServiceSpec operation is not referenced by any flow in the workspace"},
+	{Code: CodeServiceAlias, Name: "service-alias", Level: "WARN", Description: "call alias differs from the bound ServiceSpec's service name"},
+	{Code: CodeParallelDependency, Name: "parallel-dependency", Level: "ERROR", Description: "parallel steps consume each other's outputs"},
+	{Code: CodeMissingVersion, Name: "missing-version", Level: "WARN", Disabled: true, Description: "info.version is not set"},
+	{Code: CodeStepDestination, Name: "step-destination", Level: "WARN", Description: "destination is set on a call step, or missing on a publish/consume step"},
+	{Code: CodeEmptyMatcher, Name: "empty-matcher", Level: "WARN", Description: "match block is empty, so any span of the service matches"},
+	{Code: CodeAllowServicePart, Name: "allow-service-part", Level: "WARN", Description: "allowUnmatched pattern has no service part"},
+	{Code: CodeNestedCompensate, Name: "nested-compensate", Level: "WARN", Description: "compensation step declares its own compensation, which is never triggered"},
+}
+
+// LintRules 返回全部静态检查规则
+func LintRules() []LintRule {
+	return append([]LintRule(nil), lintRules...)
+}
+
+// lookupLintRule 按诊断码或规则名查找规则
+func lookupLintRule(key string) (LintRule, bool) {
+	for _, r := range lintRules {
+		if r.Code == key || r.Name == key {
+			return r, true
+		}
+	}
+	return LintRule{}, false
+}
@@ -0,0 +1,62 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package validate
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lruCache 是并发安全、容量固定的 LRU 缓存。长期运行的进程（choreoatlas lsp）
+// 会不断编译编辑中的表达式，容量上限防止缓存无限增长。
+type lruCache[V any] struct {
+	mu    sync.Mutex
+	max   int
+	order *list.List               // 最近使用的在前
+	items map[string]*list.Element // key -> *lruEntry
+}
+
+type lruEntry[V any] struct {
+	key   string
+	value V
+}
+
+func newLRUCache[V any](max int) *lruCache[V] {
+	return &lruCache[V]{max: max, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+// Get 返回缓存的值并将其标记为最近使用
+func (c *lruCache[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		c.order.MoveToFront(e)
+		return e.Value.(*lruEntry[V]).value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Add 存入 key 对应的值；key 已存在时返回已有的值，超出容量时淘汰最久未使用的条目
+func (c *lruCache[V]) Add(key string, value V) V {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.items[key]; ok {
+		c.order.MoveToFront(e)
+		return e.Value.(*lruEntry[V]).value
+	}
+	c.items[key] = c.order.PushFront(&lruEntry[V]{key: key, value: value})
+	for c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry[V]).key)
+	}
+	return value
+}
+
+// Len 返回缓存的条目数
+func (c *lruCache[V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,169 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package validate
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/choreoatlas2025/cli/internal/spec"
+	"github.com/choreoatlas2025/cli/internal/trace"
+)
+
+// matchStep reports whether a span satisfies a step, together with a short
+// human readable reason. The service alias must always match; then
+//   - a `match:` block compares span attributes (and optionally a CEL predicate),
+//   - publish/consume steps compare the messaging role and destination,
+//   - call steps compare the operation with the span name or the operation
+//     ID that `discover` computes for the span.
+func matchStep(st spec.FlowStep, sp trace.Span) (bool, string) {
+	svc, op, err := splitCall(st.Call)
+	if err != nil {
+		return false, ""
+	}
+	if normalize(sp.Service) != normalize(svc) {
+		return false, ""
+	}
+	if st.IsMessaging() {
+		if sp.MessagingRole() != st.Kind {
+			return false, ""
+		}
+		if st.Destination != "" && sp.MessagingDestination() != st.Destination {
+			return false, ""
+		}
+		if st.Match == nil {
+			return true, fmt.Sprintf("messaging %s %s", st.Kind, sp.MessagingDestination())
+		}
+	}
+	if st.Match != nil {
+		return matchAttributes(st.Match, sp)
+	}
+	if normalize(sp.Name) == normalize(op) {
+		return true, "span name"
+	}
+	// Specs produced by `discover` name operations by their computed ID
+	// (e.g. "POST /orders/{id}" -> postOrdersById)
+	if normalize(spec.ComputeOperationID(sp)) == normalize(op) {
+		return true, "operation id"
+	}
+	return false, ""
+}
+
+// matchAttributes evaluates a `match:` block against a span
+func matchAttributes(m *spec.SpanMatcher, sp trace.Span) (bool, string) {
+	var reasons []string
+	check := func(key, want string, equal func(got, want string) bool) bool {
+		got := attrString(sp, key)
+		if got == "" || !equal(got, want) {
+			return false
+		}
+		reasons = append(reasons, fmt.Sprintf("%s=%s", key, want))
+		return true
+	}
+	exact := func(got, want string) bool { return got == want }
+
+	if m.HTTPMethod != "" {
+		// Semconv 1.21+ records http.request.method; older instrumentations http.method
+		key := "http.request.method"
+		if attrString(sp, key) == "" {
+			key = "http.method"
+		}
+		if !check(key, m.HTTPMethod, strings.EqualFold) {
+			return false, ""
+		}
+	}
+	if m.HTTPRoute != "" {
+		// Older instrumentations only record http.target (path plus query)
+		key := "http.route"
+		if attrString(sp, key) == "" {
+			key = "http.target"
+		}
+		routeEqual := func(got, want string) bool {
+			if i := strings.IndexByte(got, '?'); i >= 0 {
+				got = got[:i]
+			}
+			return got == want
+		}
+		if !check(key, m.HTTPRoute, routeEqual) {
+			return false, ""
+		}
+	}
+	if m.RPCMethod != "" && !check("rpc.method", m.RPCMethod, exact) {
+		return false, ""
+	}
+	for _, key := range sortedKeys(m.Attributes) {
+		if !check(key, m.Attributes[key], exact) {
+			return false, ""
+		}
+	}
+	for _, key := range sortedKeys(m.Regex) {
+		re, err := cachedRegexp(m.Regex[key])
+		if err != nil {
+			return false, ""
+		}
+		got := attrString(sp, key)
+		if got == "" || !re.MatchString(got) {
+			return false, ""
+		}
+		reasons = append(reasons, fmt.Sprintf("%s=~/%s/", key, m.Regex[key]))
+	}
+	if m.CEL != "" {
+		ok, _, err := evalCELBool(m.CEL, spanEnv(sp))
+		if err != nil || !ok {
+			return false, ""
+		}
+		reasons = append(reasons, fmt.Sprintf("cel(%s)", m.CEL))
+	}
+	return true, "match " + strings.Join(reasons, ", ")
+}
+
+// attrString renders a span attribute as a string; missing attributes yield ""
+func attrString(sp trace.Span, key string) string {
+	v, ok := sp.Attributes[key]
+	if !ok || v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+var regexCache sync.Map // pattern -> *regexp.Regexp
+
+func cachedRegexp(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexCache.Store(pattern, re)
+	return re, nil
+}
+
+// nodeSpan rebuilds the span view of a call graph node for matching
+func nodeSpan(n *CallNode) trace.Span {
+	return trace.Span{
+		Name:       n.Operation,
+		Service:    n.Service,
+		StartNanos: n.StartNanos,
+		EndNanos:   n.EndNanos,
+		Attributes: n.Attributes,
+		Kind:       n.Kind,
+		Links:      n.Links,
+	}
+}
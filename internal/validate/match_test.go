@@ -0,0 +1,144 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package validate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/choreoatlas2025/cli/internal/spec"
+	"github.com/choreoatlas2025/cli/internal/trace"
+)
+
+func httpSpan(service, method, route string, start int64) trace.Span {
+	return trace.Span{
+		Service:    service,
+		Name:       "HTTP " + method,
+		StartNanos: start,
+		EndNanos:   start + 50,
+		Attributes: map[string]any{"http.method": method, "http.route": route, "http.status_code": 200},
+	}
+}
+
+func TestMatchStep_GenericSpanNamesByRoute(t *testing.T) {
+	fs := &spec.FlowSpec{
+		Flow: []spec.FlowStep{
+			{Step: "createOrder", Call: "orderService.createOrder",
+				Match: &spec.SpanMatcher{HTTPMethod: "post", HTTPRoute: "/orders"}},
+			{Step: "confirmOrder", Call: "orderService.confirmOrder",
+				Match: &spec.SpanMatcher{HTTPMethod: "POST", HTTPRoute: "/orders/{id}/confirm"}},
+		},
+	}
+	tr := &trace.Trace{Spans: []trace.Span{
+		httpSpan("orderService", "GET", "/orders", 50),
+		httpSpan("orderService", "POST", "/orders", 100),
+		httpSpan("orderService", "POST", "/orders/{id}/confirm", 200),
+	}}
+
+	results, ok := ValidateAgainstTrace(fs, nil, tr)
+	if !ok {
+		t.Fatalf("expected attribute matchers to pass, got %+v", results)
+	}
+	if got := results[0].MatchReason; !strings.Contains(got, "http.method=post") || !strings.Contains(got, "http.route=/orders") {
+		t.Errorf("unexpected match reason: %q", got)
+	}
+}
+
+func TestMatchStep_HTTPRequestMethod(t *testing.T) {
+	st := spec.FlowStep{Step: "createOrder", Call: "orderService.createOrder",
+		Match: &spec.SpanMatcher{HTTPMethod: "POST", HTTPRoute: "/orders"}}
+	sp := trace.Span{Service: "orderService", Name: "POST /orders",
+		Attributes: map[string]any{"http.request.method": "POST", "http.route": "/orders"}}
+	ok, reason := matchStep(st, sp)
+	if !ok || !strings.Contains(reason, "http.request.method=POST") {
+		t.Errorf("expected semconv 1.21 http.request.method to match, got ok=%v reason=%q", ok, reason)
+	}
+	sp.Attributes["http.request.method"] = "GET"
+	if ok, _ := matchStep(st, sp); ok {
+		t.Error("expected a different http.request.method not to match")
+	}
+}
+
+func TestMatchStep_NameOnlyFailsOnGenericSpans(t *testing.T) {
+	st := spec.FlowStep{Step: "createOrder", Call: "orderService.createOrder"}
+	if ok, _ := matchStep(st, httpSpan("orderService", "POST", "/orders", 0)); ok {
+		t.Fatal("expected name matching to reject generic span name")
+	}
+}
+
+func TestMatchStep_RegexAttributesAndCEL(t *testing.T) {
+	sp := trace.Span{
+		Service: "paymentService",
+		Name:    "grpc call",
+		Kind:    "client",
+		Attributes: map[string]any{
+			"rpc.method":  "Charge",
+			"rpc.service": "payments.v1.Payments",
+			"peer.host":   "payments-7f9c.internal",
+		},
+	}
+	cases := []struct {
+		name  string
+		match spec.SpanMatcher
+		want  bool
+	}{
+		{"rpc method", spec.SpanMatcher{RPCMethod: "Charge"}, true},
+		{"attribute equality", spec.SpanMatcher{Attributes: map[string]string{"rpc.service": "payments.v1.Payments"}}, true},
+		{"attribute mismatch", spec.SpanMatcher{Attributes: map[string]string{"rpc.service": "other"}}, false},
+		{"missing attribute", spec.SpanMatcher{HTTPMethod: "POST"}, false},
+		{"regex", spec.SpanMatcher{Regex: map[string]string{"peer.host": `^payments-[0-9a-f]+\.internal$`}}, true},
+		{"regex mismatch", spec.SpanMatcher{Regex: map[string]string{"peer.host": `^orders-`}}, false},
+		{"cel", spec.SpanMatcher{CEL: `span.kind == "client" && span.attributes["rpc.method"].startsWith("Ch")`}, true},
+		{"cel false", spec.SpanMatcher{CEL: `span.kind == "server"`}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := tc.match
+			st := spec.FlowStep{Step: "charge", Call: "paymentService.charge", Match: &m}
+			ok, reason := matchStep(st, sp)
+			if ok != tc.want {
+				t.Fatalf("matchStep = %v (%q), want %v", ok, reason, tc.want)
+			}
+			if ok && !strings.HasPrefix(reason, "match ") {
+				t.Errorf("expected attribute match reason, got %q", reason)
+			}
+		})
+	}
+}
+
+func TestLint_InvalidMatcher(t *testing.T) {
+	fs := &spec.FlowSpec{
+		Info: spec.FlowInfo{Title: "match"},
+		Flow: []spec.FlowStep{
+			{Step: "createOrder", Call: "orderService.createOrder",
+				Match: &spec.SpanMatcher{Regex: map[string]string{"http.route": "("}, CEL: "span.name =="}},
+		},
+	}
+	opIndex := map[string]map[string]spec.ServiceOperation{
+		"orderService": {"createOrder": {OperationId: "createOrder"}},
+	}
+	issues, err := LintFlow("", fs, opIndex, nil)
+	if err != nil {
+		t.Fatalf("lint: %v", err)
+	}
+	var regexErr, celErr bool
+	for _, is := range issues {
+		if is.Level != "ERROR" {
+			continue
+		}
+		regexErr = regexErr || strings.Contains(is.Msg, "match.regex[http.route]")
+		celErr = celErr || strings.Contains(is.Msg, "match.cel does not compile")
+	}
+	if !regexErr || !celErr {
+		t.Fatalf("expected regex and cel lint errors, got %+v", issues)
+	}
+}
+
+func TestMatchStep_ComputedOperationID(t *testing.T) {
+	sp := httpSpan("orderService", "POST", "/orders/{id}", 0)
+	for _, call := range []string{"orderService.postOrdersById", "orderService.HTTP POST"} {
+		if ok, _ := matchStep(spec.FlowStep{Step: "s", Call: call}, sp); !ok {
+			t.Errorf("expected %s to match span %q", call, sp.Name)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package validate
+
+import (
+	"fmt"
+
+	"github.com/choreoatlas2025/cli/internal/spec"
+	"github.com/choreoatlas2025/cli/internal/trace"
+)
+
+// hasMessagingSteps reports whether the flow contains publish/consume steps
+func hasMessagingSteps(fs *spec.FlowSpec) bool {
+	for _, name := range orderedStepNames(fs) {
+		if st, ok := fs.StepByName(name); ok && st.IsMessaging() {
+			return true
+		}
+	}
+	return false
+}
+
+// checkMessageCausality verifies that every matched consume step is causally
+// linked to the publish step that produced its message. Causality is taken from
+// "message" edges of the call graph (span links or shared message IDs). Without
+// such an edge, strict mode fails the step and temporal mode falls back to start
+// time ordering. Returns false if any step was failed.
+func checkMessageCausality(fs *spec.FlowSpec, results []StepResult, tr *trace.Trace) bool {
+	graph, err := BuildCallGraph(tr.Spans)
+	if err != nil {
+		return true // graph-build errors are reported by the main validators
+	}
+	messageEdges := make(map[string]bool)
+	for _, e := range graph.Edges {
+		if e.Relationship == "message" {
+			messageEdges[e.From+"->"+e.To] = true
+		}
+	}
+
+	spanOf := make(map[string]string)
+	for _, r := range results {
+		if r.SpanID != "" {
+			spanOf[r.Step] = r.SpanID
+		}
+	}
+
+	ok := true
+	for i := range results {
+		r := &results[i]
+		st, found := fs.StepByName(r.Step)
+		if !found || st.Kind != spec.StepKindConsume || r.Status != "PASS" {
+			continue
+		}
+		producer := producerStep(fs, st)
+		if producer == "" || spanOf[producer] == "" {
+			continue // external producer or producer already reported
+		}
+		prodNode, consNode := graph.Nodes[spanOf[producer]], graph.Nodes[r.SpanID]
+		if prodNode == nil || consNode == nil {
+			continue
+		}
+
+		var note string
+		switch {
+		case messageEdges[prodNode.SpanID+"->"+consNode.SpanID]:
+			note = fmt.Sprintf("linked to producer %s", producer)
+		case GlobalCausalityMode == CausalityStrict:
+			r.Status, r.Code = "FAIL", CodeMessagingViolation
+			note = fmt.Sprintf("consumer not linked to producer %s (no span link or message id, strict mode)", producer)
+			ok = false
+		case consNode.StartNanos < prodNode.StartNanos:
+			r.Status, r.Code = "FAIL", CodeMessagingViolation
+			note = fmt.Sprintf("consumer started before producer %s", producer)
+			ok = false
+		default:
+			note = fmt.Sprintf("ordered after producer %s by time only (no span link or message id)", producer)
+		}
+		if r.Message != "" {
+			r.Message += " | "
+		}
+		r.Message += note
+	}
+	return ok
+}
+
+// producerStep returns the nearest publish step upstream of a consume step
+// that targets the same destination
+func producerStep(fs *spec.FlowSpec, consumer spec.FlowStep) string {
+	if consumer.Destination == "" {
+		return ""
+	}
+	isProducer := func(name string) bool {
+		st, ok := fs.StepByName(name)
+		return ok && st.Kind == spec.StepKindPublish && st.Destination == consumer.Destination
+	}
+
+	if fs.IsGraphMode() {
+		// BFS over predecessors so the closest producer wins
+		visited := map[string]bool{consumer.Step: true}
+		queue := getPredecessors(consumer.Step, fs.Graph)
+		for len(queue) > 0 {
+			id := queue[0]
+			queue = queue[1:]
+			if visited[id] {
+				continue
+			}
+			visited[id] = true
+			if isProducer(id) {
+				return id
+			}
+			queue = append(queue, getPredecessors(id, fs.Graph)...)
+		}
+		return ""
+	}
+
+	names := orderedStepNames(fs)
+	for i := len(names) - 1; i >= 0; i-- {
+		if names[i] != consumer.Step {
+			continue
+		}
+		for j := i - 1; j >= 0; j-- {
+			if isProducer(names[j]) {
+				return names[j]
+			}
+		}
+	}
+	return ""
+}
+
+// orderedStepNames lists step names in declaration order, including parallel children
+func orderedStepNames(fs *spec.FlowSpec) []string {
+	if fs.IsGraphMode() {
+		return fs.GetStepNames()
+	}
+	var names []string
+	for _, st := range fs.Flow {
+		if len(st.Parallel) > 0 {
+			for _, pst := range st.Parallel {
+				names = append(names, pst.Step)
+			}
+			continue
+		}
+		names = append(names, st.Step)
+	}
+	return names
+}
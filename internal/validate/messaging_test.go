@@ -0,0 +1,145 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package validate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/choreoatlas2025/cli/internal/spec"
+	"github.com/choreoatlas2025/cli/internal/trace"
+)
+
+func messagingFlow() *spec.FlowSpec {
+	return &spec.FlowSpec{
+		Flow: []spec.FlowStep{
+			{Step: "publishOrderCreated", Call: "orderService.publishOrderCreated", Kind: spec.StepKindPublish, Destination: "orders.created"},
+			{Step: "consumeOrderCreated", Call: "inventoryService.onOrderCreated", Kind: spec.StepKindConsume, Destination: "orders.created"},
+		},
+	}
+}
+
+func producerSpan(start int64, attrs map[string]any) trace.Span {
+	a := map[string]any{
+		"otlp.span_id":               "p1",
+		"messaging.system":           "kafka",
+		"messaging.operation":        "publish",
+		"messaging.destination.name": "orders.created",
+	}
+	for k, v := range attrs {
+		a[k] = v
+	}
+	return trace.Span{Service: "orderService", Name: "orders.created publish", Kind: "producer", StartNanos: start, EndNanos: start + 10, Attributes: a}
+}
+
+func consumerSpan(start int64, links []trace.SpanLink, attrs map[string]any) trace.Span {
+	a := map[string]any{
+		"otlp.span_id":               "c1",
+		"messaging.system":           "kafka",
+		"messaging.operation":        "process",
+		"messaging.destination.name": "orders.created",
+	}
+	for k, v := range attrs {
+		a[k] = v
+	}
+	return trace.Span{Service: "inventoryService", Name: "orders.created process", Kind: "consumer", StartNanos: start, EndNanos: start + 10, Attributes: a, Links: links}
+}
+
+func withCausalityMode(t *testing.T, mode CausalityMode) {
+	prev := GlobalCausalityMode
+	GlobalCausalityMode = mode
+	t.Cleanup(func() { GlobalCausalityMode = prev })
+}
+
+func TestBuildCallGraph_MessageEdges(t *testing.T) {
+	tests := []struct {
+		name     string
+		consumer trace.Span
+	}{
+		{"span link", consumerSpan(100, []trace.SpanLink{{SpanID: "p1"}}, nil)},
+		{"message id", consumerSpan(100, nil, map[string]any{"messaging.message.id": "m-1"})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graph, err := BuildCallGraph([]trace.Span{producerSpan(0, map[string]any{"messaging.message.id": "m-1"}), tt.consumer})
+			if err != nil {
+				t.Fatalf("BuildCallGraph failed: %v", err)
+			}
+			found := false
+			for _, e := range graph.Edges {
+				if e.Relationship == "message" && e.From == "p1" && e.To == "c1" {
+					found = true
+				}
+			}
+			if !found {
+				t.Fatalf("expected message edge p1->c1, got %+v", graph.Edges)
+			}
+		})
+	}
+}
+
+func TestValidate_MessagingStepsLinked(t *testing.T) {
+	withCausalityMode(t, CausalityStrict)
+	tr := &trace.Trace{Spans: []trace.Span{
+		producerSpan(0, nil),
+		consumerSpan(100, []trace.SpanLink{{SpanID: "p1"}}, nil),
+	}}
+
+	results, ok := ValidateAgainstTrace(messagingFlow(), nil, tr)
+	if !ok {
+		t.Fatalf("expected linked publish/consume to pass, got %+v", results)
+	}
+	if !strings.Contains(results[1].Message, "linked to producer publishOrderCreated") {
+		t.Errorf("expected link note, got %q", results[1].Message)
+	}
+}
+
+func TestValidate_MessagingStepsUnlinked(t *testing.T) {
+	// The parent span ID routes validation through the call graph, which does not enforce step order
+	tr := &trace.Trace{Spans: []trace.Span{
+		producerSpan(100, nil),
+		consumerSpan(0, nil, map[string]any{"otlp.parent_span_id": "root"}),
+	}}
+
+	t.Run("strict requires link", func(t *testing.T) {
+		withCausalityMode(t, CausalityStrict)
+		tr := &trace.Trace{Spans: []trace.Span{producerSpan(0, nil), consumerSpan(100, nil, nil)}}
+		results, ok := ValidateAgainstTrace(messagingFlow(), nil, tr)
+		if ok || !strings.Contains(results[1].Message, "not linked") {
+			t.Fatalf("expected strict mode to fail unlinked consumer, got %+v", results)
+		}
+	})
+
+	t.Run("temporal checks order", func(t *testing.T) {
+		withCausalityMode(t, CausalityTemporal)
+		results, ok := ValidateAgainstTrace(messagingFlow(), nil, tr)
+		if ok || !strings.Contains(results[1].Message, "started before producer") {
+			t.Fatalf("expected consumer before producer to fail, got %+v", results)
+		}
+	})
+
+	t.Run("off ignores missing link", func(t *testing.T) {
+		withCausalityMode(t, CausalityOff)
+		tr := &trace.Trace{Spans: []trace.Span{producerSpan(0, nil), consumerSpan(100, nil, nil)}}
+		if results, ok := ValidateAgainstTrace(messagingFlow(), nil, tr); !ok {
+			t.Fatalf("expected causality off to pass, got %+v", results)
+		}
+	})
+}
+
+func TestLint_UnknownStepKind(t *testing.T) {
+	fs := &spec.FlowSpec{
+		Info: spec.FlowInfo{Title: "events"},
+		Flow: []spec.FlowStep{{Step: "s1", Call: "orderService.createOrder", Kind: "broadcast"}},
+	}
+	opIndex := map[string]map[string]spec.ServiceOperation{
+		"orderService": {"createOrder": {OperationId: "createOrder"}},
+	}
+	issues, _ := LintFlow("", fs, opIndex, nil)
+	for _, is := range issues {
+		if is.Level == "ERROR" && strings.Contains(is.Msg, "unknown kind: broadcast") {
+			return
+		}
+	}
+	t.Fatalf("expected unknown kind error, got %+v", issues)
+}
@@ -0,0 +1,290 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package validate
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/choreoatlas2025/cli/internal/spec"
+	"github.com/choreoatlas2025/cli/internal/trace"
+)
+
+// StatusNotApplicable marks steps that were not expected to run in this execution
+// (compensations that were never triggered, forward steps after a saga failure)
+const StatusNotApplicable = "N/A"
+
+// validateSaga validates flows that declare `compensate` links.
+//
+// The forward path (all steps that are not compensations) is validated first.
+// If one of its matched spans failed (HTTP status >= 400 or span status ERROR),
+// the compensations of the steps completed before the failure must appear after
+// the failure, in reverse completion order. Forward steps that would only have
+// run after the failure are reported as not applicable instead of missing.
+func validateSaga(fs *spec.FlowSpec, opIndex map[string]map[string]spec.ServiceOperation, tr *trace.Trace) ([]StepResult, bool) {
+	comps := fs.Compensations()
+	compSteps := make(map[string]bool)
+	for _, c := range comps {
+		compSteps[c] = true
+	}
+
+	results, _ := validateForward(fs.WithoutSteps(compSteps), opIndex, tr)
+
+	spansByID := make(map[string]trace.Span)
+	for _, sp := range tr.Spans {
+		spansByID[getSpanID(sp)] = sp
+	}
+
+	// Locate the earliest failed span among the forward steps
+	failedIdx := -1
+	var failedSpan trace.Span
+	failReason := ""
+	for i, r := range results {
+		sp, ok := spansByID[r.SpanID]
+		if r.SpanID == "" || !ok {
+			continue
+		}
+		if failed, reason := spanFailed(sp); failed {
+			if failedIdx < 0 || sp.StartNanos < failedSpan.StartNanos {
+				failedIdx, failedSpan, failReason = i, sp, reason
+			}
+		}
+	}
+
+	// Happy path: no compensation is expected
+	if failedIdx < 0 {
+		for _, name := range fs.GetStepNames() {
+			if !compSteps[name] {
+				continue
+			}
+			st, _ := fs.StepByName(name)
+			results = append(results, StepResult{Step: name, Call: st.Call, Status: StatusNotApplicable, Message: "not applicable: compensation not triggered"})
+		}
+		return results, sagaPassed(results)
+	}
+
+	markFailedStep(&results[failedIdx], failReason)
+
+	// Completed steps (matched, not failed, started before the failure), latest first
+	type completed struct {
+		step  string
+		start int64
+	}
+	var done []completed
+	usedSpans := make(map[string]bool)
+	before := stepsBefore(fs, results[failedIdx].Step)
+	for i := range results {
+		r := &results[i]
+		if r.SpanID == "" {
+			// 失败步骤之前必须执行的步骤缺失时保留原有的 FAIL
+			if i != failedIdx && r.Call != "internal" && !before[r.Step] {
+				r.Status = StatusNotApplicable
+				r.Message = fmt.Sprintf("not applicable: flow aborted after %s failed", results[failedIdx].Step)
+				r.Conditions = nil
+			}
+			continue
+		}
+		usedSpans[r.SpanID] = true
+		sp := spansByID[r.SpanID]
+		if i != failedIdx && sp.StartNanos < failedSpan.StartNanos {
+			done = append(done, completed{step: r.Step, start: sp.StartNanos})
+		}
+	}
+	sort.SliceStable(done, func(i, j int) bool { return done[i].start > done[j].start })
+
+	// Required compensations must follow the failure in reverse completion order
+	sortedSpans := make([]trace.Span, len(tr.Spans))
+	copy(sortedSpans, tr.Spans)
+	sort.SliceStable(sortedSpans, func(i, j int) bool { return sortedSpans[i].StartNanos < sortedSpans[j].StartNanos })
+
+	compResults := make(map[string]StepResult)
+	lastStart := failedSpan.StartNanos
+	lastCompensated := "" // 最近一个已匹配到补偿的步骤
+	for _, d := range done {
+		compName, ok := comps[d.step]
+		if !ok {
+			continue
+		}
+		st, _ := fs.StepByName(compName)
+		sr := StepResult{Step: compName, Call: st.Call}
+		svc, op, err := splitCall(st.Call)
+		if err != nil {
+			sr.Status, sr.Message, sr.Code = "FAIL", err.Error(), CodeTraceError
+			compResults[compName] = sr
+			continue
+		}
+
+		var match *trace.Span
+		reason := ""
+		misordered := false
+		for j := range sortedSpans {
+			sp := sortedSpans[j]
+			if usedSpans[getSpanID(sp)] {
+				continue
+			}
+			ok, why := matchStep(st, sp)
+			if !ok {
+				continue
+			}
+			if sp.StartNanos >= lastStart {
+				match, reason = &sortedSpans[j], why
+				break
+			}
+			if sp.StartNanos >= failedSpan.StartNanos {
+				misordered = true
+			}
+		}
+
+		switch {
+		case match != nil:
+			usedSpans[getSpanID(*match)] = true
+			lastStart = match.StartNanos
+			lastCompensated = d.step
+			sr.Status = "PASS"
+			sr.SpanID = getSpanID(*match)
+			sr.MatchReason = reason
+			sr.Message = fmt.Sprintf("compensates %s", d.step)
+			if EnableSemantic {
+				if opSpec, ok := opIndex[svc][op]; ok {
+					conds, okSem := EvaluateConditions(st, opSpec, *match, map[string]any{})
+					sr.Conditions = conds
+					if !okSem {
+						sr.Status, sr.Code = "FAIL", CodeConditionFailed
+						sr.Message += " | semantic validation failed"
+					}
+				}
+			}
+		case misordered:
+			sr.Status, sr.Code = "FAIL", CodeOrderViolation
+			expected := fmt.Sprintf("%s failed", results[failedIdx].Step)
+			if lastCompensated != "" {
+				expected = "the compensation for " + lastCompensated
+			}
+			sr.Message = fmt.Sprintf("compensation for %s ran out of order (expected after %s)", d.step, expected)
+		default:
+			sr.Status, sr.Code = "FAIL", CodeRuntimeFailure
+			sr.Message = fmt.Sprintf("compensation for %s missing after %s failed", d.step, results[failedIdx].Step)
+		}
+		compResults[compName] = sr
+	}
+
+	for _, name := range fs.GetStepNames() {
+		if !compSteps[name] {
+			continue
+		}
+		if sr, ok := compResults[name]; ok {
+			results = append(results, sr)
+			continue
+		}
+		st, _ := fs.StepByName(name)
+		results = append(results, StepResult{Step: name, Call: st.Call, Status: StatusNotApplicable, Message: "not applicable: compensated step did not complete"})
+	}
+
+	return results, sagaPassed(results)
+}
+
+// stepsBefore returns the steps that must have run before the failed step:
+// earlier flow entries in the sequential format, the transitive dependencies in
+// the graph format. Other forward steps may legitimately be skipped after a failure.
+func stepsBefore(fs *spec.FlowSpec, failed string) map[string]bool {
+	before := make(map[string]bool)
+	if fs.IsGraphMode() {
+		depends := make(map[string][]string)
+		for _, n := range fs.Graph.Nodes {
+			depends[n.ID] = n.Depends
+		}
+		queue := append([]string(nil), depends[failed]...)
+		for len(queue) > 0 {
+			id := queue[0]
+			queue = queue[1:]
+			if before[id] {
+				continue
+			}
+			before[id] = true
+			queue = append(queue, depends[id]...)
+		}
+		return before
+	}
+	for _, entry := range fs.Flow {
+		names := []string{entry.Step}
+		for _, pst := range entry.Parallel {
+			names = append(names, pst.Step)
+		}
+		for _, name := range names {
+			if name == failed {
+				return before
+			}
+		}
+		for _, name := range names {
+			before[name] = true
+		}
+	}
+	return before
+}
+
+// markFailedStep records the runtime failure on the step that triggered compensation.
+// Postconditions describe a successful outcome and no longer apply.
+func markFailedStep(r *StepResult, reason string) {
+	semanticFail := false
+	for i := range r.Conditions {
+		c := &r.Conditions[i]
+		if c.Status == "FAIL" {
+			semanticFail = true
+		}
+		if c.Kind == "post" {
+			c.Status = "SKIP"
+			c.Message = "not applicable: step failed at runtime"
+		}
+	}
+	// Structural failures (ordering, causality) are kept as-is
+	if r.Status == "FAIL" && !semanticFail {
+		return
+	}
+	for _, c := range r.Conditions {
+		if c.Status == "FAIL" {
+			r.Status, r.Code = "FAIL", CodeConditionFailed
+			r.Message = fmt.Sprintf("runtime failure detected (%s) | semantic validation failed", reason)
+			return
+		}
+	}
+	r.Status, r.Code = "PASS", ""
+	r.Message = fmt.Sprintf("runtime failure detected (%s); compensation path validated", reason)
+}
+
+// spanFailed reports whether a span represents a failed call
+func spanFailed(sp trace.Span) (bool, string) {
+	if code, ok := toInt64(sp.Attributes["otlp.status.code"]); ok && code == 2 {
+		return true, "span status ERROR"
+	}
+	for _, k := range []string{"response.status", "http.response.status_code", "http.status_code", "statusCode"} {
+		if code, ok := toInt64(sp.Attributes[k]); ok {
+			if code >= 400 {
+				return true, fmt.Sprintf("status code %d", code)
+			}
+			break
+		}
+	}
+	return false, ""
+}
+
+// sagaPassed treats not-applicable steps as neutral
+func sagaPassed(results []StepResult) bool {
+	for _, r := range results {
+		if r.Status != "PASS" && r.Status != StatusNotApplicable {
+			return false
+		}
+	}
+	return true
+}
+
+func toInt64(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	case float64:
+		return int64(n), true
+	}
+	return 0, false
+}
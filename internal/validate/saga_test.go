@@ -0,0 +1,254 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package validate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/choreoatlas2025/cli/internal/spec"
+	"github.com/choreoatlas2025/cli/internal/trace"
+)
+
+func sagaFlow() *spec.FlowSpec {
+	return &spec.FlowSpec{
+		Flow: []spec.FlowStep{
+			{Step: "createOrder", Call: "orderService.createOrder", Compensate: "cancelOrder"},
+			{Step: "reserveInventory", Call: "inventoryService.reserveInventory", Compensate: "releaseInventory"},
+			{Step: "processPayment", Call: "paymentService.processPayment"},
+			{Step: "createShipment", Call: "shippingService.createShipment"},
+			{Step: "releaseInventory", Call: "inventoryService.releaseInventory"},
+			{Step: "cancelOrder", Call: "orderService.cancelOrder"},
+		},
+	}
+}
+
+func sagaSpan(service, name string, start int64, status int) trace.Span {
+	return trace.Span{
+		Service:    service,
+		Name:       name,
+		StartNanos: start,
+		EndNanos:   start + 50,
+		Attributes: map[string]any{"http.status_code": status},
+	}
+}
+
+func resultByStep(results []StepResult) map[string]StepResult {
+	m := make(map[string]StepResult)
+	for _, r := range results {
+		m[r.Step] = r
+	}
+	return m
+}
+
+func TestValidateSaga_HappyPathCompensationsNotApplicable(t *testing.T) {
+	tr := &trace.Trace{Spans: []trace.Span{
+		sagaSpan("orderService", "createOrder", 100, 201),
+		sagaSpan("inventoryService", "reserveInventory", 200, 200),
+		sagaSpan("paymentService", "processPayment", 300, 200),
+		sagaSpan("shippingService", "createShipment", 400, 201),
+	}}
+
+	results, ok := ValidateAgainstTrace(sagaFlow(), nil, tr)
+	if !ok {
+		t.Fatalf("expected happy path to pass, got %+v", results)
+	}
+	byStep := resultByStep(results)
+	for _, name := range []string{"releaseInventory", "cancelOrder"} {
+		if byStep[name].Status != StatusNotApplicable {
+			t.Errorf("expected %s to be N/A, got %s", name, byStep[name].Status)
+		}
+	}
+}
+
+func TestValidateSaga_CompensationsInReverseOrder(t *testing.T) {
+	tr := &trace.Trace{Spans: []trace.Span{
+		sagaSpan("orderService", "createOrder", 100, 201),
+		sagaSpan("inventoryService", "reserveInventory", 200, 200),
+		sagaSpan("paymentService", "processPayment", 300, 500),
+		sagaSpan("inventoryService", "releaseInventory", 400, 200),
+		sagaSpan("orderService", "cancelOrder", 500, 200),
+	}}
+
+	results, ok := ValidateAgainstTrace(sagaFlow(), nil, tr)
+	if !ok {
+		t.Fatalf("expected saga rollback to pass, got %+v", results)
+	}
+	byStep := resultByStep(results)
+	if byStep["createShipment"].Status != StatusNotApplicable {
+		t.Errorf("expected step after failure to be N/A, got %s (%s)", byStep["createShipment"].Status, byStep["createShipment"].Message)
+	}
+	if !strings.Contains(byStep["processPayment"].Message, "status code 500") {
+		t.Errorf("expected failure reason on failed step, got %q", byStep["processPayment"].Message)
+	}
+	for _, name := range []string{"releaseInventory", "cancelOrder"} {
+		if byStep[name].Status != "PASS" {
+			t.Errorf("expected compensation %s to pass, got %s (%s)", name, byStep[name].Status, byStep[name].Message)
+		}
+	}
+}
+
+func TestValidateSaga_CompensationOutOfOrder(t *testing.T) {
+	tr := &trace.Trace{Spans: []trace.Span{
+		sagaSpan("orderService", "createOrder", 100, 201),
+		sagaSpan("inventoryService", "reserveInventory", 200, 200),
+		sagaSpan("paymentService", "processPayment", 300, 500),
+		sagaSpan("orderService", "cancelOrder", 400, 200),
+		sagaSpan("inventoryService", "releaseInventory", 500, 200),
+	}}
+
+	results, ok := ValidateAgainstTrace(sagaFlow(), nil, tr)
+	if ok {
+		t.Fatal("expected out-of-order compensation to fail")
+	}
+	cancel := resultByStep(results)["cancelOrder"]
+	if cancel.Status != "FAIL" || !strings.Contains(cancel.Message, "out of order (expected after the compensation for reserveInventory)") {
+		t.Errorf("expected cancelOrder out of order, got %s (%s)", cancel.Status, cancel.Message)
+	}
+}
+
+func TestValidateSaga_NewSemconvStatusCode(t *testing.T) {
+	span := func(service, name string, start int64, status int) trace.Span {
+		return trace.Span{Service: service, Name: name, StartNanos: start, EndNanos: start + 50,
+			Attributes: map[string]any{"http.response.status_code": status}}
+	}
+	tr := &trace.Trace{Spans: []trace.Span{
+		span("orderService", "createOrder", 100, 201),
+		span("inventoryService", "reserveInventory", 200, 200),
+		span("paymentService", "processPayment", 300, 502),
+		span("inventoryService", "releaseInventory", 400, 200),
+		span("orderService", "cancelOrder", 500, 200),
+	}}
+
+	results, ok := ValidateAgainstTrace(sagaFlow(), nil, tr)
+	if !ok {
+		t.Fatalf("expected compensated failure to pass, got %+v", results)
+	}
+	byStep := resultByStep(results)
+	if !strings.Contains(byStep["processPayment"].Message, "status code 502") {
+		t.Errorf("expected processPayment failure from http.response.status_code, got %q", byStep["processPayment"].Message)
+	}
+	if byStep["releaseInventory"].Status != "PASS" || byStep["cancelOrder"].Status != "PASS" {
+		t.Errorf("expected compensations to run, got %+v", results)
+	}
+}
+
+func TestValidateSaga_MissingCompensation(t *testing.T) {
+	tr := &trace.Trace{Spans: []trace.Span{
+		sagaSpan("orderService", "createOrder", 100, 201),
+		sagaSpan("inventoryService", "reserveInventory", 200, 200),
+		{Service: "paymentService", Name: "processPayment", StartNanos: 300, EndNanos: 350,
+			Attributes: map[string]any{"otlp.status.code": 2}},
+		sagaSpan("inventoryService", "releaseInventory", 400, 200),
+	}}
+
+	results, ok := ValidateAgainstTrace(sagaFlow(), nil, tr)
+	if ok {
+		t.Fatal("expected missing compensation to fail")
+	}
+	byStep := resultByStep(results)
+	if byStep["releaseInventory"].Status != "PASS" {
+		t.Errorf("expected releaseInventory to pass, got %s", byStep["releaseInventory"].Status)
+	}
+	if cancel := byStep["cancelOrder"]; cancel.Status != "FAIL" || !strings.Contains(cancel.Message, "missing") {
+		t.Errorf("expected cancelOrder missing, got %s (%s)", cancel.Status, cancel.Message)
+	}
+}
+
+func TestValidateSaga_MissingStepBeforeFailure(t *testing.T) {
+	tr := &trace.Trace{Spans: []trace.Span{
+		sagaSpan("orderService", "createOrder", 100, 201),
+		sagaSpan("paymentService", "processPayment", 300, 500),
+		sagaSpan("orderService", "cancelOrder", 400, 200),
+	}}
+
+	results, ok := ValidateAgainstTrace(sagaFlow(), nil, tr)
+	if ok {
+		t.Fatalf("expected a step missing before the failure to fail, got %+v", results)
+	}
+	byStep := resultByStep(results)
+	if r := byStep["reserveInventory"]; r.Status != "FAIL" || r.Code != CodeStepNotObserved {
+		t.Errorf("expected reserveInventory FAIL %s, got %s %s (%s)", CodeStepNotObserved, r.Status, r.Code, r.Message)
+	}
+	if r := byStep["createShipment"]; r.Status != StatusNotApplicable {
+		t.Errorf("expected createShipment after the failure to be N/A, got %s", r.Status)
+	}
+	if r := byStep["cancelOrder"]; r.Status != "PASS" {
+		t.Errorf("expected cancelOrder to pass, got %s (%s)", r.Status, r.Message)
+	}
+}
+
+func TestValidateSaga_GraphMode(t *testing.T) {
+	fs := &spec.FlowSpec{
+		Graph: &spec.GraphSpec{
+			Nodes: []spec.GraphNode{
+				{ID: "createOrder", Call: "orderService.createOrder", Compensate: "cancelOrder"},
+				{ID: "processPayment", Call: "paymentService.processPayment", Depends: []string{"createOrder"}},
+				{ID: "cancelOrder", Call: "orderService.cancelOrder", Depends: []string{"createOrder"}},
+			},
+		},
+	}
+	tr := &trace.Trace{Spans: []trace.Span{
+		sagaSpan("orderService", "createOrder", 100, 201),
+		sagaSpan("paymentService", "processPayment", 200, 402),
+		sagaSpan("orderService", "cancelOrder", 300, 200),
+	}}
+
+	results, ok := ValidateAgainstTrace(fs, nil, tr)
+	if !ok {
+		t.Fatalf("expected graph saga rollback to pass, got %+v", results)
+	}
+	if r := resultByStep(results)["cancelOrder"]; r.Status != "PASS" {
+		t.Errorf("expected cancelOrder to pass, got %s (%s)", r.Status, r.Message)
+	}
+}
+
+func TestLint_CompensateUnknownStep(t *testing.T) {
+	fs := &spec.FlowSpec{
+		Info: spec.FlowInfo{Title: "saga"},
+		Flow: []spec.FlowStep{
+			{Step: "createOrder", Call: "orderService.createOrder", Compensate: "undoOrder"},
+		},
+	}
+	opIndex := map[string]map[string]spec.ServiceOperation{
+		"orderService": {"createOrder": {OperationId: "createOrder"}},
+	}
+	issues, err := LintFlow("", fs, opIndex, nil)
+	if err != nil {
+		t.Fatalf("lint: %v", err)
+	}
+	for _, is := range issues {
+		if is.Level == "ERROR" && strings.Contains(is.Msg, "compensate references unknown step: undoOrder") {
+			return
+		}
+	}
+	t.Fatalf("expected unknown compensate target error, got %+v", issues)
+}
+
+func TestValidate_OptionalStepNotObserved(t *testing.T) {
+	fs := &spec.FlowSpec{
+		Flow: []spec.FlowStep{
+			{Step: "create", Call: "orderService.createOrder"},
+			{Step: "assess", Call: "riskService.assess", Optional: true},
+			{Step: "pay", Call: "paymentService.processPayment"},
+		},
+	}
+	tr := &trace.Trace{Spans: []trace.Span{
+		sagaSpan("orderService", "createOrder", 100, 201),
+		sagaSpan("paymentService", "processPayment", 200, 200),
+	}}
+
+	results, ok := ValidateAgainstTrace(fs, nil, tr)
+	if !ok {
+		t.Fatalf("expected missing optional step not to fail, got %+v", results)
+	}
+	if assess := resultByStep(results)["assess"]; assess.Status != StatusNotApplicable {
+		t.Errorf("expected N/A for optional step, got %+v", assess)
+	}
+
+	fs.Flow[2].Optional = true
+	results, ok = ValidateAgainstTrace(fs, nil, &trace.Trace{Spans: tr.Spans[1:]})
+	if ok || resultByStep(results)["create"].Status != "FAIL" {
+		t.Errorf("expected required step to still fail, got %+v", results)
+	}
+}
@@ -5,6 +5,7 @@ package validate
 import (
 	"errors"
 	"fmt"
+	"path"
 	"regexp"
 	"sort"
 	"strings"
@@ -16,63 +17,93 @@ import (
 type LintIssue struct {
 	Level string // "ERROR" or "WARN"
 	Msg   string
+	Code  string        // 稳定的诊断码（见 diagnostics.go）
+	Pos   spec.Position // 源码位置，未知时为零值
+	Ref   string        // 关联的 step / node ID
+	Hint  string        // 可选的修复建议
 }
 
 var varRefRe = regexp.MustCompile(`\$\{\s*([a-zA-Z_][\w\-\.]*)\s*\}`)
 
-// LintFlow 对流程规约进行静态检查
-func LintFlow(flowPath string, fs *spec.FlowSpec, opIndex map[string]map[string]spec.ServiceOperation) ([]LintIssue, error) {
+// LintFlow 对流程规约进行静态检查；cfg 为 nil 时使用默认规则配置
+func LintFlow(flowPath string, fs *spec.FlowSpec, opIndex map[string]map[string]spec.ServiceOperation, cfg *LintConfig) ([]LintIssue, error) {
+	return applyLintConfig(flowPath, lintSpec(flowPath, fs, opIndex, cfg), cfg, fs.Source), nil
+}
+
+// lintSpec 运行全部规则，返回未经配置过滤的问题
+func lintSpec(flowPath string, fs *spec.FlowSpec, opIndex map[string]map[string]spec.ServiceOperation, cfg *LintConfig) []LintIssue {
 	var issues []LintIssue
 
 	// 1) 基本结构检查
 	if fs.Info.Title == "" {
-		issues = append(issues, LintIssue{"WARN", "info.title is empty"})
+		issues = append(issues, newIssue(CodeEmptyTitle, "info.title is empty").
+			at(fs.Source.Lookup("info", "title")).withHint("set info.title to a short name for the flow"))
+	}
+	if fs.Info.Version == "" {
+		issues = append(issues, newIssue(CodeMissingVersion, "info.version is not set").
+			at(fs.Source.Lookup("info")).withHint("set info.version, e.g. \"%s\"", DefaultFlowVersion))
 	}
 	
 	// Check format compatibility
 	if len(fs.Flow) == 0 && fs.Graph == nil {
-		return append(issues, LintIssue{"ERROR", "either flow or graph must be specified"}), nil
+		return append(issues, newIssue(CodeFlowFormat, "either flow or graph must be specified").at(fs.Source.Lookup()))
 	}
 	if len(fs.Flow) > 0 && fs.Graph != nil {
-		return append(issues, LintIssue{"ERROR", "cannot specify both 'flow' and 'graph' - please choose one format"}), nil
+		return append(issues, newIssue(CodeFlowFormat, "cannot specify both 'flow' and 'graph' - please choose one format").
+			at(fs.Source.Lookup("graph")))
 	}
 	
 	// Route to appropriate linting based on format
 	if fs.IsGraphMode() {
-		return lintGraph(fs, opIndex)
+		issues = append(issues, lintGraph(fs, opIndex, cfg)...)
+		issues = append(issues, lintDeadNodes(fs)...)
+	} else {
+		issues = append(issues, lintFlow(fs, opIndex, cfg)...)
 	}
-	return lintFlow(fs, opIndex)
+
+	// 引用完整性：未使用的输出、绑定与操作，以及别名与服务名不一致
+	issues = append(issues, lintUnusedOutputs(fs)...)
+	issues = append(issues, lintUnusedBindings(fs)...)
+	issues = append(issues, lintServiceAliases(fs, flowPath)...)
+	issues = append(issues, lintUnusedOperations(fs, flowPath, cfg)...)
+	return issues
 }
 
 // lintFlow handles traditional flow format linting
-func lintFlow(fs *spec.FlowSpec, opIndex map[string]map[string]spec.ServiceOperation) ([]LintIssue, error) {
+func lintFlow(fs *spec.FlowSpec, opIndex map[string]map[string]spec.ServiceOperation, cfg *LintConfig) []LintIssue {
     var issues []LintIssue
 
 	// Add warning for legacy flow format
-	issues = append(issues, LintIssue{"WARN", "Using legacy flow format. Graph (DAG) format is recommended for better expressiveness and validation."})
+	issues = append(issues, newIssue(CodeLegacyFormat, "Using legacy flow format. Graph (DAG) format is recommended for better expressiveness and validation.").
+		at(fs.Source.Lookup("flow")))
 
 	if len(fs.Flow) == 0 {
-		return append(issues, LintIssue{"ERROR", "flow is empty"}), nil
+		return append(issues, newIssue(CodeFlowFormat, "flow is empty").at(fs.Source.Lookup("flow")))
 	}
 
 	// 2) 步骤唯一性 & 调用合法性检查
 	stepNames := map[string]struct{}{}
 	var allSteps []spec.FlowStep
-	
+	var stepPaths [][]any // 各步骤在 YAML 中的路径，用于定位
+
 	// 收集所有步骤（包括并发步骤）
-	for _, st := range fs.Flow {
+	for i, st := range fs.Flow {
 		allSteps = append(allSteps, st)
-		if len(st.Parallel) > 0 {
-			allSteps = append(allSteps, st.Parallel...)
+		stepPaths = append(stepPaths, []any{"flow", i})
+		for j, pst := range st.Parallel {
+			allSteps = append(allSteps, pst)
+			stepPaths = append(stepPaths, []any{"flow", i, "parallel", j})
 		}
 	}
 	
     for i, st := range allSteps {
+		at := fieldLocator(fs.Source, stepPaths[i])
 		if st.Step == "" {
-			issues = append(issues, LintIssue{"ERROR", fmt.Sprintf("step #%d is missing step name", i+1)})
+			issues = append(issues, newIssue(CodeStepName, "step #%d is missing step name", i+1).at(at()))
 		}
 		if _, ok := stepNames[st.Step]; ok {
-			issues = append(issues, LintIssue{"ERROR", fmt.Sprintf("duplicate step name: %s", st.Step)})
+			issues = append(issues, newIssue(CodeStepName, "duplicate step name: %s", st.Step).
+				at(at("step")).about(st.Step).withHint("step names must be unique; rename one of the steps"))
 		}
 		stepNames[st.Step] = struct{}{}
 
@@ -80,42 +111,42 @@ func lintFlow(fs *spec.FlowSpec, opIndex map[string]map[string]spec.ServiceOpera
 		if st.Call == "" && len(st.Parallel) > 0 {
 			continue
 		}
-
-		svc, op, err := splitCall(st.Call)
-		if err != nil {
-			issues = append(issues, LintIssue{"ERROR", fmt.Sprintf("step=%s invalid call: %v", st.Step, err)})
+		callIssues, resolved := lintCall("step", st.Step, st.Call, opIndex, at)
+		issues = append(issues, callIssues...)
+		if !resolved {
 			continue
 		}
-		ops, ok := opIndex[svc]
-		if !ok {
-			issues = append(issues, LintIssue{"ERROR", fmt.Sprintf("step=%s references undeclared service: %s", st.Step, svc)})
-			continue
-		}
-        if _, ok := ops[op]; !ok {
-            issues = append(issues, LintIssue{"ERROR", fmt.Sprintf("step=%s references non-existent operation %s in service %s", st.Step, op, svc)})
-        }
 
         // 输入键检查：禁止将遥测属性直接放入 FlowSpec.input
         if len(st.Input) > 0 {
             if bad := findTelemetryKeys(st.Input); len(bad) > 0 {
-                issues = append(issues, LintIssue{"ERROR", fmt.Sprintf(
+                issues = append(issues, newIssue(CodeTelemetryInput,
                     "step=%s input contains telemetry keys not allowed in FlowSpec.input: %s (建议: 将 http.* / otel.* / span.* 移至 ServiceSpec 的 preconditions/postconditions)",
-                    st.Step, strings.Join(bad, ", "))})
+                    st.Step, strings.Join(bad, ", ")).at(at("input")).about(st.Step).
+                    withHint("move http.* / otel.* / span.* into ServiceSpec preconditions/postconditions"))
             }
         }
+
+        issues = append(issues, lintStepKind("step", st.Step, st, at)...)
+        issues = append(issues, lintMatcher("step", st.Step, st.Match, at)...)
     }
 
+	// 补偿（saga）链接检查
+	issues = append(issues, lintCompensations(fs, "step")...)
+	issues = append(issues, lintAllowUnmatched(fs.AllowUnmatched, fs.Source)...)
+	issues = append(issues, lintForbidRules(fs.Forbid, fs.Source)...)
+	issues = append(issues, lintConditions(opIndex)...)
+
 	// 3) 变量引用连贯性检查（简单版）
 	// 按步骤顺序，前置步骤输出的 token 可被后续步骤引用
 	knownVars := map[string]struct{}{}
 
-	// 预定义一些常见的初始变量（通常从请求或外部输入获得）
-	initialVars := []string{"customerId", "orderItems", "totalAmount", "userId", "requestId"}
-	for _, v := range initialVars {
+	// 初始变量（通常从请求或外部输入获得），可在项目配置中声明
+	for _, v := range cfg.InitialVars() {
 		knownVars[v] = struct{}{}
 	}
 
-	for _, st := range fs.Flow {
+	for i, st := range fs.Flow {
 		// 处理并发步骤
 		if len(st.Parallel) > 0 {
 			// 并发步骤前的变量对所有并发子步骤都可见
@@ -124,13 +155,27 @@ func lintFlow(fs *spec.FlowSpec, opIndex map[string]map[string]spec.ServiceOpera
 				parallelVars[outVar] = struct{}{}
 			}
 			
-			// 检查并发子步骤的变量依赖
+			// 同组其他子步骤的输出，并发执行时不可用
+			siblingOutputs := map[string]string{}
 			for _, pst := range st.Parallel {
+				for outVar := range pst.Output {
+					siblingOutputs[outVar] = pst.Step
+				}
+			}
+
+			// 检查并发子步骤的变量依赖
+			for j, pst := range st.Parallel {
 				for _, v := range collectVarRefs(pst.Input) {
 					rootVar := strings.SplitN(v, ".", 2)[0]
-					if _, ok := parallelVars[rootVar]; !ok {
-						issues = append(issues, LintIssue{"ERROR", fmt.Sprintf("step=%s references unknown variable ${%s}", pst.Step, v)})
+					if _, ok := parallelVars[rootVar]; ok {
+						continue
+					}
+					pos := fs.Source.Lookup("flow", i, "parallel", j, "input")
+					if sibling, ok := siblingOutputs[rootVar]; ok && sibling != pst.Step {
+						issues = append(issues, parallelDependency("step", pst.Step, v, sibling, pos))
+						continue
 					}
+					issues = append(issues, unknownVariable("step", pst.Step, v, pos))
 				}
 			}
 			
@@ -146,7 +191,7 @@ func lintFlow(fs *spec.FlowSpec, opIndex map[string]map[string]spec.ServiceOpera
 				// 对于嵌套变量引用（如 orderResponse.items），只检查根变量（orderResponse）
 				rootVar := strings.SplitN(v, ".", 2)[0]
 				if _, ok := knownVars[rootVar]; !ok {
-					issues = append(issues, LintIssue{"ERROR", fmt.Sprintf("step=%s references unknown variable ${%s}", st.Step, v)})
+					issues = append(issues, unknownVariable("step", st.Step, v, fs.Source.Lookup("flow", i, "input")))
 				}
 			}
 			// 将本步骤 output 的 key 视为新的变量名
@@ -156,7 +201,50 @@ func lintFlow(fs *spec.FlowSpec, opIndex map[string]map[string]spec.ServiceOpera
 		}
 	}
 
-	return issues, nil
+	return issues
+}
+
+// unknownVariable 报告引用了前序步骤未输出的变量
+func unknownVariable(kind, name, ref string, pos spec.Position) LintIssue {
+	root := strings.SplitN(ref, ".", 2)[0]
+	return newIssue(CodeUnknownVariable, "%s=%s references unknown variable ${%s}", kind, name, ref).
+		at(pos).about(name).withHint("add an output %q to a preceding %s, or fix the reference", root, kind)
+}
+
+// parallelDependency 报告引用了并发兄弟步骤输出的变量
+func parallelDependency(kind, name, ref, producer string, pos spec.Position) LintIssue {
+	return newIssue(CodeParallelDependency, "%s=%s references ${%s} produced by parallel %s %s", kind, name, ref, kind, producer).
+		at(pos).about(name).withHint("move %s after %s, or declare the dependency explicitly", name, producer)
+}
+
+// fieldLocator 返回定位 base 路径下字段的函数
+func fieldLocator(src *spec.SourceMap, base []any) func(field ...any) spec.Position {
+	return func(field ...any) spec.Position {
+		return src.Lookup(append(append([]any{}, base...), field...)...)
+	}
+}
+
+// lintCall 检查调用格式及其引用的服务与操作；调用格式错误或服务未声明时 resolved 为 false
+func lintCall(kind, name, call string, opIndex map[string]map[string]spec.ServiceOperation, at func(...any) spec.Position) (issues []LintIssue, resolved bool) {
+	svc, op, err := splitCall(call)
+	if err != nil {
+		format := "%s=%s invalid call: %v"
+		if kind == "node" {
+			format = "%s=%s has invalid call: %v"
+		}
+		return []LintIssue{newIssue(CodeInvalidCall, format, kind, name, err).at(at("call")).about(name).
+			withHint("use the form serviceAlias.operationId")}, false
+	}
+	ops, ok := opIndex[svc]
+	if !ok {
+		return []LintIssue{newIssue(CodeUndeclaredService, "%s=%s references undeclared service: %s", kind, name, svc).
+			at(at("call")).about(name).withHint("declare %s under services with the path to its ServiceSpec", svc)}, false
+	}
+	if _, ok := ops[op]; !ok {
+		return []LintIssue{newIssue(CodeUnknownOperation, "%s=%s references non-existent operation %s in service %s", kind, name, op, svc).
+			at(at("call")).about(name).withHint("known operations of %s: %s", svc, strings.Join(sortedKeys(ops), ", "))}, true
+	}
+	return nil, true
 }
 
 // splitCall 解析服务调用格式
@@ -190,51 +278,252 @@ func collectVarRefs(v any) []string {
 }
 
 // lintGraph handles DAG format linting
-func lintGraph(fs *spec.FlowSpec, opIndex map[string]map[string]spec.ServiceOperation) ([]LintIssue, error) {
+func lintGraph(fs *spec.FlowSpec, opIndex map[string]map[string]spec.ServiceOperation, cfg *LintConfig) []LintIssue {
     var issues []LintIssue
 	
 	if fs.Graph == nil {
-		return append(issues, LintIssue{"ERROR", "graph is empty"}), nil
+		return append(issues, newIssue(CodeFlowFormat, "graph is empty").at(fs.Source.Lookup("graph")))
 	}
 	
 	// 1) Validate basic DAG structure (cycles, connectivity)
 	if err := fs.Graph.ValidateGraphStructure(); err != nil {
-		issues = append(issues, LintIssue{"ERROR", fmt.Sprintf("DAG structure validation failed: %v", err)})
-		return issues, nil // Stop here if structure is invalid
+		issues = append(issues, newIssue(CodeGraphStructure, "DAG structure validation failed: %v", err).at(fs.Source.Lookup("graph", "nodes")))
+		return issues // Stop here if structure is invalid
 	}
 	
 	// 2) Node call validation (similar to flow step validation)
-    for _, node := range fs.Graph.Nodes {
-		svc, op, err := splitCall(node.Call)
-		if err != nil {
-			issues = append(issues, LintIssue{"ERROR", fmt.Sprintf("node=%s has invalid call: %v", node.ID, err)})
-			continue
-		}
-		ops, ok := opIndex[svc]
-		if !ok {
-			issues = append(issues, LintIssue{"ERROR", fmt.Sprintf("node=%s references undeclared service: %s", node.ID, svc)})
+    for i, node := range fs.Graph.Nodes {
+		at := fieldLocator(fs.Source, []any{"graph", "nodes", i})
+		callIssues, resolved := lintCall("node", node.ID, node.Call, opIndex, at)
+		issues = append(issues, callIssues...)
+		if !resolved {
 			continue
 		}
-        if _, ok := ops[op]; !ok {
-            issues = append(issues, LintIssue{"ERROR", fmt.Sprintf("node=%s references non-existent operation %s in service %s", node.ID, op, svc)})
-        }
 
         // 输入键检查：禁止遥测属性出现在输入中
         if len(node.Input) > 0 {
             if bad := findTelemetryKeys(node.Input); len(bad) > 0 {
-                issues = append(issues, LintIssue{"ERROR", fmt.Sprintf(
+                issues = append(issues, newIssue(CodeTelemetryInput,
                     "node=%s input contains telemetry keys not allowed in FlowSpec.input: %s (Suggestion: move http.* / otel.* / span.* into ServiceSpec preconditions/postconditions)",
-                    node.ID, strings.Join(bad, ", "))})
+                    node.ID, strings.Join(bad, ", ")).at(at("input")).about(node.ID).
+                    withHint("move http.* / otel.* / span.* into ServiceSpec preconditions/postconditions"))
             }
         }
+
+        issues = append(issues, lintStepKind("node", node.ID, spec.FlowStep{Kind: node.Kind, Destination: node.Destination}, at)...)
+        issues = append(issues, lintMatcher("node", node.ID, node.Match, at)...)
     }
 	
+	// Saga compensation links
+	issues = append(issues, lintCompensations(fs, "node")...)
+	issues = append(issues, lintAllowUnmatched(fs.AllowUnmatched, fs.Source)...)
+	issues = append(issues, lintForbidRules(fs.Forbid, fs.Source)...)
+	issues = append(issues, lintConditions(opIndex)...)
+
 	// 3) Variable flow validation for DAG
-	if err := validateVariableFlow(fs.Graph); err != nil {
-		issues = append(issues, LintIssue{"ERROR", fmt.Sprintf("Variable flow validation failed: %v", err)})
+	if err := validateVariableFlow(fs.Graph, cfg.InitialVars()); err != nil {
+		is := newIssue(CodeUnknownVariable, "Variable flow validation failed: %v", err)
+		var vfe *variableFlowError
+		if errors.As(err, &vfe) {
+			is = is.at(fs.Source.Step(vfe.node, "input")).about(vfe.node).
+				withHint("add %q to the output of a node this node depends on", strings.SplitN(vfe.ref, ".", 2)[0])
+			if vfe.producer != "" {
+				is = parallelDependency("node", vfe.node, vfe.ref, vfe.producer, is.Pos).
+					withHint("add %s to the depends of %s", vfe.producer, vfe.node)
+			}
+		}
+		issues = append(issues, is)
 	}
 	
-	return issues, nil
+	return issues
+}
+
+// lintStepKind checks the step kind and messaging destination
+func lintStepKind(kind, name string, st spec.FlowStep, at func(...any) spec.Position) []LintIssue {
+	switch st.Kind {
+	case "", spec.StepKindCall:
+		if st.Destination != "" {
+			return []LintIssue{newIssue(CodeStepDestination, "%s=%s sets destination but is not a publish/consume step; destination is ignored", kind, name).
+				at(at("destination")).about(name).withHint("set kind: publish or kind: consume, or remove destination")}
+		}
+	case spec.StepKindPublish, spec.StepKindConsume:
+		if st.Destination == "" {
+			return []LintIssue{newIssue(CodeStepDestination, "%s=%s is a %s step without destination; any %s span of the service will match", kind, name, st.Kind, st.Kind).
+				at(at("kind")).about(name).withHint("set destination to the topic or queue name")}
+		}
+	default:
+		return []LintIssue{newIssue(CodeStepKind, "%s=%s has unknown kind: %s (expected call|publish|consume)", kind, name, st.Kind).
+			at(at("kind")).about(name)}
+	}
+	return nil
+}
+
+// lintMatcher checks that `match:` regexes and CEL predicates compile
+func lintMatcher(kind, name string, m *spec.SpanMatcher, at func(...any) spec.Position) []LintIssue {
+	if m == nil {
+		return nil
+	}
+	var issues []LintIssue
+	if m.HTTPRoute == "" && m.HTTPMethod == "" && m.RPCMethod == "" && len(m.Attributes) == 0 && len(m.Regex) == 0 && m.CEL == "" {
+		issues = append(issues, newIssue(CodeEmptyMatcher, "%s=%s has an empty match block; any span of the service will match", kind, name).
+			at(at("match")).about(name).withHint("add http.route, http.method, rpc.method, attributes, regex or cel, or remove match"))
+	}
+	keys := make([]string, 0, len(m.Regex))
+	for k := range m.Regex {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if _, err := regexp.Compile(m.Regex[k]); err != nil {
+			issues = append(issues, newIssue(CodeInvalidMatcher, "%s=%s match.regex[%s] is not a valid regular expression: %v", kind, name, k, err).
+				at(at("match", "regex", k)).about(name))
+		}
+	}
+	if m.CEL != "" {
+		if err := compileCEL(m.CEL); err != nil {
+			issues = append(issues, newIssue(CodeInvalidMatcher, "%s=%s match.cel does not compile: %v", kind, name, err).
+				at(at("match", "cel")).about(name))
+		}
+	}
+	return issues
+}
+
+// lintAllowUnmatched checks that `allowUnmatched` entries are valid glob patterns
+func lintAllowUnmatched(patterns []string, src *spec.SourceMap) []LintIssue {
+	var issues []LintIssue
+	for i, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			issues = append(issues, newIssue(CodeInvalidAllow, "allowUnmatched pattern %q is invalid: %v", p, err).
+				at(src.Lookup("allowUnmatched", i)))
+		} else if !strings.Contains(p, ".") {
+			issues = append(issues, newIssue(CodeAllowServicePart, "allowUnmatched pattern %q has no service part; use service.operation (e.g. \"*.healthCheck\")", p).
+				at(src.Lookup("allowUnmatched", i)).withHint("use %q to allow the operation in any service", "*."+p))
+		}
+	}
+	return issues
+}
+
+// lintConditions compiles and type-checks every ServiceSpec pre/postcondition
+// against the evaluation environment: syntax errors, unknown identifiers and
+// non-bool results are reported instead of being skipped at validate time.
+func lintConditions(opIndex map[string]map[string]spec.ServiceOperation) []LintIssue {
+	var issues []LintIssue
+	for _, svc := range sortedKeys(opIndex) {
+		for _, opID := range sortedKeys(opIndex[svc]) {
+			op := opIndex[svc][opID]
+			for _, group := range []struct {
+				kind  string
+				conds map[string]spec.Condition
+			}{{"precondition", op.Preconditions}, {"postcondition", op.Postconditions}} {
+				for _, name := range sortedKeys(group.conds) {
+					if err := compileCEL(group.conds[name].Expr); err != nil {
+						issues = append(issues, newIssue(CodeInvalidCondition,
+							"service=%s operation=%s %s %q is invalid: %v", svc, opID, group.kind, name, err).
+							at(group.conds[name].Pos))
+					}
+				}
+			}
+		}
+	}
+	return issues
+}
+
+// LintServiceSpec compiles and type-checks every condition of a single
+// ServiceSpec, e.g. an editor buffer that is not saved yet
+func LintServiceSpec(specPath string, data []byte, cfg *LintConfig) ([]LintIssue, error) {
+	ss, err := spec.ParseServiceSpec(specPath, data)
+	if err != nil {
+		return nil, err
+	}
+	src, err := spec.NewSourceMap(specPath, data)
+	if err != nil {
+		return nil, err
+	}
+	ops := map[string]spec.ServiceOperation{}
+	for _, op := range ss.Operations {
+		ops[op.OperationId] = op
+	}
+	issues := lintConditions(map[string]map[string]spec.ServiceOperation{ss.Service: ops})
+	return applyLintConfig(specPath, issues, cfg, src), nil
+}
+
+// lintForbidRules checks call patterns and CEL scopes of `forbid:` rules
+func lintForbidRules(rules []spec.ForbidRule, src *spec.SourceMap) []LintIssue {
+	var issues []LintIssue
+	var at func(...any) spec.Position
+	fieldPath := func(field string) []any {
+		var parts []any
+		for _, part := range strings.Split(field, ".") {
+			parts = append(parts, part)
+		}
+		return parts
+	}
+	checkPattern := func(rule, field, pattern string) {
+		if pattern == "" {
+			issues = append(issues, newIssue(CodeInvalidForbid, "forbid rule %q: %s is empty", rule, field).at(at()))
+		} else if _, err := path.Match(pattern, ""); err != nil {
+			issues = append(issues, newIssue(CodeInvalidForbid, "forbid rule %q: %s pattern %q is invalid: %v", rule, field, pattern, err).
+				at(at(fieldPath(field)...)))
+		}
+	}
+	checkCEL := func(rule, field, expr string) {
+		if expr == "" {
+			return
+		}
+		if err := compileCEL(expr); err != nil {
+			issues = append(issues, newIssue(CodeInvalidForbid, "forbid rule %q: %s does not compile: %v", rule, field, err).
+				at(at(fieldPath(field)...)))
+		}
+	}
+	for i, r := range rules {
+		name := r.RuleName()
+		at = fieldLocator(src, []any{"forbid", i})
+		checkPattern(name, "call", r.Call)
+		checkCEL(name, "when", r.When)
+		if r.Before != nil {
+			checkPattern(name, "before", r.Before.Call)
+			checkCEL(name, "before.when", r.Before.When)
+		}
+		if r.After != nil {
+			checkPattern(name, "after", r.After.Call)
+			checkCEL(name, "after.when", r.After.When)
+		}
+	}
+	return issues
+}
+
+// lintCompensations checks that `compensate` links point at existing, callable steps
+func lintCompensations(fs *spec.FlowSpec, kind string) []LintIssue {
+	var issues []LintIssue
+	comps := fs.Compensations()
+	names := make([]string, 0, len(comps))
+	for name := range comps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		target := comps[name]
+		if target == name {
+			issues = append(issues, newIssue(CodeInvalidCompensate, "%s=%s cannot compensate itself", kind, name).
+				at(fs.Source.Step(name, "compensate")).about(name))
+			continue
+		}
+		st, ok := fs.StepByName(target)
+		if !ok {
+			issues = append(issues, newIssue(CodeInvalidCompensate, "%s=%s compensate references unknown %s: %s", kind, name, kind, target).
+				at(fs.Source.Step(name, "compensate")).about(name))
+			continue
+		}
+		if st.Call == "" {
+			issues = append(issues, newIssue(CodeInvalidCompensate, "%s=%s compensate target %s has no call", kind, name, target).
+				at(fs.Source.Step(name, "compensate")).about(name))
+		}
+		if _, chained := comps[target]; chained {
+			issues = append(issues, newIssue(CodeNestedCompensate, "%s=%s compensation %s declares its own compensation, which is never triggered", kind, name, target).
+				at(fs.Source.Step(target, "compensate")).about(target))
+		}
+	}
+	return issues
 }
 
 // findTelemetryKeys 检查输入映射中是否出现明显的遥测前缀键
@@ -270,7 +559,7 @@ func hasTelemetryPrefix(k string) bool {
 }
 
 // validateVariableFlow checks that variables flow correctly through the DAG
-func validateVariableFlow(graph *spec.GraphSpec) error {
+func validateVariableFlow(graph *spec.GraphSpec, initialVars []string) error {
 	// Ensure edges are built from depends field
 	graph.EnsureEdges()
 
@@ -298,7 +587,6 @@ func validateVariableFlow(graph *spec.GraphSpec) error {
 		availableVars := make(map[string]bool)
 		
 		// Pre-seed with initial variables that are typically available at start
-		initialVars := []string{"customerId", "orderItems", "totalAmount", "userId", "requestId"}
 		for _, v := range initialVars {
 			availableVars[v] = true
 		}
@@ -334,7 +622,15 @@ func validateVariableFlow(graph *spec.GraphSpec) error {
 		for _, requiredVar := range requiredVars {
 			rootVar := strings.SplitN(requiredVar, ".", 2)[0]
 			if !availableVars[rootVar] {
-				return fmt.Errorf("node %s references variable ${%s} that is not available from predecessor nodes", node.ID, requiredVar)
+				vfe := &variableFlowError{node: node.ID, ref: requiredVar}
+				// Produced by a node that is neither a predecessor nor a successor: the two may run in parallel
+				for _, other := range graph.Nodes {
+					if _, ok := other.Output[rootVar]; ok && other.ID != node.ID && !reachable(adj, node.ID, other.ID) {
+						vfe.producer = other.ID
+						break
+					}
+				}
+				return vfe
 			}
 		}
 	}
@@ -342,6 +638,35 @@ func validateVariableFlow(graph *spec.GraphSpec) error {
 	return nil
 }
 
+// reachable reports whether to can be reached from from along adj
+func reachable(adj map[string][]string, from, to string) bool {
+	seen := map[string]bool{}
+	stack := []string{from}
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if cur == to {
+			return true
+		}
+		if seen[cur] {
+			continue
+		}
+		seen[cur] = true
+		stack = append(stack, adj[cur]...)
+	}
+	return false
+}
+
+// variableFlowError 表示节点引用了前驱节点未输出的变量
+type variableFlowError struct {
+	node, ref string
+	producer  string // 输出该变量但不是前驱的节点（如有）
+}
+
+func (e *variableFlowError) Error() string {
+	return fmt.Sprintf("node %s references variable ${%s} that is not available from predecessor nodes", e.node, e.ref)
+}
+
 // unique 去重并排序字符串切片
 func unique(in []string) []string {
 	if len(in) == 0 {
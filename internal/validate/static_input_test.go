@@ -37,7 +37,7 @@ func TestLint_FlagsTelemetryInInput(t *testing.T) {
     if err != nil {
         t.Fatalf("build op index: %v", err)
     }
-    issues, err := LintFlow(flowPath, fs, opIndex)
+    issues, err := LintFlow(flowPath, fs, opIndex, nil)
     if err != nil {
         t.Fatalf("lint: %v", err)
     }
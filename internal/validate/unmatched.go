@@ -0,0 +1,143 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package validate
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/choreoatlas2025/cli/internal/spec"
+	"github.com/choreoatlas2025/cli/internal/trace"
+)
+
+// UnmatchedOptions narrows the spans considered by FindUnmatchedSpans
+type UnmatchedOptions struct {
+	Kinds    []string // span kinds to consider (server, client, producer, ...); empty = all
+	MaxDepth int      // maximum depth in the span tree (0 = root spans); negative = unlimited
+}
+
+// UnmatchedSpan is a span no step accounted for
+type UnmatchedSpan struct {
+	SpanID      string `json:"spanId"`
+	Service     string `json:"service"`
+	Name        string `json:"name"`
+	OperationID string `json:"operationId,omitempty"`
+	Kind        string `json:"kind,omitempty"`
+	Depth       int    `json:"depth"`
+	StartNanos  int64  `json:"startNanos"`
+}
+
+// UnmatchedReport summarizes the unexpected interactions found in a trace
+type UnmatchedReport struct {
+	SpansTotal int             `json:"spansTotal"`
+	Matched    int             `json:"matched"`
+	Allowed    int             `json:"allowed"` // unmatched but covered by allowUnmatched
+	Ignored    int             `json:"ignored"` // filtered out by kind/depth
+	Spans      []UnmatchedSpan `json:"spans,omitempty"`
+}
+
+// FindUnmatchedSpans lists the spans of a trace that no step result refers to
+// and that are not allowed by the flow's `allowUnmatched` patterns
+func FindUnmatchedSpans(fs *spec.FlowSpec, tr *trace.Trace, results []StepResult, opts UnmatchedOptions) UnmatchedReport {
+	report := UnmatchedReport{SpansTotal: len(tr.Spans)}
+
+	matched := make(map[string]bool)
+	for _, r := range results {
+		if r.SpanID != "" {
+			matched[r.SpanID] = true
+		}
+	}
+	kinds := make(map[string]bool)
+	for _, k := range opts.Kinds {
+		kinds[normalize(k)] = true
+	}
+	depths := spanDepths(tr.Spans)
+
+	for _, sp := range tr.Spans {
+		id := getSpanID(sp)
+		if matched[id] {
+			report.Matched++
+			continue
+		}
+		depth := depths[id]
+		if (len(kinds) > 0 && !kinds[normalize(sp.Kind)]) || (opts.MaxDepth >= 0 && depth > opts.MaxDepth) {
+			report.Ignored++
+			continue
+		}
+		if allowedUnmatched(fs.AllowUnmatched, sp) {
+			report.Allowed++
+			continue
+		}
+		report.Spans = append(report.Spans, UnmatchedSpan{
+			SpanID:      id,
+			Service:     sp.Service,
+			Name:        sp.Name,
+			OperationID: spec.ComputeOperationID(sp),
+			Kind:        sp.Kind,
+			Depth:       depth,
+			StartNanos:  sp.StartNanos,
+		})
+	}
+	sort.SliceStable(report.Spans, func(i, j int) bool { return report.Spans[i].StartNanos < report.Spans[j].StartNanos })
+	return report
+}
+
+// allowedUnmatched reports whether any allowUnmatched pattern covers the span
+func allowedUnmatched(patterns []string, sp trace.Span) bool {
+	for _, p := range patterns {
+		if matchCallPattern(p, sp) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchCallPattern matches a `service.operation` glob pattern against both the
+// span name and its computed operation ID (case-insensitive). Unlike path.Match,
+// `*` also spans slashes so that "*.GET /orders*" works on HTTP span names.
+func matchCallPattern(pattern string, sp trace.Span) bool {
+	pattern = globSafe(normalize(pattern))
+	for _, name := range []string{
+		normalize(sp.Service + "." + sp.Name),
+		normalize(sp.Service + "." + spec.ComputeOperationID(sp)),
+	} {
+		if ok, err := path.Match(pattern, globSafe(name)); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+func globSafe(s string) string {
+	return strings.ReplaceAll(s, "/", "\u2215")
+}
+
+// spanDepths computes the depth of each span in the parent/child tree
+func spanDepths(spans []trace.Span) map[string]int {
+	parentOf := make(map[string]string)
+	for _, sp := range spans {
+		parentOf[getSpanID(sp)] = getParentSpanID(sp)
+	}
+	depths := make(map[string]int)
+	var depth func(id string, seen int) int
+	depth = func(id string, seen int) int {
+		if d, ok := depths[id]; ok {
+			return d
+		}
+		parent, ok := parentOf[id]
+		if !ok || parent == "" || seen > len(spans) {
+			return 0
+		}
+		if _, known := parentOf[parent]; !known {
+			return 0 // parent not part of this trace
+		}
+		d := depth(parent, seen+1) + 1
+		depths[id] = d
+		return d
+	}
+	for id := range parentOf {
+		depths[id] = depth(id, 0)
+	}
+	return depths
+}
@@ -0,0 +1,75 @@
+// SPDX-FileCopyrightText: 2025 ChoreoAtlas contributors
+// SPDX-License-Identifier: Apache-2.0
+package validate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/choreoatlas2025/cli/internal/spec"
+	"github.com/choreoatlas2025/cli/internal/trace"
+)
+
+func unmatchedFixture() (*spec.FlowSpec, *trace.Trace) {
+	fs := &spec.FlowSpec{
+		Flow: []spec.FlowStep{
+			{Step: "create", Call: "orderService.createOrder"},
+		},
+		AllowUnmatched: []string{"*.healthCheck"},
+	}
+	span := func(id, parent, service, name, kind string, start int64) trace.Span {
+		attrs := map[string]any{"otlp.span_id": id}
+		if parent != "" {
+			attrs["otlp.parent_span_id"] = parent
+		}
+		return trace.Span{Service: service, Name: name, Kind: kind, StartNanos: start, EndNanos: start + 10, Attributes: attrs}
+	}
+	tr := &trace.Trace{Spans: []trace.Span{
+		span("a", "", "orderService", "createOrder", "server", 100),
+		span("b", "a", "fraudService", "score", "client", 110),
+		span("c", "b", "fraudService", "SELECT", "client", 115),
+		span("d", "", "orderService", "healthCheck", "server", 200),
+	}}
+	return fs, tr
+}
+
+func TestFindUnmatchedSpans(t *testing.T) {
+	fs, tr := unmatchedFixture()
+	results, _ := ValidateAgainstTrace(fs, nil, tr)
+
+	report := FindUnmatchedSpans(fs, tr, results, UnmatchedOptions{MaxDepth: -1})
+	if report.SpansTotal != 4 || report.Matched != 1 || report.Allowed != 1 {
+		t.Fatalf("unexpected counts: %+v", report)
+	}
+	if len(report.Spans) != 2 || report.Spans[0].Name != "score" || report.Spans[1].Depth != 2 {
+		t.Fatalf("expected score and SELECT to be unexpected, got %+v", report.Spans)
+	}
+
+	shallow := FindUnmatchedSpans(fs, tr, results, UnmatchedOptions{MaxDepth: 1})
+	if len(shallow.Spans) != 1 || shallow.Ignored != 1 {
+		t.Errorf("expected depth filter to keep only score, got %+v", shallow)
+	}
+
+	servers := FindUnmatchedSpans(fs, tr, results, UnmatchedOptions{Kinds: []string{"server"}, MaxDepth: -1})
+	if len(servers.Spans) != 0 {
+		t.Errorf("expected no unmatched server spans, got %+v", servers.Spans)
+	}
+}
+
+func TestLint_AllowUnmatchedPattern(t *testing.T) {
+	fs := &spec.FlowSpec{
+		Info:           spec.FlowInfo{Title: "allow"},
+		Flow:           []spec.FlowStep{{Step: "create", Call: "orderService.createOrder"}},
+		AllowUnmatched: []string{"orderService.[health"},
+	}
+	opIndex := map[string]map[string]spec.ServiceOperation{
+		"orderService": {"createOrder": {OperationId: "createOrder"}},
+	}
+	issues, _ := LintFlow("", fs, opIndex, nil)
+	for _, is := range issues {
+		if is.Level == "ERROR" && strings.Contains(is.Msg, "allowUnmatched pattern") {
+			return
+		}
+	}
+	t.Fatalf("expected invalid pattern error, got %+v", issues)
+}